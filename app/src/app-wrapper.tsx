@@ -102,6 +102,7 @@ export function AppWrapper() {
 
           const token = params.token;
           const userId = params.user_id;
+          const oauthState = params.state;
           const error = params.error;
           const errorDescription = params.error_description;
 
@@ -135,7 +136,7 @@ export function AppWrapper() {
               const response = await fetch(exchangeUrl, {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ token }),
+                body: JSON.stringify({ token, state: oauthState }),
               });
 
               console.log('🌐 [DeepLink] Token exchange response:', {
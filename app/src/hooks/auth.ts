@@ -103,13 +103,19 @@ export function useAuth() {
 
   // OAuth login mutation - simplified to just do token exchange
   const login = useMutation({
-    mutationFn: async (mobileToken: string) => {
+    mutationFn: async ({
+      mobileToken,
+      state,
+    }: {
+      mobileToken: string;
+      state: string;
+    }) => {
       const response = await fetch(`${API_BASE}/api/v1/oauth/mobile/exchange`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
         },
-        body: JSON.stringify({ token: mobileToken }),
+        body: JSON.stringify({ token: mobileToken, state }),
       });
 
       if (!response.ok) {
@@ -137,7 +143,7 @@ export function useAuth() {
       if (token) {
         try {
           await fetch(`${API_BASE}/api/v1/oauth/logout`, {
-            method: 'GET',
+            method: 'POST',
             headers: {
               Authorization: `Bearer ${token}`,
             },
@@ -4,6 +4,7 @@
 
 import { useState, useCallback, useEffect } from '@lynx-js/react';
 import { useWebBrowserAuth } from './useWebBrowser';
+import { generateOAuthState } from '../lynx-web-browser-api';
 
 import { oauthCookieCapture } from '../utils/cookie-capture';
 import type { WebBrowserAuthSessionResult } from '../rspeedy-env';
@@ -94,6 +95,7 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
       url: string,
     ): {
       token?: string;
+      state?: string;
       userID?: number;
       error?: string;
       errorDescription?: string;
@@ -104,6 +106,7 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
 
         return {
           token: params.get('token') || undefined,
+          state: params.get('state') || undefined,
           userID: params.get('user_id')
             ? parseInt(params.get('user_id')!)
             : undefined,
@@ -124,6 +127,7 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
   const exchangeTokenForSession = useCallback(
     async (
       token: string,
+      state: string,
     ): Promise<{
       success: boolean;
       sessionToken?: string;
@@ -140,7 +144,7 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
             headers: {
               'Content-Type': 'application/json',
             },
-            body: JSON.stringify({ token }),
+            body: JSON.stringify({ token, state }),
           },
         );
 
@@ -195,7 +199,10 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
 
       // Construct mobile OAuth URLs
       const customScheme = options.customScheme || 'hextok://oauth/callback';
-      const authUrl = `${options.baseUrl}/api/v1/oauth/mobile/start/${options.provider}?redirect_uri=${encodeURIComponent(customScheme)}`;
+      // The server requires a client state and binds the one-time exchange
+      // code to it; it is echoed back in the deep link.
+      const oauthState = generateOAuthState();
+      const authUrl = `${options.baseUrl}/api/v1/oauth/start/${options.provider}?client=mobile&state=${encodeURIComponent(oauthState)}&redirect_uri=${encodeURIComponent(customScheme)}`;
 
       console.log('📱 Starting mobile OAuth flow...');
       console.log('Auth URL:', authUrl);
@@ -253,6 +260,7 @@ export function useMobileOAuth(options: MobileOAuthOptions) {
           // Exchange token for session
           const exchangeResult = await exchangeTokenForSession(
             callbackData.token,
+            callbackData.state ?? '',
           );
 
           if (exchangeResult.success) {
@@ -3,7 +3,10 @@
 // Provides easy-to-use hooks for browser operations in React components
 
 import { useState, useCallback, useRef, useEffect } from '@lynx-js/react';
-import { registerOAuthCallback } from '../lynx-web-browser-api';
+import {
+  generateOAuthState,
+  registerOAuthCallback,
+} from '../lynx-web-browser-api';
 import type {
   WebBrowserOpenOptions,
   AuthSessionOpenOptions,
@@ -740,14 +743,7 @@ export function useMobileOAuth(
   const generateState = useCallback(() => {
     'background only';
 
-    // Simple random string generator for OAuth state
-    const chars =
-      'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789';
-    let result = '';
-    for (let i = 0; i < 32; i++) {
-      result += chars.charAt(Math.floor(Math.random() * chars.length));
-    }
-    return result;
+    return generateOAuthState();
   }, []);
 
   // We no longer rely on a native OAuthResultModule.
@@ -758,7 +754,10 @@ export function useMobileOAuth(
 
   // Exchange mobile token for session
   const exchangeToken = useCallback(
-    async (token: string): Promise<MobileTokenExchangeResponse> => {
+    async (
+      token: string,
+      state: string,
+    ): Promise<MobileTokenExchangeResponse> => {
       'background only';
 
       const exchangeUrl = `${baseUrl}/api/v1/oauth/mobile/exchange`;
@@ -769,7 +768,7 @@ export function useMobileOAuth(
           headers: {
             'Content-Type': 'application/json',
           },
-          body: JSON.stringify({ token }),
+          body: JSON.stringify({ token, state }),
         });
 
         if (!response.ok) {
@@ -816,7 +815,7 @@ export function useMobileOAuth(
           throw new Error('LynxWebBrowserModule not available');
         }
 
-        const oauthStartUrl = `${baseUrl}/api/v1/oauth/mobile/start/github?state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
+        const oauthStartUrl = `${baseUrl}/api/v1/oauth/start/github?client=mobile&state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
 
         LynxWebBrowserModule.openAuthSessionAsync(
           oauthStartUrl,
@@ -302,7 +302,7 @@ export async function startMobileOAuthFlow(
   }
 
   // Construct the OAuth start URL for your backend
-  const oauthStartUrl = `${baseUrl}/api/v1/oauth/mobile/start/github?state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
+  const oauthStartUrl = `${baseUrl}/api/v1/oauth/start/github?client=mobile&state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
 
   return openAuthSessionAsync(oauthStartUrl, redirectUri, {
     preferEphemeralSession: true,
@@ -318,6 +318,7 @@ export async function startMobileOAuthFlow(
  */
 export async function exchangeMobileToken(
   token: string,
+  state: string,
   baseUrl: string = '',
 ): Promise<MobileTokenExchangeResponse> {
   'background only';
@@ -327,7 +328,7 @@ export async function exchangeMobileToken(
   }
 
   const exchangeUrl = `${baseUrl}/api/v1/oauth/mobile/exchange`;
-  const request: MobileTokenExchangeRequest = { token };
+  const request: MobileTokenExchangeRequest = { token, state };
 
   try {
     const response = await fetch(exchangeUrl, {
@@ -353,20 +354,40 @@ export async function exchangeMobileToken(
   }
 }
 
+const BASE64URL_CHARS =
+  'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_';
+
+/**
+ * Encodes bytes as unpadded base64url, without relying on btoa
+ */
+function base64url(bytes: Uint8Array): string {
+  let result = '';
+  for (let i = 0; i < bytes.length; i += 3) {
+    const n =
+      (bytes[i] << 16) |
+      ((i + 1 < bytes.length ? bytes[i + 1] : 0) << 8) |
+      (i + 2 < bytes.length ? bytes[i + 2] : 0);
+    result +=
+      BASE64URL_CHARS[(n >> 18) & 63] + BASE64URL_CHARS[(n >> 12) & 63];
+    if (i + 1 < bytes.length) result += BASE64URL_CHARS[(n >> 6) & 63];
+    if (i + 2 < bytes.length) result += BASE64URL_CHARS[n & 63];
+  }
+  return result;
+}
+
 /**
  * Helper function to generate a random state for OAuth flows
+ *
+ * The server binds the one-time exchange code to this state, so it must not
+ * be guessable: 32 bytes from crypto.getRandomValues, base64url encoded.
+ * There is deliberately no Math.random fallback.
  */
 export function generateOAuthState(): string {
   'background only';
 
-  // Generate a random state for OAuth flows
-  const chars =
-    'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789';
-  let result = '';
-  for (let i = 0; i < 32; i++) {
-    result += chars.charAt(Math.floor(Math.random() * chars.length));
-  }
-  return result;
+  const bytes = new Uint8Array(32);
+  crypto.getRandomValues(bytes);
+  return base64url(bytes);
 }
 
 /**
@@ -497,6 +518,7 @@ export async function completeOAuthFlow(
             // Exchange the mobile token for a session
             const exchangeResult = await exchangeMobileToken(
               result.token,
+              state,
               baseUrl,
             );
             resolve(exchangeResult);
@@ -1,6 +1,7 @@
 import { useState, useCallback, useEffect } from '@lynx-js/react';
 import { useWebBrowser } from '../../../hooks/useWebBrowser';
 import { API_BASE } from '../../../config';
+import { generateOAuthState } from '../../../lynx-web-browser-api';
 
 const LoginPage = () => {
   const [status, setStatus] = useState<
@@ -16,15 +17,7 @@ const LoginPage = () => {
   } = useWebBrowser();
 
   // Generate a random state for OAuth security
-  const generateState = useCallback(() => {
-    const chars =
-      'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789';
-    let result = '';
-    for (let i = 0; i < 32; i++) {
-      result += chars.charAt(Math.floor(Math.random() * chars.length));
-    }
-    return result;
-  }, []);
+  const generateState = useCallback(() => generateOAuthState(), []);
   useEffect(() => {
     if (browserError) {
       setStatus('error');
@@ -45,7 +38,7 @@ const LoginPage = () => {
     const redirectUri = 'hextok://oauth/callback';
 
     // Build OAuth URL to start the flow
-    const oauthUrl = `${API_BASE}/api/v1/oauth/mobile/start/github?state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
+    const oauthUrl = `${API_BASE}/api/v1/oauth/start/github?client=mobile&state=${encodeURIComponent(state)}&redirect_uri=${encodeURIComponent(redirectUri)}`;
 
     console.log('🚀 [Login] Starting OAuth flow:', {
       oauthUrl,
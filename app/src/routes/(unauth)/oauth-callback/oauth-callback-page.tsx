@@ -114,7 +114,7 @@ const OAuthCallbackPage = () => {
           headers: {
             'Content-Type': 'application/json',
           },
-          body: JSON.stringify({ token }),
+          body: JSON.stringify({ token, state }),
         });
 
         console.log('🌐 [OAuth Callback] Token exchange response:', {
@@ -15,6 +15,13 @@ export const hexSchema = z4.object({
   }),
   likeCount: z4.number().int().nonnegative().optional(),
   isLiked: z4.boolean().optional(),
+  createdBy: z4.number().int().optional(),
+  createdAt: z4
+    .string()
+    .refine((date) => !isNaN(Date.parse(date)), {
+      message: 'Invalid date format',
+    })
+    .optional(),
 });
 export type UserResponse = z4.infer<typeof userSchema> & { id: string };
 export type HexResponse = z4.infer<typeof hexSchema> & { id: string };
@@ -2,86 +2,193 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
 	"github.com/HimanshuKumarDutt094/hextok/internal/db"
+	"github.com/HimanshuKumarDutt094/hextok/internal/db/migrations"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/mail"
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
 	"github.com/HimanshuKumarDutt094/hextok/internal/platform"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/health"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 	v1 "github.com/HimanshuKumarDutt094/hextok/internal/server/v1"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/auth"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/follows"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/hexes"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/identities"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/likes"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/sessions"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/tokens"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/users"
-	"github.com/joho/godotenv"
+	"github.com/HimanshuKumarDutt094/hextok/internal/tracing"
 )
 
 func main() {
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	level, _ := cfg.Log.SlogLevel()
+	logger := logging.New(os.Stderr, cfg.Log.Format, level)
+	// stray log.Printf calls end up in the same redacted JSON stream
+	slog.SetDefault(logger)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		fatal("configuring tracing", err)
 	}
-	d, err := db.New()
+
+	d, err := db.New(cfg.Database)
 	if err != nil {
-		log.Fatal(err)
+		fatal("connecting to the database", err)
 	}
 	defer d.Close()
+	metrics.RegisterDB(d, cfg.Database.Name)
+	tokenKeys, err := cfg.Auth.TokenEncryption.Keyring()
+	if err != nil {
+		fatal("loading token encryption keys", err)
+	}
+	if tokenKeys == nil {
+		slog.Warn("TOKEN_ENCRYPTION_KEYS is not set; OAuth provider tokens are stored unencrypted")
+	}
 
 	userStore := platform.NewUserStore(d)
 	hexStore := platform.NewHexStore(d)
-	oauthStore := platform.NewOauthStore(d)
+	oauthStore := platform.NewOauthStore(d, tokenKeys)
 	followStore := platform.NewFollowStore(d)
 	sessionStore := platform.NewSessionStore(d)
 	likeStore := platform.NewLikeStore(d)
+	authCodeStore := platform.NewAuthCodeStore(d)
+	emailTokenStore := platform.NewEmailTokenStore(d)
+	accessTokenStore := platform.NewAccessTokenStore(d)
+	mailer := mail.New(cfg.Mail)
+	if cfg.Mail.SMTPHost == "" && cfg.Mail.Dir == "" {
+		slog.Warn("SMTP_HOST is not set; emails, login links included, are printed to stderr")
+	}
+
+	sessionPolicy := cfg.Auth.Session.Policy()
+	authMiddleware := middlewares.NewAuthMiddleware(sessionStore, accessTokenStore, sessionPolicy)
 
-	usersHandler := users.NewHandler(userStore, sessionStore)
-	authHandler := auth.NewHandler(userStore, oauthStore, sessionStore, nil)
-	hexHandler := hexes.NewHandler(hexStore, likeStore, sessionStore)
-	followHandler := follows.NewHandler(followStore, sessionStore)
-	likeHandler := likes.NewHandler(hexStore, likeStore, sessionStore)
+	usersHandler := users.NewHandler(userStore, authMiddleware)
+	authHandler := auth.NewHandler(userStore, oauthStore, sessionStore, authCodeStore, emailTokenStore, mailer, cfg.Auth, nil)
+	hexHandler := hexes.NewHandler(hexStore, userStore, likeStore, authMiddleware)
+	followHandler := follows.NewHandler(followStore, authMiddleware)
+	likeHandler := likes.NewHandler(hexStore, likeStore, authMiddleware)
+	sessionsHandler := sessions.NewHandler(sessionStore, sessionPolicy, authMiddleware)
+	identitiesHandler := identities.NewHandler(oauthStore, authMiddleware)
+	tokensHandler := tokens.NewHandler(accessTokenStore, authMiddleware)
+	healthHandler := health.NewHandler(d, migrations.NewMigrator(d), cfg.Database.PingTimeout)
 
-	rootMux := server.NewMux()
+	rootMux := server.NewMux(healthHandler)
 
 	apiMux := http.NewServeMux()
-	rootMux.Handle("/api/", http.StripPrefix("/api", apiMux))
+	rootMux.Handle("/api/", http.StripPrefix("/api", middlewares.ProblemMux(apiMux)))
 
 	v1Mux := http.NewServeMux()
 
-	apiMux.Handle("/v1/", http.StripPrefix("/v1", v1Mux))
+	apiMux.Handle("/v1/", http.StripPrefix("/v1", middlewares.RecordRoute(v1Mux)))
+
+	v1.RegisterV1Routes(v1Mux, usersHandler, authHandler, followHandler, hexHandler, likeHandler, sessionsHandler, identitiesHandler, tokensHandler)
+
+	// /metrics sits on the API listener unless an admin address is set
+	var adminSrv *http.Server
+	if cfg.HTTP.AdminAddr == "" {
+		rootMux.Handle("GET /metrics", metrics.Handler())
+	} else {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /metrics", metrics.Handler())
+		adminSrv = &http.Server{
+			Addr:              cfg.HTTP.AdminAddr,
+			Handler:           adminMux,
+			ReadHeaderTimeout: cfg.HTTP.ReadTimeout,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		}
+	}
 
-	v1.RegisterV1Routes(v1Mux, usersHandler, authHandler, followHandler, hexHandler, likeHandler)
+	csrf, err := middlewares.NewCSRFMiddleware(cfg.HTTP.Origins())
+	if err != nil {
+		fatal("configuring CSRF protection", err)
+	}
+	// RecordRoute labels the root routes (/healthz, /metrics, ...) and
+	// leaves /api/ to the API's own muxes
+	var handler http.Handler = csrf(middlewares.RecordRoute(rootMux))
+	handler = middlewares.NewMetricsMiddleware()(handler)
+	handler = middlewares.NewAccessLogMiddleware(logger)(handler)
+	// inside the forwarded-for middleware so spans record the client address
+	handler = otelhttp.NewHandler(handler, "http.server")
+	if cfg.HTTP.TrustForwardedFor {
+		handler = middlewares.NewForwardedForMiddleware()(handler)
+	}
 
 	srv := &http.Server{
-		Addr:         ":8080",
-		Handler:      rootMux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:         cfg.HTTP.Addr,
+		Handler:      handler,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	go platform.Reap(ctx, "session", cfg.Auth.Session.ReapInterval, func(ctx context.Context, now time.Time) (int64, error) {
+		createdBefore, verifiedBefore := sessionPolicy.Cutoffs(now)
+		return sessionStore.DeleteExpiredSessions(ctx, createdBefore, verifiedBefore)
+	})
+	go platform.Reap(ctx, "auth code", cfg.Auth.Session.ReapInterval, authCodeStore.DeleteExpiredAuthCodes)
+	go platform.Reap(ctx, "email token", cfg.Auth.Session.ReapInterval, emailTokenStore.DeleteExpiredEmailTokens)
+
 	go func() {
-		log.Printf("starting server on %s", srv.Addr)
+		slog.Info("starting server", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen failed: %v", err)
+			fatal("listen failed", err)
 		}
 	}()
+	if adminSrv != nil {
+		go func() {
+			slog.Info("starting admin server", "addr", adminSrv.Addr)
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("admin listen failed", err)
+			}
+		}()
+	}
 
 	<-ctx.Done()
-	log.Println("shutdown signal received")
+	slog.Info("shutdown signal received")
+	healthHandler.Drain()
+	if cfg.HTTP.DrainDelay > 0 {
+		slog.Info("draining", "delay", cfg.HTTP.DrainDelay)
+		time.Sleep(cfg.HTTP.DrainDelay)
+	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("graceful shutdown failed: %v", err)
+		slog.Error("graceful shutdown failed", "err", err)
 	} else {
-		log.Println("server stopped gracefully")
+		slog.Info("server stopped gracefully")
+	}
+	if adminSrv != nil {
+		_ = adminSrv.Shutdown(shutdownCtx)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("flushing traces failed", "err", err)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,48 @@
+// Command fakeidp serves a fake GitHub for local end-to-end auth testing.
+// Run the API against it with
+//
+//	GITHUB_URL=http://localhost:9090 GITHUB_API_URL=http://localhost:9090 \
+//	GITHUB_CLIENT_ID=fake-client-id GITHUB_CLIENT_SECRET=fake-client-secret
+//
+// Authorize requests are approved as the first user unless the URL carries
+// login=<name>, or deny=1 to refuse. Device codes are approved by opening
+// /login/device?user_code=XXXX-XXXX. Faults are installed with
+//
+//	curl -d endpoint=token -d status=502 -d times=1 localhost:9090/_fakeidp/faults
+//	curl -d endpoint=user -d delay=15s localhost:9090/_fakeidp/faults
+//	curl -X DELETE localhost:9090/_fakeidp/faults
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/fakeidp"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9090", "address to listen on")
+	clientID := flag.String("client-id", "fake-client-id", "OAuth app client id")
+	clientSecret := flag.String("client-secret", "fake-client-secret", "OAuth app client secret")
+	callback := flag.String("callback", "http://localhost:8080/api/v1/oauth/callback/github", "callback URL registered for the app")
+	users := flag.String("users", "octocat,hubot", "comma separated logins of the fake accounts")
+	interval := flag.Duration("device-interval", 5*time.Second, "initial device flow polling interval")
+	flag.Parse()
+
+	idp := fakeidp.New(*clientID, *clientSecret)
+	idp.CallbackURL = *callback
+	idp.DeviceInterval = *interval
+	for i, login := range strings.Split(*users, ",") {
+		login = strings.TrimSpace(login)
+		if login == "" {
+			continue
+		}
+		idp.AddUser(fakeidp.User{ID: int64(i + 1), Login: login, Email: login + "@example.com"})
+	}
+
+	log.Printf("fake GitHub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, idp))
+}
@@ -1,11 +1,144 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/db"
 	"github.com/HimanshuKumarDutt094/hextok/internal/db/migrations"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform"
 )
 
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up                 apply all pending migrations
+  down [-n steps]    revert the most recent migrations (default 1)
+  status             list migrations and whether they are applied
+  redo               revert and re-apply the most recent migration
+  reset --force      revert every migration and re-apply them (destroys all data)
+  rotate-keys        re-encrypt stored OAuth tokens with the active encryption key
+`
+
 func main() {
-	migrations.Drop()
-	migrations.Migrate()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := db.New(cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := migrations.NewMigrator(conn)
+
+	switch cmd {
+	case "up":
+		parseFlags(cmd, args)
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		steps := fs.Int("n", 1, "number of migrations to revert")
+		_ = fs.Parse(args)
+		n, err := m.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("migrate down: %v", err)
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+	case "status":
+		parseFlags(cmd, args)
+		st, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("migrate status: %v", err)
+		}
+		printStatus(st)
+	case "redo":
+		parseFlags(cmd, args)
+		if err := m.Redo(ctx); err != nil {
+			log.Fatalf("migrate redo: %v", err)
+		}
+		fmt.Println("redo complete")
+	case "reset":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		force := fs.Bool("force", false, "confirm that all data should be destroyed")
+		_ = fs.Parse(args)
+		err := m.Reset(ctx, *force)
+		if errors.Is(err, migrations.ErrResetNotForced) {
+			log.Fatal("reset drops every table and all data; re-run with --force to confirm")
+		}
+		if err != nil {
+			log.Fatalf("migrate reset: %v", err)
+		}
+		fmt.Println("database reset to latest schema")
+	case "rotate-keys":
+		fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+		batch := fs.Int("batch", 500, "rows re-encrypted per transaction")
+		_ = fs.Parse(args)
+		if *batch < 1 {
+			log.Fatal("rotate-keys: -batch must be positive")
+		}
+		keys, err := cfg.Auth.TokenEncryption.Keyring()
+		if err != nil {
+			log.Fatalf("rotate-keys: %v", err)
+		}
+		if keys == nil {
+			log.Fatal("rotate-keys: TOKEN_ENCRYPTION_KEYS is not set")
+		}
+		n, err := platform.NewOauthStore(conn, keys).RotateKeys(ctx, *batch)
+		if err != nil {
+			log.Fatalf("rotate-keys: %v (%d row(s) re-encrypted before the error)", err, n)
+		}
+		fmt.Printf("re-encrypted %d oauth row(s) with key %q\n", n, keys.Active())
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+func parseFlags(name string, args []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	_ = fs.Parse(args)
+}
 
+func printStatus(st []migrations.Status) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range st {
+		state := "pending"
+		appliedAt := "-"
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Unknown:
+			state = "unknown"
+		case s.Mismatch:
+			state = "checksum mismatch"
+		}
+		fmt.Fprintf(tw, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	_ = tw.Flush()
 }
@@ -3,18 +3,28 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
+	"os"
 	"time"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
 	"github.com/HimanshuKumarDutt094/hextok/internal/db"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/platform"
 )
 
 func main() {
-	database, err := db.New()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	database, err := db.New(cfg.Database)
 	if err != nil {
 		fmt.Println(err, "failed")
+		os.Exit(1)
 	}
 	store := platform.NewHexStore(database)
 	defer database.Close()
@@ -23,7 +33,10 @@ func main() {
 	const count = 100
 	for range count {
 		hex := getRandomHex()
-		_, err := store.CreateHex(ctx, hex)
+		_, err := store.CreateHex(ctx, hex, 0)
+		if errors.Is(err, domains.ErrAlreadyExists) {
+			continue
+		}
 		if err != nil {
 			fmt.Printf("insert error %v\n", err)
 			continue
@@ -37,5 +50,5 @@ func getRandomHex() string {
 	if err != nil {
 		return "#000000"
 	}
-	return fmt.Sprintf("#%06X", n.Int64())
+	return fmt.Sprintf("#%06x", n.Int64())
 }
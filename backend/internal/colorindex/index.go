@@ -0,0 +1,166 @@
+package colorindex
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+type Metric string
+
+const (
+	MetricCIE76     Metric = "cie76"
+	MetricCIEDE2000 Metric = "ciede2000"
+)
+
+// ParseMetric maps a query parameter to a Metric, defaulting to CIEDE2000.
+func ParseMetric(s string) (Metric, error) {
+	switch Metric(s) {
+	case "", MetricCIEDE2000:
+		return MetricCIEDE2000, nil
+	case MetricCIE76:
+		return MetricCIE76, nil
+	}
+	return "", fmt.Errorf("unknown metric %q (want %s or %s)", s, MetricCIEDE2000, MetricCIE76)
+}
+
+// ciede2000Oversample is how many CIE76 neighbours are re-ranked per
+// requested CIEDE2000 result. The two metrics agree closely for nearby
+// colors but not exactly; at 4x a fifth of random queries came back in a
+// different order than a full CIEDE2000 scan, at 16x none did (see
+// TestIndexCIEDE2000).
+const ciede2000Oversample = 16
+
+// Index is a Tree loaded lazily from the hex table. Hexes created through
+// this process are added immediately; the whole tree is rebuilt once it is
+// older than maxAge to pick up rows written by other instances.
+//
+// A rebuild loads and builds the new tree without holding mu, so searches
+// and Add carry on against the old tree meanwhile; only the swap is
+// exclusive.
+type Index struct {
+	load   func(ctx context.Context) ([]domains.HexLab, error)
+	maxAge time.Duration
+
+	// loading is held by the one request rebuilding the tree.
+	loading sync.Mutex
+
+	mu      sync.RWMutex
+	tree    *Tree
+	builtAt time.Time
+	// added collects hexes added during a rebuild, which the load may
+	// have missed. It is nil when no rebuild is running.
+	added []domains.HexLab
+}
+
+func New(load func(ctx context.Context) ([]domains.HexLab, error), maxAge time.Duration) *Index {
+	return &Index{load: load, maxAge: maxAge}
+}
+
+// Add inserts a newly created hex. Before the first load it is a no-op,
+// since the load will include the row, unless that load is already running.
+func (ix *Index) Add(p domains.HexLab) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.tree != nil {
+		ix.tree.Insert(p)
+	}
+	if ix.added != nil {
+		ix.added = append(ix.added, p)
+	}
+}
+
+// Nearest returns up to k hexes closest to target under metric. CIE76
+// results are exact. CIEDE2000 results are approximate: the nearest
+// k*ciede2000Oversample by CIE76 are re-ranked.
+func (ix *Index) Nearest(ctx context.Context, target domains.Lab, k int, metric Metric) ([]Match, error) {
+	tree, err := ix.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if metric != MetricCIEDE2000 {
+		return tree.Nearest(target, k), nil
+	}
+	candidates := tree.Nearest(target, k*ciede2000Oversample)
+	for i := range candidates {
+		candidates[i].Distance = domains.DeltaE2000(target, candidates[i].Lab)
+	}
+	slices.SortStableFunc(candidates, func(a, b Match) int {
+		switch {
+		case a.Distance < b.Distance:
+			return -1
+		case a.Distance > b.Distance:
+			return 1
+		}
+		return 0
+	})
+	return candidates[:min(k, len(candidates))], nil
+}
+
+// current returns the tree to search, rebuilding it when it is older than
+// maxAge. While one request rebuilds, the others keep searching the stale
+// tree; only before the first load do they wait for it.
+func (ix *Index) current(ctx context.Context) (*Tree, error) {
+	ix.mu.RLock()
+	tree, builtAt := ix.tree, ix.builtAt
+	ix.mu.RUnlock()
+	if tree != nil && time.Since(builtAt) < ix.maxAge {
+		return tree, nil
+	}
+
+	if tree == nil {
+		ix.loading.Lock()
+	} else if !ix.loading.TryLock() {
+		return tree, nil
+	}
+	defer ix.loading.Unlock()
+
+	ix.mu.Lock()
+	// another request may have rebuilt while we waited for the lock
+	if ix.tree != nil && time.Since(ix.builtAt) < ix.maxAge {
+		tree := ix.tree
+		ix.mu.Unlock()
+		return tree, nil
+	}
+	ix.added = []domains.HexLab{}
+	ix.mu.Unlock()
+
+	started := time.Now()
+	points, err := ix.load(ctx)
+	var next *Tree
+	if err == nil {
+		next = Build(points)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	added := ix.added
+	ix.added = nil
+	if err != nil {
+		if ix.tree != nil {
+			// keep serving the stale tree rather than failing searches
+			return ix.tree, nil
+		}
+		return nil, err
+	}
+	if len(added) > 0 {
+		loaded := make(map[int64]bool, len(points))
+		for _, p := range points {
+			loaded[p.Id] = true
+		}
+		for _, p := range added {
+			if !loaded[p.Id] {
+				next.Insert(p)
+			}
+		}
+	}
+	ix.tree, ix.builtAt = next, started
+	return next, nil
+}
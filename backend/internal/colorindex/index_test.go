@@ -0,0 +1,138 @@
+package colorindex
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func hexLab(id int64, hex string) domains.HexLab {
+	c, err := domains.ParseColor(hex)
+	if err != nil {
+		panic(err)
+	}
+	return domains.HexLab{Hex: domains.Hex{Id: id, HexValue: hex}, Lab: c.Lab()}
+}
+
+func ids(ms []Match) []int64 {
+	out := make([]int64, len(ms))
+	for i, m := range ms {
+		out[i] = m.Id
+	}
+	slices.Sort(out)
+	return out
+}
+
+// expire makes the next search rebuild the tree.
+func (ix *Index) expire() {
+	ix.mu.Lock()
+	ix.builtAt = time.Time{}
+	ix.mu.Unlock()
+}
+
+func TestIndexRebuildDoesNotBlock(t *testing.T) {
+	red, green, blue := hexLab(1, "#ff0000"), hexLab(2, "#00ff00"), hexLab(3, "#0000ff")
+	entered, release := make(chan struct{}), make(chan []domains.HexLab)
+	calls := 0
+	ix := New(func(context.Context) ([]domains.HexLab, error) {
+		calls++
+		if calls == 1 {
+			return []domains.HexLab{red}, nil
+		}
+		entered <- struct{}{}
+		return <-release, nil
+	}, time.Hour)
+	ctx := context.Background()
+
+	if _, err := ix.Nearest(ctx, red.Lab, 10, MetricCIE76); err != nil {
+		t.Fatal(err)
+	}
+	ix.expire()
+	rebuilt := make(chan error)
+	go func() {
+		_, err := ix.Nearest(ctx, red.Lab, 10, MetricCIE76)
+		rebuilt <- err
+	}()
+	<-entered
+
+	// while the rebuild loads, searches use the old tree and Add goes on
+	got, err := ix.Nearest(ctx, red.Lab, 10, MetricCIE76)
+	if err != nil || !slices.Equal(ids(got), []int64{1}) {
+		t.Fatalf("Nearest during rebuild = %v, %v, want the stale tree", ids(got), err)
+	}
+	ix.Add(green)
+	ix.Add(blue)
+	if got, _ := ix.Nearest(ctx, red.Lab, 10, MetricCIE76); !slices.Equal(ids(got), []int64{1, 2, 3}) {
+		t.Errorf("Nearest after Add during rebuild = %v, want 1 2 3", ids(got))
+	}
+
+	// the load saw blue, written before it ran, but not green
+	release <- []domains.HexLab{red, blue}
+	if err := <-rebuilt; err != nil {
+		t.Fatal(err)
+	}
+	got, _ = ix.Nearest(ctx, red.Lab, 10, MetricCIE76)
+	if !slices.Equal(ids(got), []int64{1, 2, 3}) {
+		t.Errorf("Nearest after rebuild = %v, want 1 2 3 once each", ids(got))
+	}
+	if calls != 2 {
+		t.Errorf("load ran %d times, want 2", calls)
+	}
+}
+
+func TestIndexFirstLoadWaits(t *testing.T) {
+	release := make(chan struct{})
+	calls := 0
+	ix := New(func(context.Context) ([]domains.HexLab, error) {
+		calls++
+		<-release
+		return []domains.HexLab{hexLab(1, "#ff0000")}, nil
+	}, time.Hour)
+
+	done := make(chan []Match, 2)
+	for range 2 {
+		go func() {
+			got, _ := ix.Nearest(context.Background(), domains.Lab{}, 1, MetricCIE76)
+			done <- got
+		}()
+	}
+	close(release)
+	for range 2 {
+		if got := <-done; len(got) != 1 {
+			t.Errorf("Nearest = %v, want the loaded hex", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("load ran %d times, want once", calls)
+	}
+}
+
+func TestIndexLoadError(t *testing.T) {
+	fail := errors.New("db down")
+	var err error
+	ix := New(func(context.Context) ([]domains.HexLab, error) {
+		if err != nil {
+			return nil, err
+		}
+		return []domains.HexLab{hexLab(1, "#ff0000")}, nil
+	}, time.Hour)
+	ctx := context.Background()
+
+	err = fail
+	if _, got := ix.Nearest(ctx, domains.Lab{}, 1, MetricCIE76); !errors.Is(got, fail) {
+		t.Fatalf("Nearest before any load = %v, want %v", got, fail)
+	}
+	err = nil
+	if got, _ := ix.Nearest(ctx, domains.Lab{}, 1, MetricCIE76); len(got) != 1 {
+		t.Fatalf("Nearest = %v, want the loaded hex", got)
+	}
+	ix.expire()
+	err = fail
+	if got, e := ix.Nearest(ctx, domains.Lab{}, 1, MetricCIE76); e != nil || len(got) != 1 {
+		t.Errorf("Nearest with a failing rebuild = %v, %v, want the stale tree", got, e)
+	}
+}
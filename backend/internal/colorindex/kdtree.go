@@ -0,0 +1,146 @@
+// Package colorindex answers nearest-color queries over the stored hexes
+// with a k-d tree in CIE Lab space, so similarity search does not scan the
+// whole hex table on every request.
+package colorindex
+
+import (
+	"container/heap"
+	"slices"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+type node struct {
+	point       domains.HexLab
+	axis        int
+	left, right *node
+}
+
+// Tree is a static 3-d tree over Lab coordinates. Insert keeps it usable
+// between rebuilds but does not rebalance.
+type Tree struct {
+	root *node
+	size int
+}
+
+// Build constructs a balanced tree from points.
+func Build(points []domains.HexLab) *Tree {
+	ps := slices.Clone(points)
+	return &Tree{root: build(ps, 0), size: len(ps)}
+}
+
+func build(ps []domains.HexLab, depth int) *node {
+	if len(ps) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	slices.SortFunc(ps, func(a, b domains.HexLab) int {
+		av, bv := coord(a.Lab, axis), coord(b.Lab, axis)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	})
+	mid := len(ps) / 2
+	return &node{
+		point: ps[mid],
+		axis:  axis,
+		left:  build(ps[:mid], depth+1),
+		right: build(ps[mid+1:], depth+1),
+	}
+}
+
+func (t *Tree) Len() int {
+	return t.size
+}
+
+// Insert adds a point below the existing leaves.
+func (t *Tree) Insert(p domains.HexLab) {
+	t.size++
+	link := &t.root
+	depth := 0
+	for *link != nil {
+		n := *link
+		if coord(p.Lab, n.axis) < coord(n.point.Lab, n.axis) {
+			link = &n.left
+		} else {
+			link = &n.right
+		}
+		depth++
+	}
+	*link = &node{point: p, axis: depth % 3}
+}
+
+// Match is a search result with its distance from the query color.
+type Match struct {
+	domains.HexLab
+	Distance float64
+}
+
+// Nearest returns the k points closest to target by Euclidean distance in
+// Lab, i.e. CIE76, ordered nearest first.
+func (t *Tree) Nearest(target domains.Lab, k int) []Match {
+	if k <= 0 || t.root == nil {
+		return nil
+	}
+	h := &maxHeap{}
+	t.search(t.root, target, k, h)
+	out := make([]Match, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(Match)
+	}
+	return out
+}
+
+func (t *Tree) search(n *node, target domains.Lab, k int, h *maxHeap) {
+	if n == nil {
+		return
+	}
+	d := domains.DeltaE76(target, n.point.Lab)
+	if h.Len() < k {
+		heap.Push(h, Match{HexLab: n.point, Distance: d})
+	} else if d < (*h)[0].Distance {
+		(*h)[0] = Match{HexLab: n.point, Distance: d}
+		heap.Fix(h, 0)
+	}
+
+	diff := coord(target, n.axis) - coord(n.point.Lab, n.axis)
+	near, far := n.left, n.right
+	if diff >= 0 {
+		near, far = n.right, n.left
+	}
+	t.search(near, target, k, h)
+	// the far side can only help if the splitting plane is closer than the
+	// current k-th best
+	if h.Len() < k || diff*diff < (*h)[0].Distance*(*h)[0].Distance {
+		t.search(far, target, k, h)
+	}
+}
+
+func coord(l domains.Lab, axis int) float64 {
+	switch axis {
+	case 0:
+		return l.L
+	case 1:
+		return l.A
+	}
+	return l.B
+}
+
+// maxHeap keeps the current k best matches with the worst on top.
+type maxHeap []Match
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].Distance > h[j].Distance }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(Match)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
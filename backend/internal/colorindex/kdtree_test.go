@@ -0,0 +1,115 @@
+package colorindex
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// randomPoints returns n random colors. With coarse set, coordinates are
+// rounded so many points tie on an axis.
+func randomPoints(rng *rand.Rand, n int, coarse bool) []domains.HexLab {
+	ps := make([]domains.HexLab, n)
+	for i := range ps {
+		c := domains.Color{R: uint8(rng.IntN(256)), G: uint8(rng.IntN(256)), B: uint8(rng.IntN(256)), A: 0xff}
+		lab := c.Lab()
+		if coarse {
+			lab = domains.Lab{L: math.Round(lab.L / 10), A: math.Round(lab.A / 10), B: math.Round(lab.B / 10)}
+		}
+		ps[i] = domains.HexLab{Hex: domains.Hex{Id: int64(i + 1), HexValue: c.Hex()}, Lab: lab}
+	}
+	return ps
+}
+
+// bruteForce ranks every point by metric.
+func bruteForce(points []domains.HexLab, target domains.Lab, k int, metric domains.ColorMetric) []float64 {
+	d := make([]float64, len(points))
+	for i, p := range points {
+		d[i] = metric(target, p.Lab)
+	}
+	slices.Sort(d)
+	return d[:min(k, len(d))]
+}
+
+func distances(ms []Match) []float64 {
+	d := make([]float64, len(ms))
+	for i, m := range ms {
+		d[i] = m.Distance
+	}
+	return d
+}
+
+func TestNearestMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, coarse := range []bool{false, true} {
+		points := randomPoints(rng, 2000, coarse)
+		tree := Build(points[:1500])
+		// points added after the build hang off the leaves
+		for _, p := range points[1500:] {
+			tree.Insert(p)
+		}
+		if tree.Len() != len(points) {
+			t.Fatalf("Len = %d, want %d", tree.Len(), len(points))
+		}
+
+		for q := range 300 {
+			target := randomPoints(rng, 1, coarse)[0].Lab
+			k := []int{1, 5, 20, 2500}[q%4]
+			got := tree.Nearest(target, k)
+			want := bruteForce(points, target, k, domains.DeltaE76)
+			if !slices.Equal(distances(got), want) {
+				t.Fatalf("coarse=%v: Nearest(%+v, %d) distances = %v, want %v", coarse, target, k, distances(got), want)
+			}
+			seen := make(map[int64]bool, len(got))
+			for _, m := range got {
+				if seen[m.Id] {
+					t.Fatalf("Nearest returned hex %d twice", m.Id)
+				}
+				seen[m.Id] = true
+				if m.Distance != domains.DeltaE76(target, m.Lab) {
+					t.Fatalf("match %d has distance %v, want its DeltaE76", m.Id, m.Distance)
+				}
+			}
+		}
+	}
+}
+
+func TestNearestEmpty(t *testing.T) {
+	if got := Build(nil).Nearest(domains.Lab{}, 3); len(got) != 0 {
+		t.Errorf("Nearest on empty tree = %v", got)
+	}
+	tree := Build(randomPoints(rand.New(rand.NewPCG(3, 4)), 10, false))
+	if got := tree.Nearest(domains.Lab{}, 0); len(got) != 0 {
+		t.Errorf("Nearest(k=0) = %v", got)
+	}
+}
+
+func TestIndexCIEDE2000(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	points := randomPoints(rng, 2000, false)
+	ix := New(func(context.Context) ([]domains.HexLab, error) { return points, nil }, time.Hour)
+
+	const k = 10
+	for range 200 {
+		target := randomPoints(rng, 1, false)[0].Lab
+		got, err := ix.Nearest(context.Background(), target, k, MetricCIEDE2000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != k {
+			t.Fatalf("Nearest returned %d matches, want %d", len(got), k)
+		}
+		if !slices.IsSortedFunc(got, func(a, b Match) int { return cmp.Compare(a.Distance, b.Distance) }) {
+			t.Fatalf("matches are not ordered by CIEDE2000: %v", distances(got))
+		}
+		if want := bruteForce(points, target, k, domains.DeltaE2000); !slices.Equal(distances(got), want) {
+			t.Fatalf("Nearest(%+v) CIEDE2000 distances = %v, brute force found %v", target, distances(got), want)
+		}
+	}
+}
@@ -0,0 +1,494 @@
+// Package config loads the typed runtime configuration for the hextok
+// binaries. Values are resolved in order of increasing precedence: built-in
+// defaults, an optional YAML file, environment variables (a .env file in the
+// working directory is honoured) and finally command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/keyring"
+)
+
+type Config struct {
+	HTTP     HTTP     `yaml:"http"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Mail     Mail     `yaml:"mail"`
+	Log      Log      `yaml:"log"`
+	Tracing  Tracing  `yaml:"tracing"`
+}
+
+type HTTP struct {
+	Addr            string        `yaml:"addr" env:"HTTP_ADDR" flag:"addr" usage:"address the API server listens on"`
+	ReadTimeout     time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// TrustForwardedFor takes the client address from X-Forwarded-For. Only
+	// enable it behind a reverse proxy that sets the header.
+	TrustForwardedFor bool `yaml:"trustForwardedFor" env:"HTTP_TRUST_FORWARDED_FOR"`
+	// TrustedOrigins is a comma separated list of origins, besides the API's
+	// own, allowed to make cookie-authenticated requests that change state,
+	// e.g. "https://app.example.com".
+	TrustedOrigins string `yaml:"trustedOrigins" env:"HTTP_TRUSTED_ORIGINS"`
+	// AdminAddr, when set, moves /metrics off the API listener onto a
+	// separate one, e.g. "127.0.0.1:9091", so it need not be exposed
+	// publicly.
+	AdminAddr string `yaml:"adminAddr" env:"HTTP_ADMIN_ADDR" flag:"admin-addr" usage:"address for the admin listener serving /metrics"`
+	// DrainDelay is how long the server keeps serving after SIGTERM, with
+	// /readyz failing, before it stops accepting connections. Set it to a
+	// little over the load balancer's probe interval; zero shuts down
+	// straight away.
+	DrainDelay time.Duration `yaml:"drainDelay" env:"HTTP_DRAIN_DELAY"`
+}
+
+// Origins splits TrustedOrigins.
+func (h HTTP) Origins() []string {
+	var origins []string
+	for o := range strings.SplitSeq(h.TrustedOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+type Database struct {
+	// DSN, when set, is used verbatim and the individual connection fields
+	// below are ignored.
+	DSN              string        `yaml:"dsn" env:"DATABASE_URL" flag:"db-dsn" usage:"postgres connection string"`
+	Host             string        `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"postgres host"`
+	Port             int           `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"postgres port"`
+	User             string        `yaml:"user" env:"DB_USER"`
+	Password         string        `yaml:"password" env:"DB_PASSWORD"`
+	Name             string        `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"postgres database name"`
+	SSLMode          string        `yaml:"sslMode" env:"DB_SSLMODE"`
+	StatementTimeout time.Duration `yaml:"statementTimeout" env:"DB_STATEMENT_TIMEOUT"`
+	MaxOpenConns     int           `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns     int           `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime  time.Duration `yaml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime  time.Duration `yaml:"connMaxIdleTime" env:"DB_CONN_MAX_IDLE_TIME"`
+	PingTimeout      time.Duration `yaml:"pingTimeout" env:"DB_PING_TIMEOUT"`
+}
+
+type Auth struct {
+	BaseURL            string `yaml:"baseURL" env:"BASE_URL" flag:"base-url" usage:"public base URL of the API"`
+	GithubClientID     string `yaml:"githubClientID" env:"GITHUB_CLIENT_ID"`
+	GithubClientSecret string `yaml:"githubClientSecret" env:"GITHUB_CLIENT_SECRET"`
+	GoogleClientID     string `yaml:"googleClientID" env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `yaml:"googleClientSecret" env:"GOOGLE_CLIENT_SECRET"`
+	OIDC               OIDC   `yaml:"oidc"`
+	// StateKey signs OAuth states and email login links. It is required
+	// and must be at least 32 bytes, e.g. from "openssl rand -base64 32".
+	StateKey string `yaml:"stateKey" env:"OAUTH_STATE_KEY"`
+	// GithubURL, GithubAPIURL and GoogleIssuer locate the built-in
+	// providers. Override them to log in against a stand-in such as
+	// cmd/fakeidp.
+	GithubURL    string `yaml:"githubURL" env:"GITHUB_URL"`
+	GithubAPIURL string `yaml:"githubAPIURL" env:"GITHUB_API_URL"`
+	GoogleIssuer string `yaml:"googleIssuer" env:"GOOGLE_ISSUER"`
+	// RequirePKCE rejects mobile logins that do not use PKCE (S256).
+	RequirePKCE bool    `yaml:"requirePKCE" env:"OAUTH_REQUIRE_PKCE" flag:"require-pkce" usage:"reject mobile OAuth flows without a PKCE code challenge"`
+	Session     Session `yaml:"session"`
+	// TokenEncryption encrypts stored provider tokens.
+	TokenEncryption TokenEncryption `yaml:"tokenEncryption"`
+}
+
+// TokenEncryption configures how OAuth provider tokens are encrypted at
+// rest. Without keys they are stored in plaintext.
+type TokenEncryption struct {
+	// Keys is a comma separated list of id:key pairs, each key 32 random
+	// bytes in standard base64. Keep a retired key listed until
+	// "migrate rotate-keys" has moved every row off it.
+	Keys string `yaml:"keys" env:"TOKEN_ENCRYPTION_KEYS"`
+	// ActiveKey is the id new tokens are encrypted with. It may be left
+	// empty when only one key is listed.
+	ActiveKey string `yaml:"activeKey" env:"TOKEN_ENCRYPTION_ACTIVE_KEY"`
+}
+
+// OIDC configures a generic OpenID Connect login provider. It is enabled
+// when Issuer is set.
+type OIDC struct {
+	// Name is the provider's key in the /oauth/start/{provider} routes.
+	Name         string `yaml:"name" env:"OIDC_PROVIDER_NAME"`
+	Issuer       string `yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string `yaml:"clientID" env:"OIDC_CLIENT_ID"`
+	ClientSecret string `yaml:"clientSecret" env:"OIDC_CLIENT_SECRET"`
+	// Scopes is space separated; "openid" is always requested.
+	Scopes string `yaml:"scopes" env:"OIDC_SCOPES"`
+}
+
+// Mail configures outgoing email. Without an SMTP host messages are written
+// to Dir when it is set, or else printed to stderr with their login links
+// unmasked, for development.
+type Mail struct {
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	SMTPHost     string `yaml:"smtpHost" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtpPort" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtpUsername" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtpPassword" env:"SMTP_PASSWORD"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"`
+}
+
+// Log configures the process logger.
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum level logged: debug, info, warn or error"`
+	// Format is json, or text for reading logs in a terminal.
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// SlogLevel parses Level.
+func (l Log) SlogLevel() (slog.Level, error) {
+	var lv slog.Level
+	err := lv.UnmarshalText([]byte(l.Level))
+	return lv, err
+}
+
+// Tracing configures OpenTelemetry tracing.
+type Tracing struct {
+	// Exporter is "none", "stdout" for local debugging, or "otlp" to send
+	// spans to a collector over OTLP/HTTP.
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"span exporter: none, stdout or otlp"`
+	// Endpoint is the collector's URL, e.g. "http://localhost:4318". When
+	// empty the standard OTEL_EXPORTER_OTLP_* variables are used.
+	Endpoint string `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	// SampleRatio is the share of new traces recorded. Requests that arrive
+	// with a traceparent follow the caller's decision.
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"serviceName" env:"TRACING_SERVICE_NAME"`
+}
+
+// Session controls how long login sessions stay valid on the server.
+type Session struct {
+	AbsoluteTTL   time.Duration `yaml:"absoluteTTL" env:"SESSION_ABSOLUTE_TTL"`
+	IdleTTL       time.Duration `yaml:"idleTTL" env:"SESSION_IDLE_TTL"`
+	RenewInterval time.Duration `yaml:"renewInterval" env:"SESSION_RENEW_INTERVAL"`
+	ReapInterval  time.Duration `yaml:"reapInterval" env:"SESSION_REAP_INTERVAL"`
+}
+
+func (s Session) Policy() domains.SessionPolicy {
+	return domains.SessionPolicy{
+		AbsoluteTTL: s.AbsoluteTTL,
+		IdleTTL:     s.IdleTTL,
+		RenewAfter:  s.RenewInterval,
+	}
+}
+
+// Keyring builds the configured keys. It returns nil when encryption is off.
+func (t TokenEncryption) Keyring() (*keyring.Keyring, error) {
+	if t.Keys == "" {
+		if t.ActiveKey != "" {
+			return nil, errors.New("activeKey is set but no keys are")
+		}
+		return nil, nil
+	}
+	keys, err := keyring.ParseKeys(t.Keys)
+	if err != nil {
+		return nil, err
+	}
+	active := t.ActiveKey
+	if active == "" {
+		if len(keys) > 1 {
+			return nil, errors.New("activeKey is required when several keys are listed")
+		}
+		for id := range keys {
+			active = id
+		}
+	}
+	return keyring.New(active, keys)
+}
+
+// Default returns the configuration used when nothing else is provided. It
+// matches the local docker-compose style setup used in development.
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:            ":8080",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		Database: Database{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "root",
+			Password:        "password",
+			Name:            "hextok",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			PingTimeout:     5 * time.Second,
+		},
+		Auth: Auth{
+			GithubURL:    "https://github.com",
+			GithubAPIURL: "https://api.github.com",
+			GoogleIssuer: "https://accounts.google.com",
+			OIDC: OIDC{
+				Name:   "oidc",
+				Scopes: "openid email profile",
+			},
+			Session: Session{
+				AbsoluteTTL:   30 * 24 * time.Hour,
+				IdleTTL:       7 * 24 * time.Hour,
+				RenewInterval: time.Hour,
+				ReapInterval:  time.Hour,
+			},
+		},
+		Mail: Mail{
+			From:     "hextok <no-reply@localhost>",
+			SMTPPort: 587,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "hextok-api",
+		},
+	}
+}
+
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	tracingExporters = []string{"none", "stdout", "otlp"}
+)
+
+// minStateKeyLen is the shortest auth.stateKey accepted, the size of the
+// HMAC-SHA256 key it is used as.
+const minStateKeyLen = 32
+
+var (
+	providerName          = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	reservedProviderNames = []string{"github", "google", "email"}
+)
+
+// Validate reports every invalid setting at once so a misconfigured
+// deployment fails at startup with a complete list of problems.
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		bad("http.addr", "must be host:port, got %q", c.HTTP.Addr)
+	}
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"http.readTimeout", c.HTTP.ReadTimeout},
+		{"http.writeTimeout", c.HTTP.WriteTimeout},
+		{"http.idleTimeout", c.HTTP.IdleTimeout},
+		{"http.shutdownTimeout", c.HTTP.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			bad(t.name, "must be positive, got %s", t.d)
+		}
+	}
+
+	if c.HTTP.DrainDelay < 0 {
+		bad("http.drainDelay", "must not be negative, got %s", c.HTTP.DrainDelay)
+	}
+
+	if c.HTTP.AdminAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.AdminAddr); err != nil {
+			bad("http.adminAddr", "must be host:port, got %q", c.HTTP.AdminAddr)
+		} else if c.HTTP.AdminAddr == c.HTTP.Addr {
+			bad("http.adminAddr", "must differ from http.addr")
+		}
+	}
+
+	for _, o := range c.HTTP.Origins() {
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			bad("http.trustedOrigins", "must be scheme://host[:port] origins, got %q", o)
+		}
+	}
+
+	db := c.Database
+	if db.DSN == "" {
+		if db.Host == "" {
+			bad("database.host", "is required")
+		}
+		if db.Port <= 0 || db.Port > 65535 {
+			bad("database.port", "must be between 1 and 65535, got %d", db.Port)
+		}
+		if db.User == "" {
+			bad("database.user", "is required")
+		}
+		if db.Name == "" {
+			bad("database.name", "is required")
+		}
+		if !slices.Contains(sslModes, db.SSLMode) {
+			bad("database.sslMode", "must be one of %s, got %q", strings.Join(sslModes, ", "), db.SSLMode)
+		}
+	}
+	if db.StatementTimeout < 0 {
+		bad("database.statementTimeout", "must not be negative, got %s", db.StatementTimeout)
+	}
+	if db.MaxOpenConns < 0 {
+		bad("database.maxOpenConns", "must not be negative, got %d", db.MaxOpenConns)
+	}
+	if db.MaxIdleConns < 0 {
+		bad("database.maxIdleConns", "must not be negative, got %d", db.MaxIdleConns)
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		bad("database.maxIdleConns", "(%d) must not exceed maxOpenConns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	if db.ConnMaxLifetime < 0 {
+		bad("database.connMaxLifetime", "must not be negative, got %s", db.ConnMaxLifetime)
+	}
+	if db.ConnMaxIdleTime < 0 {
+		bad("database.connMaxIdleTime", "must not be negative, got %s", db.ConnMaxIdleTime)
+	}
+	if db.PingTimeout <= 0 {
+		bad("database.pingTimeout", "must be positive, got %s", db.PingTimeout)
+	}
+
+	if c.Auth.BaseURL != "" {
+		u, err := url.Parse(c.Auth.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			bad("auth.baseURL", "must be an absolute URL, got %q", c.Auth.BaseURL)
+		} else if strings.HasSuffix(c.Auth.BaseURL, "/") {
+			bad("auth.baseURL", "must not end with a slash, got %q", c.Auth.BaseURL)
+		}
+	}
+
+	switch {
+	case c.Auth.StateKey == "":
+		bad("auth.stateKey", "is required")
+	case len(c.Auth.StateKey) < minStateKeyLen:
+		bad("auth.stateKey", "must be at least %d bytes, got %d", minStateKeyLen, len(c.Auth.StateKey))
+	}
+
+	for _, f := range []struct {
+		name, v string
+	}{
+		{"auth.githubURL", c.Auth.GithubURL},
+		{"auth.githubAPIURL", c.Auth.GithubAPIURL},
+		{"auth.googleIssuer", c.Auth.GoogleIssuer},
+	} {
+		if u, err := url.Parse(f.v); err != nil || u.Scheme == "" || u.Host == "" {
+			bad(f.name, "must be an absolute URL, got %q", f.v)
+		}
+	}
+
+	if oidc := c.Auth.OIDC; oidc.Issuer != "" {
+		u, err := url.Parse(oidc.Issuer)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			bad("auth.oidc.issuer", "must be an absolute URL, got %q", oidc.Issuer)
+		}
+		if oidc.ClientID == "" {
+			bad("auth.oidc.clientID", "is required when an issuer is set")
+		}
+		if !providerName.MatchString(oidc.Name) {
+			bad("auth.oidc.name", "must be lower case letters, digits and dashes, got %q", oidc.Name)
+		} else if slices.Contains(reservedProviderNames, oidc.Name) {
+			bad("auth.oidc.name", "%q is reserved for a built-in provider", oidc.Name)
+		}
+	}
+
+	if _, err := c.Auth.TokenEncryption.Keyring(); err != nil {
+		bad("auth.tokenEncryption", "%v", err)
+	}
+
+	sess := c.Auth.Session
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"auth.session.absoluteTTL", sess.AbsoluteTTL},
+		{"auth.session.idleTTL", sess.IdleTTL},
+		{"auth.session.renewInterval", sess.RenewInterval},
+		{"auth.session.reapInterval", sess.ReapInterval},
+	} {
+		if t.d <= 0 {
+			bad(t.name, "must be positive, got %s", t.d)
+		}
+	}
+	if sess.IdleTTL > sess.AbsoluteTTL {
+		bad("auth.session.idleTTL", "(%s) must not exceed absoluteTTL (%s)", sess.IdleTTL, sess.AbsoluteTTL)
+	}
+	if sess.RenewInterval >= sess.IdleTTL {
+		bad("auth.session.renewInterval", "(%s) must be shorter than idleTTL (%s)", sess.RenewInterval, sess.IdleTTL)
+	}
+
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		bad("mail.from", "must be an email address, got %q", c.Mail.From)
+	}
+	if c.Mail.SMTPHost != "" && (c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535) {
+		bad("mail.smtpPort", "must be between 1 and 65535, got %d", c.Mail.SMTPPort)
+	}
+
+	if _, err := c.Log.SlogLevel(); err != nil {
+		bad("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		bad("log.format", "must be json or text, got %q", c.Log.Format)
+	}
+
+	tr := c.Tracing
+	if !slices.Contains(tracingExporters, tr.Exporter) {
+		bad("tracing.exporter", "must be one of %s, got %q", strings.Join(tracingExporters, ", "), tr.Exporter)
+	}
+	if tr.Endpoint != "" {
+		u, err := url.Parse(tr.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad("tracing.endpoint", "must be an http(s) URL, got %q", tr.Endpoint)
+		}
+	}
+	if tr.SampleRatio < 0 || tr.SampleRatio > 1 {
+		bad("tracing.sampleRatio", "must be between 0 and 1, got %g", tr.SampleRatio)
+	}
+	if tr.Exporter != "none" && tr.ServiceName == "" {
+		bad("tracing.serviceName", "is required when tracing is enabled")
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConnString builds the lib/pq connection string for the database settings.
+// Unknown keys such as statement_timeout are forwarded by the driver as
+// run-time parameters for every connection.
+func (d Database) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	parts := []string{
+		"host=" + quoteDSN(d.Host),
+		"port=" + strconv.Itoa(d.Port),
+		"user=" + quoteDSN(d.User),
+		"password=" + quoteDSN(d.Password),
+		"dbname=" + quoteDSN(d.Name),
+		"sslmode=" + quoteDSN(d.SSLMode),
+	}
+	if d.StatementTimeout > 0 {
+		parts = append(parts, "statement_timeout="+strconv.FormatInt(d.StatementTimeout.Milliseconds(), 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteDSN(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
+}
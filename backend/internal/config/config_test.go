@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testStateKey = "0123456789abcdef0123456789abcdef"
+
+// valid returns the defaults with the settings that have none filled in.
+func valid() Config {
+	c := Default()
+	c.Auth.StateKey = testStateKey
+	return c
+}
+
+func TestDefaultIsValidWithStateKey(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  func(c *Config)
+		want string
+	}{
+		{"no state key", func(c *Config) { c.Auth.StateKey = "" }, "auth.stateKey: is required"},
+		{"short state key", func(c *Config) { c.Auth.StateKey = "too-short" }, "auth.stateKey: must be at least 32 bytes, got 9"},
+		{"bad addr", func(c *Config) { c.HTTP.Addr = "8080" }, `http.addr: must be host:port, got "8080"`},
+		{"zero timeout", func(c *Config) { c.HTTP.ReadTimeout = 0 }, "http.readTimeout: must be positive, got 0s"},
+		{"admin addr clash", func(c *Config) { c.HTTP.AdminAddr = c.HTTP.Addr }, "http.adminAddr: must differ from http.addr"},
+		{"origin with path", func(c *Config) { c.HTTP.TrustedOrigins = "https://app.test/x" }, `http.trustedOrigins: must be scheme://host[:port] origins, got "https://app.test/x"`},
+		{"no db host", func(c *Config) { c.Database.Host = "" }, "database.host: is required"},
+		{"db host ignored with dsn", func(c *Config) { c.Database.Host, c.Database.DSN = "", "postgres://db" }, ""},
+		{"bad ssl mode", func(c *Config) { c.Database.SSLMode = "always" }, `database.sslMode: must be one of disable, allow, prefer, require, verify-ca, verify-full, got "always"`},
+		{"idle over open conns", func(c *Config) { c.Database.MaxIdleConns = 30 }, "database.maxIdleConns: (30) must not exceed maxOpenConns (25)"},
+		{"base url slash", func(c *Config) { c.Auth.BaseURL = "https://api.test/" }, `auth.baseURL: must not end with a slash, got "https://api.test/"`},
+		{"relative github url", func(c *Config) { c.Auth.GithubURL = "github.com" }, `auth.githubURL: must be an absolute URL, got "github.com"`},
+		{"oidc without client", func(c *Config) { c.Auth.OIDC.Issuer = "https://idp.test" }, "auth.oidc.clientID: is required when an issuer is set"},
+		{"oidc reserved name", func(c *Config) {
+			c.Auth.OIDC.Issuer, c.Auth.OIDC.ClientID, c.Auth.OIDC.Name = "https://idp.test", "id", "github"
+		}, `auth.oidc.name: "github" is reserved for a built-in provider`},
+		{"active key without keys", func(c *Config) { c.Auth.TokenEncryption.ActiveKey = "k1" }, "auth.tokenEncryption: activeKey is set but no keys are"},
+		{"idle over absolute", func(c *Config) { c.Auth.Session.IdleTTL = 31 * 24 * time.Hour }, "auth.session.idleTTL: (744h0m0s) must not exceed absoluteTTL (720h0m0s)"},
+		{"renew over idle", func(c *Config) { c.Auth.Session.RenewInterval = 7 * 24 * time.Hour }, "auth.session.renewInterval: (168h0m0s) must be shorter than idleTTL (168h0m0s)"},
+		{"bad mail from", func(c *Config) { c.Mail.From = "nobody" }, `mail.from: must be an email address, got "nobody"`},
+		{"bad log level", func(c *Config) { c.Log.Level = "loud" }, `log.level: must be debug, info, warn or error, got "loud"`},
+		{"bad exporter", func(c *Config) { c.Tracing.Exporter = "zipkin" }, `tracing.exporter: must be one of none, stdout, otlp, got "zipkin"`},
+		{"sample ratio", func(c *Config) { c.Tracing.SampleRatio = 2 }, "tracing.sampleRatio: must be between 0 and 1, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.mod(&c)
+			err := c.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	c := valid()
+	c.Auth.StateKey = ""
+	c.Log.Format = "xml"
+	c.Database.Port = 0
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate passed")
+	}
+	want := []string{
+		"database.port: must be between 1 and 65535, got 0",
+		"auth.stateKey: is required",
+		`log.format: must be json or text, got "xml"`,
+	}
+	if got := strings.Split(err.Error(), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Validate =\n%v\nwant\n%s", err, strings.Join(want, "\n"))
+	}
+}
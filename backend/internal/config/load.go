@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv names the environment variable that points at an optional
+// YAML config file when -config is not passed.
+const configFileEnv = "HEXTOK_CONFIG"
+
+// Load resolves the configuration for a binary. args are the command-line
+// arguments without the program name; pass nil for binaries that take their
+// own flags. The returned config has already been validated.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("load .env: %w", err)
+	}
+
+	fset := flag.NewFlagSet("hextok", flag.ContinueOnError)
+	path := fset.String("config", os.Getenv(configFileEnv), "path to a YAML config file")
+	pending := map[string]string{}
+	fields := leaves(reflect.ValueOf(&cfg).Elem(), "")
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		name := f.flag
+		fset.Func(name, f.usage, func(v string) error {
+			pending[name] = v
+			return nil
+		})
+	}
+	if err := fset.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		raw, err := os.ReadFile(*path)
+		if err != nil {
+			return cfg, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(raw, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse config file %s: %w", *path, err)
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := setValue(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+	for _, f := range fields {
+		if v, ok := pending[f.flag]; ok && f.flag != "" {
+			if err := setValue(f.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", f.flag, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+type field struct {
+	path  string
+	env   string
+	flag  string
+	usage string
+	value reflect.Value
+}
+
+// leaves walks nested config structs and returns every settable scalar
+// field together with its env and flag tags.
+func leaves(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		name := prefix + sf.Name
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, leaves(fv, name+".")...)
+			continue
+		}
+		out = append(out, field{
+			path:  name,
+			env:   sf.Tag.Get("env"),
+			flag:  sf.Tag.Get("flag"),
+			usage: sf.Tag.Get("usage"),
+			value: fv,
+		})
+	}
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv sets the state key Validate requires, clears HEXTOK_CONFIG and
+// sets env for the rest of the test.
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("OAUTH_STATE_KEY", testStateKey)
+	t.Setenv(configFileEnv, "")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hextok.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setenv(t, nil)
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := valid()
+	if cfg.HTTP != want.HTTP || cfg.Database != want.Database || cfg.Auth.StateKey != testStateKey {
+		t.Errorf("Load = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+http:
+  addr: ":9000"
+  readTimeout: 7s
+log:
+  level: debug
+  format: text
+database:
+  port: 6543
+`)
+	setenv(t, map[string]string{
+		"HTTP_ADDR": ":9100",
+		"LOG_LEVEL": "warn",
+		"DB_NAME":   "from-env",
+	})
+
+	cfg, err := Load([]string{"-config", path, "-addr", ":9200", "-db-name", "from-flag"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over env and file", cfg.HTTP.Addr, ":9200"},
+		{"flag over env", cfg.Database.Name, "from-flag"},
+		{"env over file", cfg.Log.Level, "warn"},
+		{"file over default", cfg.Log.Format, "text"},
+		{"file duration", cfg.HTTP.ReadTimeout, 7 * time.Second},
+		{"file int", cfg.Database.Port, 6543},
+		{"default kept", cfg.HTTP.WriteTimeout, 10 * time.Second},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	setenv(t, map[string]string{configFileEnv: writeFile(t, "log:\n  level: error\n")})
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("log.level = %q, want error from $%s", cfg.Log.Level, configFileEnv)
+	}
+
+	// -config wins over the variable
+	cfg, err = Load([]string{"-config", writeFile(t, "log:\n  level: debug\n")})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("log.level = %q, want debug from -config", cfg.Log.Level)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"bad env duration", map[string]string{"HTTP_READ_TIMEOUT": "soon"}, nil, `env HTTP_READ_TIMEOUT: time: invalid duration "soon"`},
+		{"bad env int", map[string]string{"DB_PORT": "x"}, nil, `env DB_PORT: strconv.ParseInt: parsing "x": invalid syntax`},
+		{"bad flag bool", nil, []string{"-require-pkce=maybe"}, `flag -require-pkce: strconv.ParseBool: parsing "maybe": invalid syntax`},
+		{"unknown flag", nil, []string{"-nope"}, "flag provided but not defined: -nope"},
+		{"missing file", nil, []string{"-config", "/nonexistent/hextok.yaml"}, "read config file: open /nonexistent/hextok.yaml: no such file or directory"},
+		{"no state key", map[string]string{"OAUTH_STATE_KEY": ""}, nil, "invalid configuration:\nauth.stateKey: is required"},
+		{"invalid value", map[string]string{"LOG_FORMAT": "xml"}, nil, "invalid configuration:\n" + `log.format: must be json or text, got "xml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+			_, err := Load(tt.args)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Load = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBadFile(t *testing.T) {
+	setenv(t, nil)
+	path := writeFile(t, "http: [")
+	_, err := Load([]string{"-config", path})
+	if err == nil || !strings.HasPrefix(err.Error(), "parse config file "+path+": ") {
+		t.Errorf("Load = %v, want a parse error for %s", err, path)
+	}
+}
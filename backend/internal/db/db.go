@@ -1,37 +1,29 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/lib/pq"
 )
 
-func New() (*sql.DB, error) {
-	const (
-		host     = "localhost"
-		port     = "5432"
-		user     = "root"
-		password = "password"
-		dbname   = "hextok"
-	)
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	db, err := sql.Open("postgres", dsn)
+func New(cfg config.Database) (*sql.DB, error) {
+	connector, err := pq.NewConnector(cfg.ConnString())
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(tracedConnector{connector})
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	if err := db.Ping(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -60,3 +60,11 @@ const TruncateAll = `TRUNCATE TABLE users, oauth, liked, session, followed, hex
 `
 const DropAll = `DROP TABLE IF EXISTS liked, followed, session, oauth, hex, users CASCADE;
 `
+
+var initMigration = Migration{
+	Version: 1,
+	Name:    "init",
+	Up: CreateUserTable + ";" + CreateHexTable + ";" + CreateSessionTable + ";" +
+		CreateOauthTable + ";" + CreateLikedJoinTable + ";" + CreateFollowedJoinTable,
+	Down: DropAll,
+}
@@ -0,0 +1,10 @@
+package migrations
+
+// The liked primary key is (userId, hexId), which cannot serve per-hex like
+// counts for a feed page.
+var likedHexIndexMigration = Migration{
+	Version: 2,
+	Name:    "liked_hex_index",
+	Up:      `CREATE INDEX IF NOT EXISTS liked_hexid_idx ON liked (hexId)`,
+	Down:    `DROP INDEX IF EXISTS liked_hexid_idx`,
+}
@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// Older rows were stored as entered (the seed wrote "#ABCDEF", clients sent
+// "abc" or "rgb(...)"). Every value domains.NormalizeHex accepts is rewritten
+// to the canonical form the API writes. Spellings of the same color are
+// merged into the oldest row, which takes over their likes, so hexValue
+// stays UNIQUE. The table is locked so no row is added under an old
+// spelling while this runs.
+const NormalizeHexValues = `LOCK TABLE hex, liked IN SHARE ROW EXCLUSIVE MODE`
+
+var normalizeHexValuesMigration = Migration{
+	Version:    3,
+	Name:       "normalize_hex_values",
+	Up:         NormalizeHexValues,
+	Backfill:   normalizeHexValues,
+	BackfillID: "normalizeHexValues/1",
+	// the original spelling is not kept, so there is nothing to restore
+	Down: `SELECT 1`,
+}
+
+type hexRow struct {
+	id    int64
+	value string
+}
+
+// hexNormalization is what normalizeHexValues does to the table.
+type hexNormalization struct {
+	// rename maps row id to its canonical value, for kept rows whose value
+	// is not canonical yet.
+	rename map[int64]string
+	// merge maps a duplicate row id to the row it is merged into.
+	merge map[int64]int64
+	// invalid holds rows NormalizeHex rejects; they are left as they are.
+	invalid []hexRow
+}
+
+// planHexNormalization groups rows, which must be in id order, by canonical
+// value and keeps the first row of each group.
+func planHexNormalization(rows []hexRow) hexNormalization {
+	plan := hexNormalization{rename: make(map[int64]string), merge: make(map[int64]int64)}
+	kept := make(map[string]int64)
+	for _, r := range rows {
+		canon, err := domains.NormalizeHex(r.value)
+		if err != nil {
+			plan.invalid = append(plan.invalid, r)
+			continue
+		}
+		if keep, ok := kept[canon]; ok {
+			plan.merge[r.id] = keep
+			continue
+		}
+		kept[canon] = r.id
+		if r.value != canon {
+			plan.rename[r.id] = canon
+		}
+	}
+	return plan
+}
+
+func normalizeHexValues(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, hexValue FROM hex WHERE hexValue IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	var all []hexRow
+	for rows.Next() {
+		var r hexRow
+		if err := rows.Scan(&r.id, &r.value); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	plan := planHexNormalization(all)
+
+	// duplicates go first so the kept rows can take their value
+	for dup, keep := range plan.merge {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO liked (userId, hexId, createdAt)
+			SELECT userId, $1, createdAt FROM liked WHERE hexId = $2
+			ON CONFLICT (userId, hexId) DO NOTHING`, keep, dup); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM hex WHERE id = $1`, dup); err != nil {
+			return err
+		}
+		slog.Info("migrations: merged duplicate hex", "id", dup, "into", keep)
+	}
+	for id, canon := range plan.rename {
+		if _, err := tx.ExecContext(ctx, `UPDATE hex SET hexValue = $1 WHERE id = $2`, canon, id); err != nil {
+			return err
+		}
+	}
+	for _, r := range plan.invalid {
+		slog.Warn("migrations: hex value is not a color and was left unchanged", "id", r.id, "value", r.value)
+	}
+	return nil
+}
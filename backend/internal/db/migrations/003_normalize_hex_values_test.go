@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestPlanHexNormalization(t *testing.T) {
+	plan := planHexNormalization([]hexRow{
+		{1, "#ABCDEF"},
+		{2, "#abcdef"},
+		{3, "abc"},
+		{4, "#aabbcc"},
+		{5, "red"},
+		{6, "#00ff00"},
+		{7, "not a color"},
+		{8, "rgb(170, 187, 204)"},
+		{9, "#ff0000"},
+	})
+
+	wantRename := map[int64]string{1: "#abcdef", 3: "#aabbcc", 5: "#ff0000"}
+	if !maps.Equal(plan.rename, wantRename) {
+		t.Errorf("rename = %v, want %v", plan.rename, wantRename)
+	}
+	// every later spelling is merged into the oldest row
+	wantMerge := map[int64]int64{2: 1, 4: 3, 8: 3, 9: 5}
+	if !maps.Equal(plan.merge, wantMerge) {
+		t.Errorf("merge = %v, want %v", plan.merge, wantMerge)
+	}
+	if len(plan.invalid) != 1 || plan.invalid[0].id != 7 {
+		t.Errorf("invalid = %v, want row 7", plan.invalid)
+	}
+}
+
+func TestPlanHexNormalizationCanonical(t *testing.T) {
+	plan := planHexNormalization([]hexRow{{1, "#000000"}, {2, "#ffffff80"}})
+	if len(plan.rename) != 0 || len(plan.merge) != 0 || len(plan.invalid) != 0 {
+		t.Errorf("plan for canonical rows = %+v, want no changes", plan)
+	}
+}
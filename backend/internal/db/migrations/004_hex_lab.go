@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// Precomputed CIE Lab coordinates let similarity search avoid parsing every
+// stored color on each request.
+const AddHexLabColumns = `
+ALTER TABLE hex
+ADD COLUMN IF NOT EXISTS labL DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS labA DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS labB DOUBLE PRECISION;
+`
+
+const DropHexLabColumns = `
+ALTER TABLE hex
+DROP COLUMN IF EXISTS labL,
+DROP COLUMN IF EXISTS labA,
+DROP COLUMN IF EXISTS labB;
+`
+
+var hexLabMigration = Migration{
+	Version:    4,
+	Name:       "hex_lab",
+	Up:         AddHexLabColumns,
+	Down:       DropHexLabColumns,
+	Backfill:   backfillHexLab,
+	BackfillID: "backfillHexLab/1",
+}
+
+// backfillHexLab computes Lab for existing rows. Values that do not parse as
+// a color are left NULL and are skipped by similarity search.
+func backfillHexLab(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, hexValue FROM hex WHERE labL IS NULL AND hexValue IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id  int64
+		lab domains.Lab
+	}
+	var updates []pending
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		c, err := domains.ParseColor(value)
+		if err != nil {
+			continue
+		}
+		updates = append(updates, pending{id: id, lab: c.Lab()})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE hex SET labL=$1, labA=$2, labB=$3 WHERE id=$4`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, u := range updates {
+		if _, err := stmt.ExecContext(ctx, u.lab.L, u.lab.A, u.lab.B, u.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package migrations
+
+// Hexes created before authorship was tracked keep a NULL createdBy and
+// take the migration time as createdAt. Deleting a user keeps their hexes
+// but forgets who made them.
+const AddHexAuthorColumns = `
+ALTER TABLE hex
+ADD COLUMN IF NOT EXISTS createdBy BIGINT REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS createdAt TIMESTAMP NOT NULL DEFAULT NOW();
+CREATE INDEX IF NOT EXISTS hex_createdby_idx ON hex (createdBy, id DESC);
+`
+
+const DropHexAuthorColumns = `
+DROP INDEX IF EXISTS hex_createdby_idx;
+ALTER TABLE hex
+DROP COLUMN IF EXISTS createdBy,
+DROP COLUMN IF EXISTS createdAt;
+`
+
+var hexAuthorMigration = Migration{
+	Version: 5,
+	Name:    "hex_author",
+	Up:      AddHexAuthorColumns,
+	Down:    DropHexAuthorColumns,
+}
@@ -0,0 +1,24 @@
+package migrations
+
+// The client details let users recognise their sessions when reviewing where
+// they are logged in. Existing sessions have neither.
+const AddSessionClientColumns = `
+ALTER TABLE session
+ADD COLUMN IF NOT EXISTS userAgent TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS session_userid_idx ON session (userId);
+`
+
+const DropSessionClientColumns = `
+DROP INDEX IF EXISTS session_userid_idx;
+ALTER TABLE session
+DROP COLUMN IF EXISTS userAgent,
+DROP COLUMN IF EXISTS ip;
+`
+
+var sessionClientMigration = Migration{
+	Version: 6,
+	Name:    "session_client",
+	Up:      AddSessionClientColumns,
+	Down:    DropSessionClientColumns,
+}
@@ -0,0 +1,23 @@
+package migrations
+
+const CreateAuthCodeTable = `
+CREATE TABLE IF NOT EXISTS auth_code (
+id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+userId BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+codeHash TEXT NOT NULL UNIQUE,
+state TEXT NOT NULL,
+createdAt TIMESTAMP NOT NULL DEFAULT NOW(),
+expiresAt TIMESTAMP NOT NULL,
+usedAt TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS auth_code_expiresat_idx ON auth_code (expiresAt);
+`
+
+const DropAuthCodeTable = `DROP TABLE IF EXISTS auth_code;`
+
+var authCodeMigration = Migration{
+	Version: 7,
+	Name:    "auth_code",
+	Up:      CreateAuthCodeTable,
+	Down:    DropAuthCodeTable,
+}
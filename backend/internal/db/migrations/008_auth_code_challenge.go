@@ -0,0 +1,18 @@
+package migrations
+
+// The PKCE challenge a mobile login was started with, checked against the
+// code_verifier when its auth code is redeemed. Empty for flows without PKCE.
+const AddAuthCodeChallenge = `
+ALTER TABLE auth_code ADD COLUMN IF NOT EXISTS codeChallenge TEXT NOT NULL DEFAULT '';
+`
+
+const DropAuthCodeChallenge = `
+ALTER TABLE auth_code DROP COLUMN IF EXISTS codeChallenge;
+`
+
+var authCodeChallengeMigration = Migration{
+	Version: 8,
+	Name:    "auth_code_challenge",
+	Up:      AddAuthCodeChallenge,
+	Down:    DropAuthCodeChallenge,
+}
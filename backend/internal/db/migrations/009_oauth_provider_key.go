@@ -0,0 +1,20 @@
+package migrations
+
+// Provider user ids are only unique within a provider: with several
+// providers a GitHub id and an OpenID Connect subject can collide.
+const ScopeOauthProviderUserId = `
+ALTER TABLE oauth DROP CONSTRAINT IF EXISTS oauth_provideruserid_key;
+ALTER TABLE oauth ADD CONSTRAINT oauth_provider_provideruserid_key UNIQUE (provider, providerUserId);
+`
+
+const UnscopeOauthProviderUserId = `
+ALTER TABLE oauth DROP CONSTRAINT IF EXISTS oauth_provider_provideruserid_key;
+ALTER TABLE oauth ADD CONSTRAINT oauth_provideruserid_key UNIQUE (providerUserId);
+`
+
+var oauthProviderKeyMigration = Migration{
+	Version: 9,
+	Name:    "oauth_provider_key",
+	Up:      ScopeOauthProviderUserId,
+	Down:    UnscopeOauthProviderUserId,
+}
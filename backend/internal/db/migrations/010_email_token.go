@@ -0,0 +1,25 @@
+package migrations
+
+const CreateEmailTokenTable = `
+CREATE TABLE IF NOT EXISTS email_token (
+id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+email TEXT NOT NULL,
+tokenHash TEXT NOT NULL UNIQUE,
+mobile BOOLEAN NOT NULL DEFAULT FALSE,
+state TEXT NOT NULL DEFAULT '',
+codeChallenge TEXT NOT NULL DEFAULT '',
+createdAt TIMESTAMP NOT NULL DEFAULT NOW(),
+expiresAt TIMESTAMP NOT NULL,
+usedAt TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_token_expiresat_idx ON email_token (expiresAt);
+`
+
+const DropEmailTokenTable = `DROP TABLE IF EXISTS email_token;`
+
+var emailTokenMigration = Migration{
+	Version: 10,
+	Name:    "email_token",
+	Up:      CreateEmailTokenTable,
+	Down:    DropEmailTokenTable,
+}
@@ -0,0 +1,25 @@
+package migrations
+
+// OAuth provider tokens are encrypted by the oauth store. keyId names the
+// key that wrapped the row's data key; rows with an empty keyId predate
+// encryption and hold plaintext until rotate-keys encrypts them.
+const AddOauthTokenEncryption = `
+ALTER TABLE oauth ADD COLUMN IF NOT EXISTS keyId TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth ADD COLUMN IF NOT EXISTS dataKey TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_oauth_keyid ON oauth(keyId);
+`
+
+// Encrypted tokens cannot be decrypted in SQL, so going back discards them.
+const DropOauthTokenEncryption = `
+UPDATE oauth SET accessToken = '', refreshToken = '' WHERE keyId <> '';
+DROP INDEX IF EXISTS idx_oauth_keyid;
+ALTER TABLE oauth DROP COLUMN IF EXISTS dataKey;
+ALTER TABLE oauth DROP COLUMN IF EXISTS keyId;
+`
+
+var oauthTokenEncryptionMigration = Migration{
+	Version: 11,
+	Name:    "oauth_token_encryption",
+	Up:      AddOauthTokenEncryption,
+	Down:    DropOauthTokenEncryption,
+}
@@ -0,0 +1,24 @@
+package migrations
+
+const CreateAccessTokenTable = `
+CREATE TABLE IF NOT EXISTS access_token (
+id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+userId BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name TEXT NOT NULL,
+tokenHash TEXT NOT NULL UNIQUE,
+scopes TEXT[] NOT NULL DEFAULT '{}',
+createdAt TIMESTAMP NOT NULL DEFAULT NOW(),
+lastUsedAt TIMESTAMP,
+expiresAt TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS access_token_userid_idx ON access_token (userId);
+`
+
+const DropAccessTokenTable = `DROP TABLE IF EXISTS access_token;`
+
+var accessTokenMigration = Migration{
+	Version: 12,
+	Name:    "access_token",
+	Up:      CreateAccessTokenTable,
+	Down:    DropAccessTokenTable,
+}
@@ -1,53 +1,415 @@
 package migrations
 
 import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
-
-	"github.com/HimanshuKumarDutt094/hextok/internal/db"
+	"slices"
+	"time"
 )
 
-func Migrate() {
-	conn, err := db.New()
+// lockKey is the Postgres advisory lock id held while migrations run so two
+// instances never migrate the same database at once.
+const lockKey int64 = 0x6865787430316b
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+version BIGINT PRIMARY KEY,
+name TEXT NOT NULL,
+checksum TEXT NOT NULL,
+appliedAt TIMESTAMP NOT NULL DEFAULT NOW()
+);
+`
+
+// Migration is a single numbered schema step. Steps are applied in Version
+// order and are never edited once released; change the schema by adding a
+// new step instead.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Backfill optionally runs after Up inside the same transaction, for data
+	// changes that cannot be expressed in SQL.
+	Backfill func(ctx context.Context, tx *sql.Tx) error
+	// BackfillID names the backfill and its revision, e.g.
+	// "normalizeHexValues/1". Go code cannot be hashed, so the ID stands in
+	// for it in the checksum. It is required with a Backfill.
+	BackfillID string
+}
+
+// ErrResetNotForced is returned by Reset when it is not forced.
+var ErrResetNotForced = errors.New("reset drops every table and all data and must be forced")
+
+// Checksum identifies the Up SQL and the backfill of a migration so edits to
+// an already applied step can be detected. Steps without a backfill hash
+// their Up SQL alone.
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.Up))
+	if m.BackfillID != "" {
+		h.Write([]byte("\x00backfill:" + m.BackfillID))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// All returns every known migration in the order it must be applied.
+func All() []Migration {
+	return []Migration{
+		initMigration,
+		likedHexIndexMigration,
+		normalizeHexValuesMigration,
+		hexLabMigration,
+		hexAuthorMigration,
+		sessionClientMigration,
+		authCodeMigration,
+		authCodeChallengeMigration,
+		oauthProviderKeyMigration,
+		emailTokenMigration,
+		oauthTokenEncryptionMigration,
+		accessTokenMigration,
+	}
+}
+
+// Status describes one migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Mismatch is set when the checksum recorded in the database differs
+	// from the migration compiled into this binary.
+	Mismatch bool
+	// Unknown is set for versions recorded in the database that this binary
+	// does not know about.
+	Unknown bool
+}
+
+type appliedRow struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{DB: db, Migrations: All()}
+}
+
+// Up applies every pending migration and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if err := m.validate(); err != nil {
+		return 0, err
+	}
+	var n int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := applyUp(ctx, conn, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts the most recently applied steps, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, errors.New("steps must be positive")
+	}
+	if err := m.validate(); err != nil {
+		return 0, err
+	}
+	var n int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.Migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := applyDown(ctx, conn, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Redo reverts and re-applies the most recently applied migration.
+func (m *Migrator) Redo(ctx context.Context) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			mig := m.Migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := applyDown(ctx, conn, mig); err != nil {
+				return err
+			}
+			return applyUp(ctx, conn, mig)
+		}
+		return errors.New("no applied migration to redo")
+	})
+}
+
+// Reset reverts every applied migration and then applies all of them again,
+// leaving an empty database at the latest schema. All data is lost, so it
+// does nothing and returns ErrResetNotForced unless force is set.
+func (m *Migrator) Reset(ctx context.Context, force bool) error {
+	if !force {
+		return ErrResetNotForced
+	}
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			mig := m.Migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := applyDown(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		for _, mig := range m.Migrations {
+			if err := applyUp(ctx, conn, mig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status reports every known migration plus any unknown versions recorded
+// in the database, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	conn, err := m.DB.Conn(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer conn.Close()
 
-	stmts := []string{
-		CreateUserTable,
-		CreateHexTable,
-		CreateSessionTable,
-		CreateOauthTable,
-		CreateLikedJoinTable,
-		CreateFollowedJoinTable,
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(m.Migrations))
+	known := make(map[int64]struct{}, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		known[mig.Version] = struct{}{}
+		s := Status{Migration: mig}
+		if row, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = row.appliedAt
+			s.Mismatch = row.checksum != mig.Checksum()
+		}
+		out = append(out, s)
+	}
+	for v, row := range applied {
+		if _, ok := known[v]; ok {
+			continue
+		}
+		out = append(out, Status{
+			Migration: Migration{Version: v, Name: row.name},
+			Applied:   true,
+			AppliedAt: row.appliedAt,
+			Unknown:   true,
+		})
 	}
+	slices.SortFunc(out, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return out, nil
+}
 
-	for _, stmt := range stmts {
-		if _, err := conn.Exec(stmt); err != nil {
-			log.Fatalf("failed executing: %v\nerror: %v", stmt, err)
+// Pending returns the number of known migrations the database does not have
+// as compiled: not yet applied, or applied with a different checksum. Versions
+// only the database knows are not counted, so an older binary still reports
+// ready while a newer one rolls out. It does not take the migration lock.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	st, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for _, s := range st {
+		if !s.Applied || s.Mismatch {
+			n++
 		}
 	}
+	return n, nil
+}
 
-	fmt.Println("Migrations executed successfully")
+func (m *Migrator) validate() error {
+	seen := make(map[int64]struct{}, len(m.Migrations))
+	var prev int64
+	for _, mig := range m.Migrations {
+		if mig.Version <= 0 {
+			return fmt.Errorf("migration %q has non-positive version %d", mig.Name, mig.Version)
+		}
+		if _, ok := seen[mig.Version]; ok {
+			return fmt.Errorf("duplicate migration version %d", mig.Version)
+		}
+		if mig.Version < prev {
+			return fmt.Errorf("migration %d is out of order", mig.Version)
+		}
+		if mig.Backfill != nil && mig.BackfillID == "" {
+			return fmt.Errorf("migration %d (%s) has a backfill but no BackfillID", mig.Version, mig.Name)
+		}
+		seen[mig.Version] = struct{}{}
+		prev = mig.Version
+	}
+	return nil
 }
 
-func Drop() {
-	conn, err := db.New()
+func (m *Migrator) verify(applied map[int64]appliedRow) error {
+	known := make(map[int64]Migration, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		known[mig.Version] = mig
+	}
+	for v, row := range applied {
+		mig, ok := known[v]
+		if !ok {
+			return fmt.Errorf("database has migration %d (%s) that this binary does not know about", v, row.name)
+		}
+		if row.checksum != mig.Checksum() {
+			return fmt.Errorf("checksum mismatch for applied migration %d (%s): was it edited after release?", v, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer conn.Close()
 
-	stmts := []string{
-		DropAll,
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
 	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	}()
 
-	for _, stmt := range stmts {
-		if _, err := conn.Exec(stmt); err != nil {
-			log.Fatalf("failed executing: %v\nerror: %v", stmt, err)
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedRow, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedRow)
+	if !exists {
+		return applied, nil
+	}
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, appliedAt FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a appliedRow
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func applyUp(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return fmt.Errorf("migration %d (%s) up: %w", mig.Version, mig.Name, err)
+	}
+	if mig.Backfill != nil {
+		if err := mig.Backfill(ctx, tx); err != nil {
+			return fmt.Errorf("migration %d (%s) backfill: %w", mig.Version, mig.Name, err)
 		}
 	}
+	query := `INSERT INTO schema_migrations (version, name, checksum, appliedAt) VALUES ($1, $2, $3, NOW())`
+	if _, err := tx.ExecContext(ctx, query, mig.Version, mig.Name, mig.Checksum()); err != nil {
+		return fmt.Errorf("record migration %d: %w", mig.Version, err)
+	}
+	return tx.Commit()
+}
 
-	fmt.Println("All tables Dropped")
+func applyDown(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("migration %d (%s) is irreversible", mig.Version, mig.Name)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+		return fmt.Errorf("migration %d (%s) down: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mig.Version); err != nil {
+		return fmt.Errorf("unrecord migration %d: %w", mig.Version, err)
+	}
+	return tx.Commit()
 }
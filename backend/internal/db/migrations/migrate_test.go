@@ -0,0 +1,375 @@
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/db"
+)
+
+// testDSNEnv names a disposable database. Each test migrates a schema of its
+// own in it, which is dropped afterwards.
+const testDSNEnv = "HEXTOK_TEST_DATABASE_URL"
+
+func TestChecksum(t *testing.T) {
+	m := Migration{Version: 1, Name: "a", Up: `CREATE TABLE a (id INT)`}
+	sum := sha256.Sum256([]byte(m.Up))
+	if got := m.Checksum(); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("Checksum without a backfill = %s, want the hash of Up", got)
+	}
+
+	noop := func(context.Context, *sql.Tx) error { return nil }
+	v1 := m
+	v1.Backfill, v1.BackfillID = noop, "fill/1"
+	v2 := v1
+	v2.BackfillID = "fill/2"
+	if v1.Checksum() == m.Checksum() || v1.Checksum() == v2.Checksum() {
+		t.Error("Checksum does not change with the backfill")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := NewMigrator(nil).validate(); err != nil {
+		t.Fatalf("All(): %v", err)
+	}
+	noop := func(context.Context, *sql.Tx) error { return nil }
+	for _, tt := range []struct {
+		name string
+		migs []Migration
+		want string
+	}{
+		{"zero version", []Migration{{Name: "a"}}, `migration "a" has non-positive version 0`},
+		{"duplicate", []Migration{{Version: 1}, {Version: 1}}, "duplicate migration version 1"},
+		{"out of order", []Migration{{Version: 2}, {Version: 1}}, "migration 1 is out of order"},
+		{"backfill without id", []Migration{{Version: 1, Name: "a", Backfill: noop}}, "migration 1 (a) has a backfill but no BackfillID"},
+	} {
+		m := &Migrator{Migrations: tt.migs}
+		if err := m.validate(); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: validate = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// testMigrator returns a Migrator with steps over a fresh schema in the
+// test database, skipping the test when none is configured.
+func testMigrator(t *testing.T, migs []Migration) *Migrator {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+	ctx := context.Background()
+	admin, err := db.New(config.Database{DSN: dsn, PingTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "migrate_test_" + strings.ToLower(rand.Text())
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { _, _ = admin.ExecContext(ctx, `DROP SCHEMA `+schema+` CASCADE`) })
+
+	d, err := db.New(config.Database{DSN: withSearchPath(dsn, schema), PingTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("connect to %s: %v", schema, err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return &Migrator{DB: d, Migrations: migs}
+}
+
+// withSearchPath sets the search_path run-time parameter in a URL or
+// key=value DSN.
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+// steps returns three migrations; the third backfills a row into a and
+// counts its runs in *fills.
+func steps(fills *int) []Migration {
+	return []Migration{
+		{Version: 1, Name: "a", Up: `CREATE TABLE a (id INT PRIMARY KEY)`, Down: `DROP TABLE a`},
+		{Version: 2, Name: "b", Up: `CREATE TABLE b (a_id INT REFERENCES a (id))`, Down: `DROP TABLE b`},
+		{Version: 3, Name: "fill_a", Up: `SELECT 1`, Down: `DELETE FROM a`, BackfillID: "fill_a/1",
+			Backfill: func(ctx context.Context, tx *sql.Tx) error {
+				*fills++
+				_, err := tx.ExecContext(ctx, `INSERT INTO a VALUES (1)`)
+				return err
+			}},
+	}
+}
+
+func applied(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+	st, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	var vs []int64
+	for _, s := range st {
+		if s.Applied {
+			vs = append(vs, s.Version)
+		}
+	}
+	return vs
+}
+
+func pending(t *testing.T, m *Migrator) int {
+	t.Helper()
+	n, err := m.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	return n
+}
+
+func rows(t *testing.T, m *Migrator) int {
+	t.Helper()
+	var n int
+	if err := m.DB.QueryRow(`SELECT count(*) FROM a`).Scan(&n); err != nil {
+		t.Fatalf("count a: %v", err)
+	}
+	return n
+}
+
+func TestUp(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+
+	if n := pending(t, m); n != 3 {
+		t.Errorf("Pending on an empty schema = %d, want 3", n)
+	}
+	if n, err := m.Up(ctx); err != nil || n != 3 {
+		t.Fatalf("Up = %d, %v, want 3", n, err)
+	}
+	if n := pending(t, m); n != 0 {
+		t.Errorf("Pending after Up = %d, want 0", n)
+	}
+	if fills != 1 || rows(t, m) != 1 {
+		t.Errorf("backfill ran %d time(s) leaving %d row(s), want once and 1", fills, rows(t, m))
+	}
+	if n, err := m.Up(ctx); err != nil || n != 0 {
+		t.Errorf("second Up = %d, %v, want nothing to do", n, err)
+	}
+
+	// a new step is applied after the ones already there
+	m.Migrations = append(m.Migrations, Migration{Version: 4, Name: "c", Up: `CREATE TABLE c (id INT)`, Down: `DROP TABLE c`})
+	if n := pending(t, m); n != 1 {
+		t.Errorf("Pending with a new step = %d, want 1", n)
+	}
+	if n, err := m.Up(ctx); err != nil || n != 1 {
+		t.Errorf("Up with a new step = %d, %v, want 1", n, err)
+	}
+	var order []int64
+	r, err := m.DB.Query(`SELECT version FROM schema_migrations ORDER BY appliedAt, version`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for r.Next() {
+		var v int64
+		_ = r.Scan(&v)
+		order = append(order, v)
+	}
+	if len(order) != 4 || order[0] != 1 || order[3] != 4 {
+		t.Errorf("applied in order %v, want 1 to 4", order)
+	}
+}
+
+func TestUpFailureRollsBack(t *testing.T) {
+	var fills int
+	migs := steps(&fills)
+	migs[1].Up = `CREATE TABLE b (id INT); SELECT no_such_column FROM a`
+	m := testMigrator(t, migs)
+
+	if _, err := m.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "migration 2 (b) up") {
+		t.Fatalf("Up = %v, want migration 2 to fail", err)
+	}
+	if got := applied(t, m); len(got) != 1 || got[0] != 1 {
+		t.Errorf("applied = %v, want only 1", got)
+	}
+	var exists bool
+	_ = m.DB.QueryRow(`SELECT to_regclass('b') IS NOT NULL`).Scan(&exists)
+	if exists || fills != 0 {
+		t.Errorf("table b exists %v, backfills %d, want the failed step and the ones after it undone", exists, fills)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, edit := range map[string]func(*Migration){
+		"up":       func(mig *Migration) { mig.Up = `SELECT 2` },
+		"backfill": func(mig *Migration) { mig.BackfillID = "fill_a/2" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			edited := &Migrator{DB: m.DB, Migrations: steps(&fills)}
+			edit(&edited.Migrations[2])
+
+			want := "checksum mismatch for applied migration 3 (fill_a)"
+			if _, err := edited.Up(ctx); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Up = %v, want %q", err, want)
+			}
+			if _, err := edited.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Down = %v, want %q", err, want)
+			}
+			if err := edited.Redo(ctx); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Redo = %v, want %q", err, want)
+			}
+			st, err := edited.Status(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !st[2].Mismatch || st[0].Mismatch {
+				t.Errorf("Status mismatch flags = %v %v %v, want only step 3", st[0].Mismatch, st[1].Mismatch, st[2].Mismatch)
+			}
+			if n := pending(t, edited); n != 1 {
+				t.Errorf("Pending = %d, want the mismatched step counted", n)
+			}
+		})
+	}
+	if got := applied(t, m); len(got) != 3 {
+		t.Errorf("applied after refused runs = %v, want all three", got)
+	}
+}
+
+func TestUnknownVersion(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DB.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (99, 'future', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := st[len(st)-1]; last.Version != 99 || !last.Unknown {
+		t.Errorf("last status = %+v, want unknown version 99", last)
+	}
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "does not know about") {
+		t.Errorf("Up = %v, want the unknown version refused", err)
+	}
+	// a newer binary is rolling out; this one stays ready
+	if n := pending(t, m); n != 0 {
+		t.Errorf("Pending = %d, want unknown versions not counted", n)
+	}
+}
+
+func TestDown(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Down(ctx, 0); err == nil {
+		t.Error("Down(0) succeeded")
+	}
+	if n, err := m.Down(ctx, 2); err != nil || n != 2 {
+		t.Fatalf("Down(2) = %d, %v, want 2", n, err)
+	}
+	if got := applied(t, m); len(got) != 1 || got[0] != 1 {
+		t.Errorf("applied after Down(2) = %v, want [1]", got)
+	}
+	if n := pending(t, m); n != 2 {
+		t.Errorf("Pending after Down(2) = %d, want 2", n)
+	}
+	if n, err := m.Down(ctx, 10); err != nil || n != 1 {
+		t.Errorf("Down(10) = %d, %v, want the 1 left", n, err)
+	}
+	if n, err := m.Down(ctx, 1); err != nil || n != 0 {
+		t.Errorf("Down on an empty schema = %d, %v, want nothing to do", n, err)
+	}
+}
+
+func TestDownIrreversible(t *testing.T) {
+	m := testMigrator(t, []Migration{{Version: 1, Name: "one_way", Up: `CREATE TABLE a (id INT)`}})
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "irreversible") {
+		t.Errorf("Down = %v, want irreversible", err)
+	}
+}
+
+func TestRedo(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+
+	if err := m.Redo(ctx); err == nil {
+		t.Error("Redo with nothing applied succeeded")
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Redo(ctx); err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+	// step 3 was reverted, deleting the row, and backfilled again
+	if fills != 2 || rows(t, m) != 1 {
+		t.Errorf("backfill ran %d time(s) leaving %d row(s), want twice and 1", fills, rows(t, m))
+	}
+	if got := applied(t, m); len(got) != 3 {
+		t.Errorf("applied after Redo = %v, want all three", got)
+	}
+}
+
+func TestReset(t *testing.T) {
+	var fills int
+	m := testMigrator(t, steps(&fills))
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.DB.Exec(`INSERT INTO a VALUES (2)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Reset(ctx, false); !errors.Is(err, ErrResetNotForced) {
+		t.Fatalf("Reset without force = %v, want ErrResetNotForced", err)
+	}
+	if n := rows(t, m); n != 2 {
+		t.Fatalf("rows after an unforced Reset = %d, want 2", n)
+	}
+
+	if err := m.Reset(ctx, true); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if n := rows(t, m); n != 1 || fills != 2 {
+		t.Errorf("after Reset a has %d row(s) and the backfill ran %d time(s), want only the backfilled row", n, fills)
+	}
+	if n := pending(t, m); n != 0 {
+		t.Errorf("Pending after Reset = %d, want 0", n)
+	}
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/tracing"
+)
+
+// tracedConnector wraps a driver so every query and exec, prepared or not,
+// gets a client span carrying the SQL text and the number of rows returned
+// or affected. Queries are parameterised, so the text never holds user data.
+// A query span lasts until its rows are closed, which includes the time
+// spent reading them.
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{cn}, nil
+}
+
+// tracedConn forwards to the wrapped connection. The optional interfaces
+// lib/pq implements are passed through; the others fall back the way
+// database/sql expects.
+type tracedConn struct {
+	driver.Conn
+}
+
+var (
+	_ driver.QueryerContext     = (*tracedConn)(nil)
+	_ driver.ExecerContext      = (*tracedConn)(nil)
+	_ driver.ConnPrepareContext = (*tracedConn)(nil)
+	_ driver.ConnBeginTx        = (*tracedConn)(nil)
+	_ driver.Pinger             = (*tracedConn)(nil)
+	_ driver.SessionResetter    = (*tracedConn)(nil)
+	_ driver.Validator          = (*tracedConn)(nil)
+)
+
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	op := strings.TrimSpace(query)
+	if i := strings.IndexFunc(op, unicode.IsSpace); i > 0 {
+		op = op[:i]
+	}
+	op = strings.ToUpper(op)
+	return tracing.Tracer.Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", op),
+		attribute.String("db.query.text", query),
+	))
+}
+
+func endWithError(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedQuery runs query in a span that ends when the returned rows are
+// closed.
+func tracedQuery(ctx context.Context, query string, run func(context.Context) (driver.Rows, error)) (driver.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := run(ctx)
+	if err != nil {
+		endWithError(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// tracedExec runs query in a span that records the rows affected.
+func tracedExec(ctx context.Context, query string, run func(context.Context) (driver.Result, error)) (driver.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	res, err := run(ctx)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			span.SetAttributes(attribute.Int64("db.response.affected_rows", n))
+		}
+	}
+	endWithError(span, err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return tracedQuery(ctx, query, func(ctx context.Context) (driver.Rows, error) {
+		return q.QueryContext(ctx, query, args)
+	})
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return tracedExec(ctx, query, func(ctx context.Context) (driver.Result, error) {
+		return e.ExecContext(ctx, query, args)
+	})
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var st driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		st, err = p.PrepareContext(ctx, query)
+	} else {
+		st, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: st, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// tracedStmt is a prepared statement whose every execution gets a span, like
+// a query run on the connection directly.
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+var (
+	_ driver.StmtQueryContext = (*tracedStmt)(nil)
+	_ driver.StmtExecContext  = (*tracedStmt)(nil)
+)
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return tracedQuery(ctx, s.query, func(ctx context.Context) (driver.Rows, error) {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			return q.QueryContext(ctx, args)
+		}
+		v, err := valuesOf(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Query(v) //nolint:staticcheck // fallback for drivers without StmtQueryContext
+	})
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return tracedExec(ctx, s.query, func(ctx context.Context) (driver.Result, error) {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			return e.ExecContext(ctx, args)
+		}
+		v, err := valuesOf(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Exec(v) //nolint:staticcheck // fallback for drivers without StmtExecContext
+	})
+}
+
+// valuesOf converts args for the pre-context Stmt methods, which have no
+// named parameters.
+func valuesOf(args []driver.NamedValue) ([]driver.Value, error) {
+	v := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("db: driver does not support named parameters")
+		}
+		v[i] = a.Value
+	}
+	return v, nil
+}
+
+// tracedRows counts the rows read and ends the query span on Close.
+type tracedRows struct {
+	driver.Rows
+	span trace.Span
+	n    int64
+	err  error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.n++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.span.SetAttributes(attribute.Int64("db.response.returned_rows", r.n))
+	endWithError(r.span, errors.Join(r.err, err))
+	return err
+}
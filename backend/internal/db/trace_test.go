@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeConnector is a driver with only the pre-context interfaces, so
+// database/sql prepares a statement for every query and exec.
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+// fakeStmt affects three rows and returns two, or fails when its query
+// mentions "missing".
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "missing") {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	return driver.RowsAffected(3), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "missing") {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	return &fakeRows{left: 2}, nil
+}
+
+type fakeRows struct{ left int }
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	dest[0] = int64(r.left)
+	return nil
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func attr(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestPreparedStatementSpans(t *testing.T) {
+	rec := recordSpans(t)
+	ctx := context.Background()
+	db := sql.OpenDB(tracedConnector{fakeConnector{}})
+	defer db.Close()
+
+	st, err := db.PrepareContext(ctx, "UPDATE hex SET hexValue = $1 WHERE id = $2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := st.ExecContext(ctx, "#abcdef", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM missing"); err == nil {
+		t.Fatal("exec on missing table succeeded")
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+	for i, want := range []struct {
+		name     string
+		key      attribute.Key
+		rows     int64
+		failed   bool
+		querySub string
+	}{
+		{"UPDATE", "db.response.affected_rows", 3, false, "UPDATE hex"},
+		{"UPDATE", "db.response.affected_rows", 3, false, "UPDATE hex"},
+		{"SELECT", "db.response.returned_rows", 2, false, "SELECT id"},
+		{"DELETE", "", 0, true, "DELETE FROM missing"},
+	} {
+		s := spans[i]
+		if s.Name() != want.name {
+			t.Errorf("span %d name = %q, want %q", i, s.Name(), want.name)
+		}
+		if q := attr(s, "db.query.text").AsString(); !strings.Contains(q, want.querySub) {
+			t.Errorf("span %d query = %q, want it to contain %q", i, q, want.querySub)
+		}
+		if want.key != "" && attr(s, want.key).AsInt64() != want.rows {
+			t.Errorf("span %d %s = %v, want %d", i, want.key, attr(s, want.key), want.rows)
+		}
+		if failed := s.Status().Code == codes.Error; failed != want.failed {
+			t.Errorf("span %d status = %v, want failed %v", i, s.Status(), want.failed)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package domains
+
+import (
+	"context"
+	"time"
+)
+
+// AccessTokenPrefix starts every personal access token so the auth
+// middleware can tell them from session tokens, and so leaked ones are easy
+// to search for.
+const AccessTokenPrefix = "hxt_"
+
+// Scopes a personal access token can be granted. Requests authenticated by
+// a session are not limited by scope.
+const (
+	ScopeReadUsers    = "read:users"
+	ScopeReadHexes    = "read:hexes"
+	ScopeWriteHexes   = "write:hexes"
+	ScopeReadLikes    = "read:likes"
+	ScopeWriteLikes   = "write:likes"
+	ScopeReadFollows  = "read:follows"
+	ScopeWriteFollows = "write:follows"
+)
+
+var Scopes = []string{
+	ScopeReadUsers,
+	ScopeReadHexes,
+	ScopeWriteHexes,
+	ScopeReadLikes,
+	ScopeWriteLikes,
+	ScopeReadFollows,
+	ScopeWriteFollows,
+}
+
+// AccessToken is a personal access token a user created for scripts and
+// integrations. Like a session secret only its hash is stored.
+type AccessToken struct {
+	Id        int64
+	UserId    int64
+	Name      string
+	TokenHash string
+	Scopes    []string
+	CreatedAt time.Time
+	// LastUsedAt is zero until the token is first used.
+	LastUsedAt time.Time
+	// ExpiresAt is zero for tokens that do not expire.
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the token can no longer be used at now.
+func (t AccessToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+type AccessTokenRepo interface {
+	// CreateAccessToken stores t; Id, CreatedAt and LastUsedAt are ignored.
+	CreateAccessToken(ctx context.Context, t AccessToken) (int64, error)
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (AccessToken, error)
+	GetAccessTokensByUser(ctx context.Context, userId int64) ([]AccessToken, error)
+	// DeleteAccessToken revokes one of the user's tokens. It returns
+	// ErrNotFound when id is not one of theirs.
+	DeleteAccessToken(ctx context.Context, userId, id int64) error
+	UpdateAccessTokenLastUsed(ctx context.Context, id int64, t time.Time) error
+}
@@ -0,0 +1,35 @@
+package domains
+
+import (
+	"context"
+	"time"
+)
+
+// AuthCode is a short-lived, single-use code handed to a native client at the
+// end of an OAuth flow and exchanged for a session. Only the code's hash is
+// stored, and it is bound to the state value the client started the flow
+// with.
+type AuthCode struct {
+	Id       int64
+	UserId   int64
+	CodeHash string
+	State    string
+	// CodeChallenge is the client's PKCE S256 challenge, empty when the
+	// flow was started without one.
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	// UsedAt is zero until the code is redeemed.
+	UsedAt time.Time
+}
+
+type AuthCodeRepo interface {
+	CreateAuthCode(ctx context.Context, userId int64, codeHash, state, codeChallenge string, expiresAt time.Time) (int64, error)
+	// RedeemAuthCode atomically marks the code as used at now and returns it.
+	// It fails with ErrNotFound for unknown codes, ErrAuthCodeUsed when the
+	// code was already redeemed and ErrAuthCodeExpired once it has expired.
+	RedeemAuthCode(ctx context.Context, codeHash string, now time.Time) (AuthCode, error)
+	// DeleteExpiredAuthCodes removes codes, used or not, that expired before
+	// the given time.
+	DeleteExpiredAuthCodes(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,249 @@
+package domains
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidColor is returned by ParseColor for input it cannot understand.
+var ErrInvalidColor = errors.New("invalid color")
+
+// Color is an sRGB color with 8-bit channels and alpha.
+type Color struct {
+	R, G, B, A uint8
+}
+
+// ParseColor accepts the CSS color syntaxes clients are likely to send:
+// 3/4/6/8 digit hex with or without a leading '#', rgb()/rgba(), hsl()/hsla()
+// and the CSS named colors. Matching is case-insensitive.
+func ParseColor(s string) (Color, error) {
+	in := strings.ToLower(strings.TrimSpace(s))
+	if in == "" {
+		return Color{}, fmt.Errorf("%w: empty value", ErrInvalidColor)
+	}
+	if digits, ok := strings.CutPrefix(in, "#"); ok {
+		return parseHexDigits(s, digits)
+	}
+	if c, ok := namedColors[in]; ok {
+		return c, nil
+	}
+	if name, args, ok := cutFunction(in); ok {
+		switch name {
+		case "rgb", "rgba":
+			return parseRGBFunc(s, args)
+		case "hsl", "hsla":
+			return parseHSLFunc(s, args)
+		}
+		return Color{}, fmt.Errorf("%w: unsupported function %q", ErrInvalidColor, name)
+	}
+	return parseHexDigits(s, in)
+}
+
+// Hex returns the canonical form stored in the hex table: lowercase
+// "#rrggbb", or "#rrggbbaa" when the color is not fully opaque.
+func (c Color) Hex() string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+func (c Color) String() string {
+	return c.Hex()
+}
+
+// NormalizeHex parses any supported color syntax and returns its canonical
+// hex form.
+func NormalizeHex(s string) (string, error) {
+	c, err := ParseColor(s)
+	if err != nil {
+		return "", err
+	}
+	return c.Hex(), nil
+}
+
+func parseHexDigits(orig, d string) (Color, error) {
+	for _, r := range d {
+		if !isHexDigit(r) {
+			return Color{}, fmt.Errorf("%w: %q is not a hex color", ErrInvalidColor, orig)
+		}
+	}
+	nib := func(i int) uint8 { v, _ := strconv.ParseUint(d[i:i+1], 16, 8); return uint8(v) * 17 }
+	byt := func(i int) uint8 { v, _ := strconv.ParseUint(d[i:i+2], 16, 8); return uint8(v) }
+	switch len(d) {
+	case 3:
+		return Color{nib(0), nib(1), nib(2), 0xff}, nil
+	case 4:
+		return Color{nib(0), nib(1), nib(2), nib(3)}, nil
+	case 6:
+		return Color{byt(0), byt(2), byt(4), 0xff}, nil
+	case 8:
+		return Color{byt(0), byt(2), byt(4), byt(6)}, nil
+	}
+	return Color{}, fmt.Errorf("%w: hex color %q must have 3, 4, 6 or 8 digits", ErrInvalidColor, orig)
+}
+
+func isHexDigit(r rune) bool {
+	return ('0' <= r && r <= '9') || ('a' <= r && r <= 'f')
+}
+
+// cutFunction splits "name(args)" into its parts.
+func cutFunction(s string) (name, args string, ok bool) {
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return "", "", false
+	}
+	return strings.TrimSpace(s[:open]), s[open+1 : len(s)-1], true
+}
+
+// splitArgs handles both the legacy comma syntax "r, g, b, a" and the modern
+// space syntax "r g b / a". It returns the three color components and the
+// optional alpha.
+func splitArgs(args string) (parts []string, alpha string, err error) {
+	if strings.Contains(args, ",") {
+		for _, p := range strings.Split(args, ",") {
+			parts = append(parts, strings.TrimSpace(p))
+		}
+		switch len(parts) {
+		case 3:
+			return parts, "", nil
+		case 4:
+			return parts[:3], parts[3], nil
+		}
+		return nil, "", errors.New("expected 3 or 4 comma separated values")
+	}
+	main, a, hasAlpha := strings.Cut(args, "/")
+	parts = strings.Fields(main)
+	if len(parts) != 3 {
+		return nil, "", errors.New("expected 3 space separated values")
+	}
+	if hasAlpha {
+		alpha = strings.TrimSpace(a)
+		if alpha == "" {
+			return nil, "", errors.New("missing alpha after '/'")
+		}
+	}
+	return parts, alpha, nil
+}
+
+func parseRGBFunc(orig, args string) (Color, error) {
+	parts, alpha, err := splitArgs(args)
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q: %v", ErrInvalidColor, orig, err)
+	}
+	var ch [3]uint8
+	for i, p := range parts {
+		var v float64
+		if pct, ok := strings.CutSuffix(p, "%"); ok {
+			f, err := strconv.ParseFloat(pct, 64)
+			if err != nil {
+				return Color{}, fmt.Errorf("%w: %q: bad channel %q", ErrInvalidColor, orig, p)
+			}
+			v = f / 100 * 255
+		} else {
+			f, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return Color{}, fmt.Errorf("%w: %q: bad channel %q", ErrInvalidColor, orig, p)
+			}
+			v = f
+		}
+		ch[i] = clampByte(v)
+	}
+	a, err := parseAlpha(alpha)
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q: %v", ErrInvalidColor, orig, err)
+	}
+	return Color{ch[0], ch[1], ch[2], a}, nil
+}
+
+func parseHSLFunc(orig, args string) (Color, error) {
+	parts, alpha, err := splitArgs(args)
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q: %v", ErrInvalidColor, orig, err)
+	}
+	h, err := parseHue(parts[0])
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q: %v", ErrInvalidColor, orig, err)
+	}
+	var sl [2]float64
+	for i, p := range parts[1:] {
+		pct, ok := strings.CutSuffix(p, "%")
+		if !ok {
+			return Color{}, fmt.Errorf("%w: %q: saturation and lightness must be percentages", ErrInvalidColor, orig)
+		}
+		f, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return Color{}, fmt.Errorf("%w: %q: bad percentage %q", ErrInvalidColor, orig, p)
+		}
+		sl[i] = math.Min(math.Max(f/100, 0), 1)
+	}
+	a, err := parseAlpha(alpha)
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q: %v", ErrInvalidColor, orig, err)
+	}
+	r, g, b := hslToRGB(h, sl[0], sl[1])
+	return Color{clampByte(r * 255), clampByte(g * 255), clampByte(b * 255), a}, nil
+}
+
+func parseHue(p string) (float64, error) {
+	unit := 1.0
+	for _, u := range []struct {
+		suffix string
+		scale  float64
+	}{
+		{"deg", 1},
+		{"grad", 0.9},
+		{"rad", 180 / math.Pi},
+		{"turn", 360},
+	} {
+		if v, ok := strings.CutSuffix(p, u.suffix); ok {
+			p, unit = v, u.scale
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(p, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad hue %q", p)
+	}
+	h := math.Mod(f*unit, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h, nil
+}
+
+func parseAlpha(p string) (uint8, error) {
+	if p == "" {
+		return 0xff, nil
+	}
+	var f float64
+	var err error
+	if pct, ok := strings.CutSuffix(p, "%"); ok {
+		f, err = strconv.ParseFloat(pct, 64)
+		f /= 100
+	} else {
+		f, err = strconv.ParseFloat(p, 64)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("bad alpha %q", p)
+	}
+	return clampByte(f * 255), nil
+}
+
+// hslToRGB converts hue in degrees and saturation/lightness in [0,1] to sRGB
+// components in [0,1], following the CSS Color 4 reference algorithm.
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(math.Round(math.Min(math.Max(v, 0), 255)))
+}
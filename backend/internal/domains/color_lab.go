@@ -0,0 +1,134 @@
+package domains
+
+import "math"
+
+// Lab is a color in the CIE L*a*b* space under the D65 white point.
+type Lab struct {
+	L, A, B float64
+}
+
+// D65 reference white in XYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// Lab converts the color to CIE Lab. Alpha is ignored.
+func (c Color) Lab() Lab {
+	r := srgbToLinear(float64(c.R) / 255)
+	g := srgbToLinear(float64(c.G) / 255)
+	b := srgbToLinear(float64(c.B) / 255)
+
+	x := 0.4124564*r + 0.3575761*g + 0.1804375*b
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := 0.0193339*r + 0.1191920*g + 0.9503041*b
+
+	fx, fy, fz := labF(x/whiteX), labF(y/whiteY), labF(z/whiteZ)
+	return Lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29.0
+}
+
+// ColorMetric measures the perceptual distance between two Lab colors.
+type ColorMetric func(a, b Lab) float64
+
+// DeltaE76 is the CIE76 color difference: plain Euclidean distance in Lab.
+// It is cheap but overstates differences between saturated colors.
+func DeltaE76(a, b Lab) float64 {
+	dl, da, db := a.L-b.L, a.A-b.A, a.B-b.B
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+// DeltaE2000 is the CIEDE2000 color difference with unit weighting factors,
+// following Sharma, Wu and Dalal (2005).
+func DeltaE2000(x, y Lab) float64 {
+	pow25to7 := math.Pow(25, 7)
+
+	c1 := math.Hypot(x.A, x.B)
+	c2 := math.Hypot(y.A, y.B)
+	cBar7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+pow25to7)))
+
+	a1 := (1 + g) * x.A
+	a2 := (1 + g) * y.A
+	c1p := math.Hypot(a1, x.B)
+	c2p := math.Hypot(a2, y.B)
+	h1p := hueDegrees(x.B, a1)
+	h2p := hueDegrees(y.B, a2)
+
+	dLp := y.L - x.L
+	dCp := c2p - c1p
+	var dhp float64
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(radians(dhp/2))
+
+	lBarp := (x.L + y.L) / 2
+	cBarp := (c1p + c2p) / 2
+	var hBarp float64
+	switch {
+	case c1p*c2p == 0:
+		hBarp = h1p + h2p
+	case math.Abs(h1p-h2p) <= 180:
+		hBarp = (h1p + h2p) / 2
+	case h1p+h2p < 360:
+		hBarp = (h1p + h2p + 360) / 2
+	default:
+		hBarp = (h1p + h2p - 360) / 2
+	}
+
+	t := 1 - 0.17*math.Cos(radians(hBarp-30)) +
+		0.24*math.Cos(radians(2*hBarp)) +
+		0.32*math.Cos(radians(3*hBarp+6)) -
+		0.20*math.Cos(radians(4*hBarp-63))
+	dTheta := 30 * math.Exp(-math.Pow((hBarp-275)/25, 2))
+	cBarp7 := math.Pow(cBarp, 7)
+	rc := 2 * math.Sqrt(cBarp7/(cBarp7+pow25to7))
+	lm50 := (lBarp - 50) * (lBarp - 50)
+	sl := 1 + 0.015*lm50/math.Sqrt(20+lm50)
+	sc := 1 + 0.045*cBarp
+	sh := 1 + 0.015*cBarp*t
+	rt := -math.Sin(radians(2*dTheta)) * rc
+
+	l, c, h := dLp/sl, dCp/sc, dHp/sh
+	return math.Sqrt(l*l + c*c + h*h + rt*c*h)
+}
+
+func hueDegrees(b, a float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,82 @@
+package domains
+
+import (
+	"math"
+	"testing"
+)
+
+// sharmaPairs is the CIEDE2000 test data from Sharma, Wu and Dalal (2005),
+// table 1: two Lab colors and their expected difference.
+var sharmaPairs = []struct {
+	x, y Lab
+	want float64
+}{
+	{Lab{50.0000, 2.6772, -79.7751}, Lab{50.0000, 0.0000, -82.7485}, 2.0425},
+	{Lab{50.0000, 3.1571, -77.2803}, Lab{50.0000, 0.0000, -82.7485}, 2.8615},
+	{Lab{50.0000, 2.8361, -74.0200}, Lab{50.0000, 0.0000, -82.7485}, 3.4412},
+	{Lab{50.0000, -1.3802, -84.2814}, Lab{50.0000, 0.0000, -82.7485}, 1.0000},
+	{Lab{50.0000, -1.1848, -84.8006}, Lab{50.0000, 0.0000, -82.7485}, 1.0000},
+	{Lab{50.0000, -0.9009, -85.5211}, Lab{50.0000, 0.0000, -82.7485}, 1.0000},
+	{Lab{50.0000, 0.0000, 0.0000}, Lab{50.0000, -1.0000, 2.0000}, 2.3669},
+	{Lab{50.0000, -1.0000, 2.0000}, Lab{50.0000, 0.0000, 0.0000}, 2.3669},
+	{Lab{50.0000, 2.4900, -0.0010}, Lab{50.0000, -2.4900, 0.0009}, 7.1792},
+	{Lab{50.0000, 2.4900, -0.0010}, Lab{50.0000, -2.4900, 0.0010}, 7.1792},
+	{Lab{50.0000, 2.4900, -0.0010}, Lab{50.0000, -2.4900, 0.0011}, 7.2195},
+	{Lab{50.0000, 2.4900, -0.0010}, Lab{50.0000, -2.4900, 0.0012}, 7.2195},
+	{Lab{50.0000, -0.0010, 2.4900}, Lab{50.0000, 0.0009, -2.4900}, 4.8045},
+	{Lab{50.0000, -0.0010, 2.4900}, Lab{50.0000, 0.0010, -2.4900}, 4.8045},
+	{Lab{50.0000, -0.0010, 2.4900}, Lab{50.0000, 0.0011, -2.4900}, 4.7461},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{50.0000, 0.0000, -2.5000}, 4.3065},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{73.0000, 25.0000, -18.0000}, 27.1492},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{61.0000, -5.0000, 29.0000}, 22.8977},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{56.0000, -27.0000, -3.0000}, 31.9030},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{58.0000, 24.0000, 15.0000}, 19.4535},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{50.0000, 3.1736, 0.5854}, 1.0000},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{50.0000, 3.2972, 0.0000}, 1.0000},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{50.0000, 1.8634, 0.5757}, 1.0000},
+	{Lab{50.0000, 2.5000, 0.0000}, Lab{50.0000, 3.2592, 0.3350}, 1.0000},
+	{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+	{Lab{63.0109, -31.0961, -5.8663}, Lab{62.8187, -29.7946, -4.0864}, 1.2630},
+	{Lab{61.2901, 3.7196, -5.3901}, Lab{61.4292, 2.2480, -4.9620}, 1.8731},
+	{Lab{35.0831, -44.1164, 3.7933}, Lab{35.0232, -40.0716, 1.5901}, 1.8645},
+	{Lab{22.7233, 20.0904, -46.6940}, Lab{23.0331, 14.9730, -42.5619}, 2.0373},
+	{Lab{36.4612, 47.8580, 18.3852}, Lab{36.2715, 50.5065, 21.2231}, 1.4146},
+	{Lab{90.8027, -2.0831, 1.4410}, Lab{91.1528, -1.6435, 0.0447}, 1.4441},
+	{Lab{90.9257, -0.5406, -0.9208}, Lab{88.6381, -0.8985, -0.7239}, 1.5381},
+	{Lab{6.7747, -0.2908, -2.4247}, Lab{5.8714, -0.0985, -2.2286}, 0.6377},
+	{Lab{2.0776, 0.0795, -1.1350}, Lab{0.9033, -0.0636, -0.5514}, 0.9082},
+}
+
+func TestDeltaE2000(t *testing.T) {
+	for i, p := range sharmaPairs {
+		if got := DeltaE2000(p.x, p.y); math.Abs(got-p.want) > 1e-4 {
+			t.Errorf("pair %d: DeltaE2000 = %.4f, want %.4f", i+1, got, p.want)
+		}
+		// the formula is symmetric
+		if got, back := DeltaE2000(p.x, p.y), DeltaE2000(p.y, p.x); math.Abs(got-back) > 1e-9 {
+			t.Errorf("pair %d: DeltaE2000 is %.6f one way and %.6f the other", i+1, got, back)
+		}
+	}
+	if d := DeltaE2000(Lab{50, 10, -10}, Lab{50, 10, -10}); d != 0 {
+		t.Errorf("DeltaE2000(same) = %v, want 0", d)
+	}
+}
+
+func TestLab(t *testing.T) {
+	tests := []struct {
+		c    Color
+		want Lab
+	}{
+		{Color{0, 0, 0, 0xff}, Lab{0, 0, 0}},
+		{Color{0xff, 0xff, 0xff, 0xff}, Lab{100, 0, 0}},
+		{Color{0xff, 0, 0, 0xff}, Lab{53.2408, 80.0925, 67.2032}},
+		{Color{0, 0xff, 0, 0xff}, Lab{87.7347, -86.1827, 83.1793}},
+		{Color{0, 0, 0xff, 0xff}, Lab{32.2970, 79.1875, -107.8602}},
+	}
+	for _, tt := range tests {
+		got := tt.c.Lab()
+		if DeltaE76(got, tt.want) > 0.01 {
+			t.Errorf("%v.Lab() = %+v, want %+v", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package domains
+
+// namedColors holds the CSS Color 4 named colors plus transparent.
+var namedColors = map[string]Color{
+	"aliceblue":            Color{0xf0, 0xf8, 0xff, 0xff},
+	"antiquewhite":         Color{0xfa, 0xeb, 0xd7, 0xff},
+	"aqua":                 Color{0x00, 0xff, 0xff, 0xff},
+	"aquamarine":           Color{0x7f, 0xff, 0xd4, 0xff},
+	"azure":                Color{0xf0, 0xff, 0xff, 0xff},
+	"beige":                Color{0xf5, 0xf5, 0xdc, 0xff},
+	"bisque":               Color{0xff, 0xe4, 0xc4, 0xff},
+	"black":                Color{0x00, 0x00, 0x00, 0xff},
+	"blanchedalmond":       Color{0xff, 0xeb, 0xcd, 0xff},
+	"blue":                 Color{0x00, 0x00, 0xff, 0xff},
+	"blueviolet":           Color{0x8a, 0x2b, 0xe2, 0xff},
+	"brown":                Color{0xa5, 0x2a, 0x2a, 0xff},
+	"burlywood":            Color{0xde, 0xb8, 0x87, 0xff},
+	"cadetblue":            Color{0x5f, 0x9e, 0xa0, 0xff},
+	"chartreuse":           Color{0x7f, 0xff, 0x00, 0xff},
+	"chocolate":            Color{0xd2, 0x69, 0x1e, 0xff},
+	"coral":                Color{0xff, 0x7f, 0x50, 0xff},
+	"cornflowerblue":       Color{0x64, 0x95, 0xed, 0xff},
+	"cornsilk":             Color{0xff, 0xf8, 0xdc, 0xff},
+	"crimson":              Color{0xdc, 0x14, 0x3c, 0xff},
+	"cyan":                 Color{0x00, 0xff, 0xff, 0xff},
+	"darkblue":             Color{0x00, 0x00, 0x8b, 0xff},
+	"darkcyan":             Color{0x00, 0x8b, 0x8b, 0xff},
+	"darkgoldenrod":        Color{0xb8, 0x86, 0x0b, 0xff},
+	"darkgray":             Color{0xa9, 0xa9, 0xa9, 0xff},
+	"darkgreen":            Color{0x00, 0x64, 0x00, 0xff},
+	"darkgrey":             Color{0xa9, 0xa9, 0xa9, 0xff},
+	"darkkhaki":            Color{0xbd, 0xb7, 0x6b, 0xff},
+	"darkmagenta":          Color{0x8b, 0x00, 0x8b, 0xff},
+	"darkolivegreen":       Color{0x55, 0x6b, 0x2f, 0xff},
+	"darkorange":           Color{0xff, 0x8c, 0x00, 0xff},
+	"darkorchid":           Color{0x99, 0x32, 0xcc, 0xff},
+	"darkred":              Color{0x8b, 0x00, 0x00, 0xff},
+	"darksalmon":           Color{0xe9, 0x96, 0x7a, 0xff},
+	"darkseagreen":         Color{0x8f, 0xbc, 0x8f, 0xff},
+	"darkslateblue":        Color{0x48, 0x3d, 0x8b, 0xff},
+	"darkslategray":        Color{0x2f, 0x4f, 0x4f, 0xff},
+	"darkslategrey":        Color{0x2f, 0x4f, 0x4f, 0xff},
+	"darkturquoise":        Color{0x00, 0xce, 0xd1, 0xff},
+	"darkviolet":           Color{0x94, 0x00, 0xd3, 0xff},
+	"deeppink":             Color{0xff, 0x14, 0x93, 0xff},
+	"deepskyblue":          Color{0x00, 0xbf, 0xff, 0xff},
+	"dimgray":              Color{0x69, 0x69, 0x69, 0xff},
+	"dimgrey":              Color{0x69, 0x69, 0x69, 0xff},
+	"dodgerblue":           Color{0x1e, 0x90, 0xff, 0xff},
+	"firebrick":            Color{0xb2, 0x22, 0x22, 0xff},
+	"floralwhite":          Color{0xff, 0xfa, 0xf0, 0xff},
+	"forestgreen":          Color{0x22, 0x8b, 0x22, 0xff},
+	"fuchsia":              Color{0xff, 0x00, 0xff, 0xff},
+	"gainsboro":            Color{0xdc, 0xdc, 0xdc, 0xff},
+	"ghostwhite":           Color{0xf8, 0xf8, 0xff, 0xff},
+	"gold":                 Color{0xff, 0xd7, 0x00, 0xff},
+	"goldenrod":            Color{0xda, 0xa5, 0x20, 0xff},
+	"gray":                 Color{0x80, 0x80, 0x80, 0xff},
+	"green":                Color{0x00, 0x80, 0x00, 0xff},
+	"greenyellow":          Color{0xad, 0xff, 0x2f, 0xff},
+	"grey":                 Color{0x80, 0x80, 0x80, 0xff},
+	"honeydew":             Color{0xf0, 0xff, 0xf0, 0xff},
+	"hotpink":              Color{0xff, 0x69, 0xb4, 0xff},
+	"indianred":            Color{0xcd, 0x5c, 0x5c, 0xff},
+	"indigo":               Color{0x4b, 0x00, 0x82, 0xff},
+	"ivory":                Color{0xff, 0xff, 0xf0, 0xff},
+	"khaki":                Color{0xf0, 0xe6, 0x8c, 0xff},
+	"lavender":             Color{0xe6, 0xe6, 0xfa, 0xff},
+	"lavenderblush":        Color{0xff, 0xf0, 0xf5, 0xff},
+	"lawngreen":            Color{0x7c, 0xfc, 0x00, 0xff},
+	"lemonchiffon":         Color{0xff, 0xfa, 0xcd, 0xff},
+	"lightblue":            Color{0xad, 0xd8, 0xe6, 0xff},
+	"lightcoral":           Color{0xf0, 0x80, 0x80, 0xff},
+	"lightcyan":            Color{0xe0, 0xff, 0xff, 0xff},
+	"lightgoldenrodyellow": Color{0xfa, 0xfa, 0xd2, 0xff},
+	"lightgray":            Color{0xd3, 0xd3, 0xd3, 0xff},
+	"lightgreen":           Color{0x90, 0xee, 0x90, 0xff},
+	"lightgrey":            Color{0xd3, 0xd3, 0xd3, 0xff},
+	"lightpink":            Color{0xff, 0xb6, 0xc1, 0xff},
+	"lightsalmon":          Color{0xff, 0xa0, 0x7a, 0xff},
+	"lightseagreen":        Color{0x20, 0xb2, 0xaa, 0xff},
+	"lightskyblue":         Color{0x87, 0xce, 0xfa, 0xff},
+	"lightslategray":       Color{0x77, 0x88, 0x99, 0xff},
+	"lightslategrey":       Color{0x77, 0x88, 0x99, 0xff},
+	"lightsteelblue":       Color{0xb0, 0xc4, 0xde, 0xff},
+	"lightyellow":          Color{0xff, 0xff, 0xe0, 0xff},
+	"lime":                 Color{0x00, 0xff, 0x00, 0xff},
+	"limegreen":            Color{0x32, 0xcd, 0x32, 0xff},
+	"linen":                Color{0xfa, 0xf0, 0xe6, 0xff},
+	"magenta":              Color{0xff, 0x00, 0xff, 0xff},
+	"maroon":               Color{0x80, 0x00, 0x00, 0xff},
+	"mediumaquamarine":     Color{0x66, 0xcd, 0xaa, 0xff},
+	"mediumblue":           Color{0x00, 0x00, 0xcd, 0xff},
+	"mediumorchid":         Color{0xba, 0x55, 0xd3, 0xff},
+	"mediumpurple":         Color{0x93, 0x70, 0xdb, 0xff},
+	"mediumseagreen":       Color{0x3c, 0xb3, 0x71, 0xff},
+	"mediumslateblue":      Color{0x7b, 0x68, 0xee, 0xff},
+	"mediumspringgreen":    Color{0x00, 0xfa, 0x9a, 0xff},
+	"mediumturquoise":      Color{0x48, 0xd1, 0xcc, 0xff},
+	"mediumvioletred":      Color{0xc7, 0x15, 0x85, 0xff},
+	"midnightblue":         Color{0x19, 0x19, 0x70, 0xff},
+	"mintcream":            Color{0xf5, 0xff, 0xfa, 0xff},
+	"mistyrose":            Color{0xff, 0xe4, 0xe1, 0xff},
+	"moccasin":             Color{0xff, 0xe4, 0xb5, 0xff},
+	"navajowhite":          Color{0xff, 0xde, 0xad, 0xff},
+	"navy":                 Color{0x00, 0x00, 0x80, 0xff},
+	"oldlace":              Color{0xfd, 0xf5, 0xe6, 0xff},
+	"olive":                Color{0x80, 0x80, 0x00, 0xff},
+	"olivedrab":            Color{0x6b, 0x8e, 0x23, 0xff},
+	"orange":               Color{0xff, 0xa5, 0x00, 0xff},
+	"orangered":            Color{0xff, 0x45, 0x00, 0xff},
+	"orchid":               Color{0xda, 0x70, 0xd6, 0xff},
+	"palegoldenrod":        Color{0xee, 0xe8, 0xaa, 0xff},
+	"palegreen":            Color{0x98, 0xfb, 0x98, 0xff},
+	"paleturquoise":        Color{0xaf, 0xee, 0xee, 0xff},
+	"palevioletred":        Color{0xdb, 0x70, 0x93, 0xff},
+	"papayawhip":           Color{0xff, 0xef, 0xd5, 0xff},
+	"peachpuff":            Color{0xff, 0xda, 0xb9, 0xff},
+	"peru":                 Color{0xcd, 0x85, 0x3f, 0xff},
+	"pink":                 Color{0xff, 0xc0, 0xcb, 0xff},
+	"plum":                 Color{0xdd, 0xa0, 0xdd, 0xff},
+	"powderblue":           Color{0xb0, 0xe0, 0xe6, 0xff},
+	"purple":               Color{0x80, 0x00, 0x80, 0xff},
+	"rebeccapurple":        Color{0x66, 0x33, 0x99, 0xff},
+	"red":                  Color{0xff, 0x00, 0x00, 0xff},
+	"rosybrown":            Color{0xbc, 0x8f, 0x8f, 0xff},
+	"royalblue":            Color{0x41, 0x69, 0xe1, 0xff},
+	"saddlebrown":          Color{0x8b, 0x45, 0x13, 0xff},
+	"salmon":               Color{0xfa, 0x80, 0x72, 0xff},
+	"sandybrown":           Color{0xf4, 0xa4, 0x60, 0xff},
+	"seagreen":             Color{0x2e, 0x8b, 0x57, 0xff},
+	"seashell":             Color{0xff, 0xf5, 0xee, 0xff},
+	"sienna":               Color{0xa0, 0x52, 0x2d, 0xff},
+	"silver":               Color{0xc0, 0xc0, 0xc0, 0xff},
+	"skyblue":              Color{0x87, 0xce, 0xeb, 0xff},
+	"slateblue":            Color{0x6a, 0x5a, 0xcd, 0xff},
+	"slategray":            Color{0x70, 0x80, 0x90, 0xff},
+	"slategrey":            Color{0x70, 0x80, 0x90, 0xff},
+	"snow":                 Color{0xff, 0xfa, 0xfa, 0xff},
+	"springgreen":          Color{0x00, 0xff, 0x7f, 0xff},
+	"steelblue":            Color{0x46, 0x82, 0xb4, 0xff},
+	"tan":                  Color{0xd2, 0xb4, 0x8c, 0xff},
+	"teal":                 Color{0x00, 0x80, 0x80, 0xff},
+	"thistle":              Color{0xd8, 0xbf, 0xd8, 0xff},
+	"tomato":               Color{0xff, 0x63, 0x47, 0xff},
+	"turquoise":            Color{0x40, 0xe0, 0xd0, 0xff},
+	"violet":               Color{0xee, 0x82, 0xee, 0xff},
+	"wheat":                Color{0xf5, 0xde, 0xb3, 0xff},
+	"white":                Color{0xff, 0xff, 0xff, 0xff},
+	"whitesmoke":           Color{0xf5, 0xf5, 0xf5, 0xff},
+	"yellow":               Color{0xff, 0xff, 0x00, 0xff},
+	"yellowgreen":          Color{0x9a, 0xcd, 0x32, 0xff},
+	"transparent":          Color{0x00, 0x00, 0x00, 0x00},
+}
@@ -0,0 +1,90 @@
+package domains
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeHex(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// hex, with and without '#', any case
+		{"#abcdef", "#abcdef"},
+		{"#ABCDEF", "#abcdef"},
+		{"AbCdEf", "#abcdef"},
+		{"  #abcdef\n", "#abcdef"},
+		{"#abc", "#aabbcc"},
+		{"ABC", "#aabbcc"},
+		{"#abcd", "#aabbccdd"},
+		{"#abcdef80", "#abcdef80"},
+		{"#abcdefff", "#abcdef"},
+		{"#fff", "#ffffff"},
+		{"000", "#000000"},
+
+		// names
+		{"red", "#ff0000"},
+		{"RebeccaPurple", "#663399"},
+		{"transparent", "#00000000"},
+
+		// rgb()
+		{"rgb(255, 0, 0)", "#ff0000"},
+		{"RGB(255,128,0)", "#ff8000"},
+		{"rgba(255, 0, 0, 0.5)", "#ff000080"},
+		{"rgb(255 0 0 / 50%)", "#ff000080"},
+		{"rgb(100%, 0%, 50%)", "#ff0080"},
+		{"rgb(300, -5, 0)", "#ff0000"},
+		{"rgba(0, 0, 0, 1)", "#000000"},
+
+		// hsl()
+		{"hsl(0, 100%, 50%)", "#ff0000"},
+		{"hsl(120deg 100% 50%)", "#00ff00"},
+		{"hsl(240, 100%, 50%)", "#0000ff"},
+		{"hsl(0.5turn, 100%, 50%)", "#00ffff"},
+		{"hsl(-120, 100%, 50%)", "#0000ff"},
+		{"hsla(0, 0%, 100%, 0.5)", "#ffffff80"},
+		{"hsl(0, 0%, 50%)", "#808080"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeHex(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeHex(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeHexInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"#",
+		"#ab",
+		"#abcde",
+		"#abcdefa",
+		"#abcdef123",
+		"#ggg",
+		"##abc",
+		"notacolor",
+		"rgb(1, 2)",
+		"rgb(1 2 3 4)",
+		"rgb(a, b, c)",
+		"rgb(1 2 3 /)",
+		"hsl(0, 100, 50%)",
+		"hsl(x, 100%, 50%)",
+		"cmyk(0, 0, 0, 0)",
+		"rgb(1, 2, 3",
+	} {
+		if got, err := NormalizeHex(in); !errors.Is(err, ErrInvalidColor) {
+			t.Errorf("NormalizeHex(%q) = %q, %v; want ErrInvalidColor", in, got, err)
+		}
+	}
+}
+
+func TestColorHexRoundTrip(t *testing.T) {
+	for _, c := range []Color{{0, 0, 0, 0xff}, {1, 2, 3, 4}, {0xff, 0x80, 0x0a, 0xff}, {0xde, 0xad, 0xbe, 0xef}} {
+		got, err := ParseColor(c.Hex())
+		if err != nil || got != c {
+			t.Errorf("ParseColor(%q) = %v, %v; want %v", c.Hex(), got, err, c)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package domains
+
+import (
+	"context"
+	"time"
+)
+
+// EmailToken backs a magic login link. Like an AuthCode it is stored hashed
+// and can be redeemed once; it also records how the login was started so the
+// link can finish it the same way.
+type EmailToken struct {
+	Id        int64
+	Email     string
+	TokenHash string
+	// Mobile logins finish with an auth code for the app instead of a
+	// session cookie; State and CodeChallenge are then carried over to it.
+	Mobile        bool
+	State         string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        time.Time
+}
+
+type EmailTokenRepo interface {
+	// CreateEmailToken stores t; Id, CreatedAt and UsedAt are ignored.
+	CreateEmailToken(ctx context.Context, t EmailToken) (int64, error)
+	// RedeemEmailToken follows the same rules as RedeemAuthCode, returning
+	// ErrNotFound, ErrEmailTokenUsed or ErrEmailTokenExpired on failure.
+	RedeemEmailToken(ctx context.Context, tokenHash string, now time.Time) (EmailToken, error)
+	DeleteExpiredEmailTokens(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,25 @@
+package domains
+
+import "errors"
+
+// Repository implementations wrap their driver errors with these sentinels so
+// callers can branch on the outcome without knowing the storage backend.
+var (
+	// ErrNotFound is returned when a looked-up row does not exist or a
+	// referenced row (user, hex) is missing.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is returned when a write would violate a uniqueness
+	// constraint, such as liking the same hex twice.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrAuthCodeUsed and ErrAuthCodeExpired are returned when redeeming an
+	// auth code that is no longer valid.
+	ErrAuthCodeUsed    = errors.New("auth code already used")
+	ErrAuthCodeExpired = errors.New("auth code expired")
+	// ErrEmailTokenUsed and ErrEmailTokenExpired are the same for login
+	// links.
+	ErrEmailTokenUsed    = errors.New("login link already used")
+	ErrEmailTokenExpired = errors.New("login link expired")
+	// ErrLastIdentity is returned when unlinking would leave a user with no
+	// way to log in.
+	ErrLastIdentity = errors.New("cannot remove the last login method")
+)
@@ -2,14 +2,37 @@ package domains
 
 import (
 	"context"
+	"time"
 )
 
 type Hex struct {
 	Id       int64
 	HexValue string
+	// CreatedBy is the id of the user who posted the hex, or 0 when unknown:
+	// the hex was seeded, predates authorship tracking, or its author was
+	// deleted.
+	CreatedBy int64
+	CreatedAt time.Time
 }
+
+// HexLab pairs a hex with its precomputed CIE Lab coordinates.
+type HexLab struct {
+	Hex
+	Lab Lab
+}
+
 type HexRepo interface {
-	CreateHex(ctx context.Context, hexValue string) (int64, error)
+	// CreateHex stores a new hex. A createdBy of 0 records no author.
+	CreateHex(ctx context.Context, hexValue string, createdBy int64) (int64, error)
 	GetHexById(ctx context.Context, hexId int64) (Hex, error)
+	GetHexByValue(ctx context.Context, hexValue string) (Hex, error)
 	ListAllHexColors(ctx context.Context) ([]Hex, error)
+	// ListHexFeed returns up to limit hexes ordered newest first, starting
+	// after the hex with id afterId. An afterId of 0 starts from the top.
+	ListHexFeed(ctx context.Context, afterId int64, limit int) ([]Hex, error)
+	// ListHexesByCreator pages through the hexes a user created, newest
+	// first, with the same afterId semantics as ListHexFeed.
+	ListHexesByCreator(ctx context.Context, userId int64, afterId int64, limit int) ([]Hex, error)
+	// ListHexLabs returns every hex whose value could be converted to Lab.
+	ListHexLabs(ctx context.Context) ([]HexLab, error)
 }
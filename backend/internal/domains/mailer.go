@@ -0,0 +1,15 @@
+package domains
+
+import "context"
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers transactional email such as login links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,89 @@
+package domains
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OAuthProvider is an identity provider users can log in with. The auth
+// handlers drive the authorization code flow through it without knowing
+// which provider is on the other end.
+type OAuthProvider interface {
+	// Name is the provider's key in routes and in the oauth table.
+	Name() string
+	// AuthCodeURL returns the URL to send the user to. redirectURI may be
+	// empty to use the one registered with the provider.
+	AuthCodeURL(ctx context.Context, state, redirectURI string) (string, error)
+	// Exchange trades the authorization code for tokens. state is the value
+	// the flow was started with; providers that issue ID tokens check it
+	// against the token's nonce.
+	Exchange(ctx context.Context, code, state, redirectURI string) (OAuthToken, error)
+	// Profile fetches the identity behind the token.
+	Profile(ctx context.Context, tok OAuthToken) (OAuthProfile, error)
+}
+
+type OAuthToken struct {
+	AccessToken  Secret
+	RefreshToken Secret
+	// IDToken is the raw OpenID Connect ID token, if the provider issued one.
+	IDToken Secret
+	// Expiry is zero when the provider did not say.
+	Expiry time.Time
+}
+
+type OAuthProfile struct {
+	// ProviderUserId is the provider's stable id for the user, unique per
+	// provider.
+	ProviderUserId string
+	// Login is a display name suitable as a default user name.
+	Login         string
+	Email         string
+	EmailVerified bool
+}
+
+// DeviceAuthorizer is implemented by providers that support the device
+// authorization grant (RFC 8628), for clients that cannot open a browser.
+type DeviceAuthorizer interface {
+	// StartDevice requests a device code and the user code to show.
+	StartDevice(ctx context.Context) (DeviceAuth, error)
+	// PollDevice asks whether the user has approved deviceCode. Until they
+	// have, and once the code can no longer be used, it returns a
+	// *DevicePollError.
+	PollDevice(ctx context.Context, deviceCode string) (OAuthToken, error)
+}
+
+type DeviceAuth struct {
+	DeviceCode      string
+	UserCode        string
+	VerificationURI string
+	ExpiresIn       time.Duration
+	// Interval is how long the client must wait between polls.
+	Interval time.Duration
+}
+
+// RFC 8628 poll error codes. The first two mean "keep polling", the others
+// that the device code can no longer be used.
+const (
+	DeviceAuthorizationPending = "authorization_pending"
+	DeviceSlowDown             = "slow_down"
+	DeviceAccessDenied         = "access_denied"
+	DeviceExpiredToken         = "expired_token"
+)
+
+// DevicePollError is the provider's answer to a poll that did not produce
+// a token. Code is an RFC 8628 or OAuth error code such as
+// "authorization_pending", "slow_down", "access_denied" or "expired_token".
+// Interval is set when the provider asks for a new poll interval.
+type DevicePollError struct {
+	Code        string
+	Description string
+	Interval    time.Duration
+}
+
+func (e *DevicePollError) Error() string {
+	if e.Description == "" {
+		return "device authorization: " + e.Code
+	}
+	return fmt.Sprintf("device authorization: %s: %s", e.Code, e.Description)
+}
@@ -0,0 +1,23 @@
+package domains
+
+import "log/slog"
+
+// Secret is a credential such as a provider access token. It formats as
+// [REDACTED] so a struct holding one can be logged or printed with %v
+// without leaking it; use string(s) where the real value is needed. Only
+// formatting is redacted: encoding/json and other marshalers see the plain
+// string, so keep secrets out of response types.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string { return `domains.Secret("` + s.String() + `")` }
+
+func (s Secret) LogValue() slog.Value { return slog.StringValue(s.String()) }
@@ -0,0 +1,39 @@
+package domains
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	s := Secret("gho_abc")
+	v := struct{ Token Secret }{s}
+
+	for _, got := range []string{s.String(), fmt.Sprint(s), fmt.Sprintf("%v %+v %#v", v, v, v)} {
+		if strings.Contains(got, "gho_abc") {
+			t.Errorf("formatted secret leaked: %s", got)
+		}
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("msg", "token", s)
+	if strings.Contains(buf.String(), "gho_abc") || !strings.Contains(buf.String(), redacted) {
+		t.Errorf("logged secret = %s", buf.String())
+	}
+	if Secret("").String() != "" {
+		t.Error("empty secret formats as non-empty")
+	}
+
+	// encoding is not formatting: JSON keeps the value
+	b, err := json.Marshal(v)
+	if err != nil || string(b) != `{"Token":"gho_abc"}` {
+		t.Errorf("json = %s, %v", b, err)
+	}
+	var back struct{ Token Secret }
+	if err := json.Unmarshal(b, &back); err != nil || back.Token != s {
+		t.Errorf("json round trip = %q, %v", string(back.Token), err)
+	}
+}
@@ -12,6 +12,9 @@ type Session struct {
 	SecretHash     []byte
 	CreatedAt      time.Time
 	LastVerifiedAt time.Time
+	// UserAgent and IP describe the client that logged in.
+	UserAgent string
+	IP        string
 }
 type ProviderType int
 
@@ -32,20 +35,62 @@ type Oauth struct {
 	UserId         int64
 	Provider       string
 	ProviderUserId string
-	AccessToken    string
-	RefreshToken   string
+	AccessToken    Secret
+	RefreshToken   Secret
 	CreatedAt      time.Time
 }
 type OauthRepo interface {
-	CreateOauth(ctx context.Context, userId int64, provider, providerUserId, accessToken, refreshToken string) (int64, error)
+	CreateOauth(ctx context.Context, userId int64, provider, providerUserId string, accessToken, refreshToken Secret) (int64, error)
 	GetOauthByProviderUserID(ctx context.Context, provider, providerUserId string) (Oauth, error)
 	GetOauthsByUser(ctx context.Context, userId int64) ([]Oauth, error)
+	// DeleteOauth unlinks one of the user's identities. It returns
+	// ErrNotFound when id is not one of theirs and ErrLastIdentity when it is
+	// the only one left.
+	DeleteOauth(ctx context.Context, userId, id int64) error
 }
 
 type SessionRepo interface {
-	CreateSession(ctx context.Context, userId int64, secretHash string) (int64, error)
+	CreateSession(ctx context.Context, userId int64, secretHash, userAgent, ip string) (int64, error)
 	GetSessionById(ctx context.Context, id int64) (Session, error)
 	GetSessionsByUser(ctx context.Context, userId int64) ([]Session, error)
 	DeleteSession(ctx context.Context, id int64) error
 	UpdateLastVerified(ctx context.Context, id int64, t time.Time) error
+	// DeleteExpiredSessions removes sessions created before createdBefore or
+	// last verified before verifiedBefore and reports how many were deleted.
+	DeleteExpiredSessions(ctx context.Context, createdBefore, verifiedBefore time.Time) (int64, error)
+}
+
+// SessionPolicy decides when a session stops being valid. A session expires
+// AbsoluteTTL after it was created no matter how active it is, and IdleTTL
+// after it was last verified. Verifying a session pushes the idle deadline
+// out, but lastVerifiedAt is only written once RenewAfter has passed so busy
+// clients do not cause a write per request.
+type SessionPolicy struct {
+	AbsoluteTTL time.Duration
+	IdleTTL     time.Duration
+	RenewAfter  time.Duration
+}
+
+// ExpiresAt returns the earlier of the absolute and idle deadlines.
+func (p SessionPolicy) ExpiresAt(s Session) time.Time {
+	abs := s.CreatedAt.Add(p.AbsoluteTTL)
+	idle := s.LastVerifiedAt.Add(p.IdleTTL)
+	if idle.Before(abs) {
+		return idle
+	}
+	return abs
+}
+
+func (p SessionPolicy) Expired(s Session, now time.Time) bool {
+	return !now.Before(p.ExpiresAt(s))
+}
+
+// NeedsRenewal reports whether lastVerifiedAt is old enough to be bumped.
+func (p SessionPolicy) NeedsRenewal(s Session, now time.Time) bool {
+	return now.Sub(s.LastVerifiedAt) >= p.RenewAfter
+}
+
+// Cutoffs returns the bounds to pass to DeleteExpiredSessions at now.
+func (p SessionPolicy) Cutoffs(now time.Time) (createdBefore, verifiedBefore time.Time) {
+	return now.Add(-p.AbsoluteTTL), now.Add(-p.IdleTTL)
 }
@@ -18,6 +18,8 @@ type LikeRepo interface {
 	GetLikedHexesByUser(ctx context.Context, userId int64) ([]Hex, error)
 	// GetLikeCountsForHexes returns a map from hexId to total like count for the provided ids.
 	GetLikeCountsForHexes(ctx context.Context, hexIds []int64) (map[int64]int, error)
+	// GetLikedHexIds reports which of the provided hex ids the user has liked.
+	GetLikedHexIds(ctx context.Context, userId int64, hexIds []int64) (map[int64]bool, error)
 }
 
 type Follow struct {
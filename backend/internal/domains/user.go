@@ -15,4 +15,6 @@ type UserRepo interface {
 	CreateUser(ctx context.Context, username string) (int64, error)
 	GetAllUser(ctx context.Context) ([]User, error)
 	GetUserById(ctx context.Context, userId int64) (User, error)
+	// DeleteUser removes the user together with everything that references it.
+	DeleteUser(ctx context.Context, userId int64) error
 }
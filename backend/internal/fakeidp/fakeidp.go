@@ -0,0 +1,542 @@
+// Package fakeidp is a stand-in for GitHub's OAuth endpoints and user API,
+// so the login flows can be exercised without network access. A Server is
+// an http.Handler: wrap it in httptest.NewServer in tests, or run
+// cmd/fakeidp, and point GITHUB_URL and GITHUB_API_URL at it.
+//
+// Only the parts hextok talks to are implemented: the web flow, the device
+// flow and GET /user. Errors are reported the way GitHub does, mostly as a
+// 200 response carrying an "error" field. SetFault adds 5xx responses and
+// slow replies on top.
+package fakeidp
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	codeTTL   = 10 * time.Minute
+	deviceTTL = 15 * time.Minute
+	// slowDownStep is how much GitHub adds to the interval on slow_down.
+	slowDownStep = 5 * time.Second
+)
+
+// Endpoint names a route for SetFault.
+type Endpoint string
+
+const (
+	Authorize  Endpoint = "authorize"
+	Token      Endpoint = "token"
+	DeviceCode Endpoint = "device_code"
+	UserAPI    Endpoint = "user"
+)
+
+// User is a GitHub account known to the server.
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Email string `json:"email,omitempty"`
+}
+
+// Fault makes an endpoint misbehave. Delay is waited out first, then a
+// non-zero Status replaces the normal response.
+type Fault struct {
+	Status int
+	Delay  time.Duration
+	// Times limits the fault to that many requests; zero keeps it until
+	// ClearFaults.
+	Times int
+}
+
+type grant struct {
+	userID      int64
+	redirectURI string
+	expires     time.Time
+}
+
+type device struct {
+	userCode string
+	// userID is set once the user approves, denied once they refuse
+	userID   int64
+	denied   bool
+	interval time.Duration
+	lastPoll time.Time
+	expires  time.Time
+}
+
+type Server struct {
+	ClientID     string
+	ClientSecret string
+	// CallbackURL is used when an authorize request has no redirect_uri,
+	// like the callback registered on a GitHub OAuth app. A redirect_uri
+	// must be on the same host.
+	CallbackURL string
+	// DeviceInterval is the polling interval the device flow starts with.
+	DeviceInterval time.Duration
+
+	mu      sync.Mutex
+	users   []User
+	current string
+	codes   map[string]grant
+	tokens  map[string]int64
+	devices map[string]*device
+	faults  map[Endpoint]*Fault
+	mux     *http.ServeMux
+	now     func() time.Time
+}
+
+// New returns a server for one OAuth app. Add users before logging in.
+func New(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		DeviceInterval: 5 * time.Second,
+		codes:          make(map[string]grant),
+		tokens:         make(map[string]int64),
+		devices:        make(map[string]*device),
+		faults:         make(map[Endpoint]*Fault),
+		mux:            http.NewServeMux(),
+		now:            time.Now,
+	}
+	s.mux.Handle("GET /login/oauth/authorize", s.faulty(Authorize, s.authorize))
+	s.mux.Handle("POST /login/oauth/access_token", s.faulty(Token, s.token))
+	s.mux.Handle("POST /login/device/code", s.faulty(DeviceCode, s.deviceCode))
+	s.mux.HandleFunc("/login/device", s.verifyDevice)
+	s.mux.Handle("GET /user", s.faulty(UserAPI, s.user))
+	s.mux.HandleFunc("POST /_fakeidp/faults", s.setFaultHandler)
+	s.mux.HandleFunc("DELETE /_fakeidp/faults", s.clearFaultsHandler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddUser registers u. The first user added is signed in until SignIn says
+// otherwise.
+func (s *Server) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, u)
+	if s.current == "" {
+		s.current = u.Login
+	}
+}
+
+// SignIn picks the user that approves authorize requests, as if they were
+// logged in to GitHub in the browser. A request can still name another
+// user with a login query parameter.
+func (s *Server) SignIn(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = login
+}
+
+// SetFault installs f on e, replacing any fault already there.
+func (s *Server) SetFault(e Endpoint, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[e] = &f
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.faults)
+}
+
+// ApproveDevice completes the device flow for userCode as login, which is
+// what a user does on the verification page.
+func (s *Server) ApproveDevice(userCode, login string) error {
+	return s.decideDevice(userCode, login, false)
+}
+
+// DenyDevice makes the next poll for userCode fail with access_denied.
+func (s *Server) DenyDevice(userCode string) error {
+	return s.decideDevice(userCode, "", true)
+}
+
+func (s *Server) decideDevice(userCode, login string, deny bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.devices {
+		if d.userCode != userCode || s.now().After(d.expires) {
+			continue
+		}
+		if deny {
+			d.denied = true
+			return nil
+		}
+		u, ok := s.lookup(login)
+		if !ok {
+			return fmt.Errorf("unknown user %q", login)
+		}
+		d.userID = u.ID
+		return nil
+	}
+	return errors.New("unknown or expired user code")
+}
+
+// lookup finds a user by login, the signed in one when login is empty.
+// Callers hold s.mu.
+func (s *Server) lookup(login string) (User, bool) {
+	if login == "" {
+		login = s.current
+	}
+	for _, u := range s.users {
+		if u.Login == login {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+func (s *Server) userByID(id int64) (User, bool) {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// faulty applies the fault installed on e, if any, before calling next.
+func (s *Server) faulty(e Endpoint, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var f Fault
+		s.mu.Lock()
+		if p := s.faults[e]; p != nil {
+			f = *p
+			if p.Times > 0 {
+				if p.Times--; p.Times == 0 {
+					delete(s.faults, e)
+				}
+			}
+		}
+		s.mu.Unlock()
+
+		if f.Delay > 0 {
+			t := time.NewTimer(f.Delay)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+		if f.Status != 0 {
+			writeJSON(w, f.Status, map[string]string{"message": http.StatusText(f.Status)})
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID {
+		http.Error(w, "unknown client_id", http.StatusNotFound)
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	target := redirectURI
+	if target == "" {
+		target = s.CallbackURL
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		http.Error(w, "no usable redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if redirectURI != "" && s.CallbackURL != "" && !sameHost(redirectURI, s.CallbackURL) {
+		http.Error(w, "redirect_uri_mismatch", http.StatusBadRequest)
+		return
+	}
+
+	back := url.Values{}
+	if st := q.Get("state"); st != "" {
+		back.Set("state", st)
+	}
+	if q.Has("deny") {
+		back.Set("error", "access_denied")
+		back.Set("error_description", "The user has denied your application access.")
+	} else {
+		s.mu.Lock()
+		user, ok := s.lookup(q.Get("login"))
+		code := randomString(20)
+		if ok {
+			s.codes[code] = grant{userID: user.ID, redirectURI: redirectURI, expires: s.now().Add(codeTTL)}
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "no such user is signed in", http.StatusUnauthorized)
+			return
+		}
+		back.Set("code", code)
+	}
+	u.RawQuery = back.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("client_id") != s.ClientID {
+		tokenError(w, r, "incorrect_client_credentials", "The client_id and/or client_secret passed are incorrect.", 0)
+		return
+	}
+	if r.PostForm.Get("grant_type") == "urn:ietf:params:oauth:grant-type:device_code" {
+		s.pollDevice(w, r)
+		return
+	}
+	if r.PostForm.Get("client_secret") != s.ClientSecret {
+		tokenError(w, r, "incorrect_client_credentials", "The client_id and/or client_secret passed are incorrect.", 0)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	if !ok || s.now().After(g.expires) {
+		tokenError(w, r, "bad_verification_code", "The code passed is incorrect or expired.", 0)
+		return
+	}
+	if ru := r.PostForm.Get("redirect_uri"); ru != "" && g.redirectURI != "" && ru != g.redirectURI {
+		tokenError(w, r, "redirect_uri_mismatch", "The redirect_uri MUST match the registered callback URL for this application.", 0)
+		return
+	}
+	s.issueToken(w, r, g.userID)
+}
+
+// issueToken answers with a new access token for userID. Callers hold s.mu.
+func (s *Server) issueToken(w http.ResponseWriter, r *http.Request, userID int64) {
+	tok := "gho_" + randomString(36)
+	s.tokens[tok] = userID
+	tokenResponse(w, r, map[string]any{"access_token": tok, "token_type": "bearer", "scope": ""})
+}
+
+func (s *Server) deviceCode(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("client_id") != s.ClientID {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "incorrect_client_credentials"})
+		return
+	}
+	code := randomString(40)
+	d := &device{
+		userCode: randomUserCode(),
+		interval: s.DeviceInterval,
+		expires:  s.now().Add(deviceTTL),
+	}
+	s.mu.Lock()
+	s.devices[code] = d
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device_code":      code,
+		"user_code":        d.userCode,
+		"verification_uri": baseURL(r) + "/login/device",
+		"expires_in":       int(deviceTTL.Seconds()),
+		"interval":         int(d.interval.Seconds()),
+	})
+}
+
+// pollDevice is the token endpoint for the device flow. Polling sooner than
+// the current interval earns slow_down and a longer interval.
+func (s *Server) pollDevice(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostForm.Get("device_code")
+	d, ok := s.devices[code]
+	now := s.now()
+	switch {
+	case !ok:
+		tokenError(w, r, "incorrect_device_code", "The device_code provided is not valid.", 0)
+	case now.After(d.expires):
+		delete(s.devices, code)
+		tokenError(w, r, "expired_token", "The device_code has expired.", 0)
+	case d.denied:
+		delete(s.devices, code)
+		tokenError(w, r, "access_denied", "The authorization request was denied.", 0)
+	case d.userID != 0:
+		delete(s.devices, code)
+		s.issueToken(w, r, d.userID)
+	case !d.lastPoll.IsZero() && now.Sub(d.lastPoll) < d.interval:
+		d.lastPoll = now
+		d.interval += slowDownStep
+		tokenError(w, r, "slow_down", "Too many requests have been made in the same timeframe.", d.interval)
+	default:
+		d.lastPoll = now
+		tokenError(w, r, "authorization_pending", "The authorization request is still pending.", 0)
+	}
+}
+
+// verifyDevice stands in for the page where users type the user code. It
+// takes user_code and optionally login or deny, as query or form values.
+func (s *Server) verifyDevice(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	userCode := r.Form.Get("user_code")
+	if userCode == "" {
+		http.Error(w, "user_code is required", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if r.Form.Has("deny") {
+		err = s.DenyDevice(userCode)
+	} else {
+		err = s.ApproveDevice(userCode, r.Form.Get("login"))
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fmt.Fprintln(w, "device authorized")
+}
+
+func (s *Server) user(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	tok, ok := strings.CutPrefix(auth, "token ")
+	if !ok {
+		tok, _ = strings.CutPrefix(auth, "Bearer ")
+	}
+	s.mu.Lock()
+	id, ok := s.tokens[tok]
+	u, found := s.userByID(id)
+	s.mu.Unlock()
+	if !ok || !found {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// setFaultHandler installs a fault from form values endpoint, status, delay
+// (a Go duration) and times, for driving cmd/fakeidp with curl.
+func (s *Server) setFaultHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	var f Fault
+	e := Endpoint(r.Form.Get("endpoint"))
+	switch e {
+	case Authorize, Token, DeviceCode, UserAPI:
+	default:
+		http.Error(w, "unknown endpoint", http.StatusBadRequest)
+		return
+	}
+	if v := r.Form.Get("status"); v != "" {
+		if _, err := fmt.Sscan(v, &f.Status); err != nil || f.Status < 100 || f.Status > 599 {
+			http.Error(w, "bad status", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.Form.Get("delay"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "bad delay", http.StatusBadRequest)
+			return
+		}
+		f.Delay = d
+	}
+	if v := r.Form.Get("times"); v != "" {
+		if _, err := fmt.Sscan(v, &f.Times); err != nil || f.Times < 0 {
+			http.Error(w, "bad times", http.StatusBadRequest)
+			return
+		}
+	}
+	s.SetFault(e, f)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) clearFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	s.ClearFaults()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tokenError reports a token endpoint error with a 200 status, as GitHub
+// does.
+func tokenError(w http.ResponseWriter, r *http.Request, code, description string, interval time.Duration) {
+	fields := map[string]any{"error": code, "error_description": description}
+	if interval > 0 {
+		fields["interval"] = int(interval.Seconds())
+	}
+	tokenResponse(w, r, fields)
+}
+
+// tokenResponse writes fields as JSON when the client asks for it and form
+// encoded otherwise, GitHub's default.
+func tokenResponse(w http.ResponseWriter, r *http.Request, fields map[string]any) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, http.StatusOK, fields)
+		return
+	}
+	v := url.Values{}
+	for k, f := range fields {
+		v.Set(k, fmt.Sprint(f))
+	}
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	fmt.Fprint(w, v.Encode())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
+
+func randomString(n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+// randomUserCode returns a code in GitHub's XXXX-XXXX format.
+func randomUserCode() string {
+	const alphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	b := make([]byte, 8)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b[:4]) + "-" + string(b[4:])
+}
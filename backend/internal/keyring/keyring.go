@@ -0,0 +1,187 @@
+// Package keyring encrypts secrets at rest with AES-256-GCM envelope
+// encryption. Each record gets its own random data key, which is stored
+// wrapped by one of the configured key-encryption keys together with that
+// key's id. Rotating a key only rewraps data keys; the records themselves
+// are untouched.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length of key-encryption and data keys (AES-256).
+const KeySize = 32
+
+// ErrUnknownKey is returned for envelopes wrapped by a key that is not in
+// the keyring.
+var ErrUnknownKey = errors.New("keyring: unknown key id")
+
+// Keyring holds the key-encryption keys. New data keys are wrapped by the
+// active key; the others are kept to unwrap records that have not been
+// rotated yet.
+type Keyring struct {
+	active string
+	keks   map[string]cipher.AEAD
+}
+
+// New builds a keyring from raw keys by id. active must be one of them.
+func New(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("keyring: active key %q is not configured", active)
+	}
+	k := &Keyring{active: active, keks: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", id, err)
+		}
+		k.keks[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeys parses a comma separated list of id:key pairs, each key being
+// KeySize bytes of standard base64.
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for pair := range strings.SplitSeq(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, enc, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, errors.New("keys must be id:base64key pairs")
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("key %q is listed twice", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		keys[id] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys given")
+	}
+	return keys, nil
+}
+
+// Active is the id new data keys are wrapped with.
+func (k *Keyring) Active() string { return k.active }
+
+// Envelope is a data key wrapped by the key-encryption key KeyId.
+type Envelope struct {
+	KeyId   string
+	Wrapped []byte
+}
+
+// DataKey encrypts the fields of one record.
+type DataKey struct {
+	aead cipher.AEAD
+}
+
+// NewDataKey generates a data key and wraps it with the active key.
+func (k *Keyring) NewDataKey() (DataKey, Envelope, error) {
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return DataKey{}, Envelope{}, err
+	}
+	env := Envelope{KeyId: k.active, Wrapped: seal(k.keks[k.active], raw, []byte(k.active))}
+	dk, err := dataKey(raw)
+	return dk, env, err
+}
+
+// Open unwraps the data key in env.
+func (k *Keyring) Open(env Envelope) (DataKey, error) {
+	raw, err := k.unwrap(env)
+	if err != nil {
+		return DataKey{}, err
+	}
+	return dataKey(raw)
+}
+
+// Rewrap returns env wrapped by the active key. The data key itself does
+// not change, so records encrypted with it stay valid.
+func (k *Keyring) Rewrap(env Envelope) (Envelope, error) {
+	if env.KeyId == k.active {
+		return env, nil
+	}
+	raw, err := k.unwrap(env)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{KeyId: k.active, Wrapped: seal(k.keks[k.active], raw, []byte(k.active))}, nil
+}
+
+func (k *Keyring) unwrap(env Envelope) ([]byte, error) {
+	kek, ok := k.keks[env.KeyId]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, env.KeyId)
+	}
+	// the key id is authenticated so a wrapped key cannot be relabelled
+	raw, err := open(kek, env.Wrapped, []byte(env.KeyId))
+	if err != nil {
+		return nil, fmt.Errorf("keyring: unwrap with key %q: %w", env.KeyId, err)
+	}
+	return raw, nil
+}
+
+// Seal encrypts plaintext. ad is authenticated but not stored; pass
+// something that ties the value to its record and field so ciphertexts
+// cannot be swapped between them.
+func (d DataKey) Seal(plaintext, ad []byte) []byte {
+	return seal(d.aead, plaintext, ad)
+}
+
+// Open decrypts a value produced by Seal with the same ad.
+func (d DataKey) Open(ciphertext, ad []byte) ([]byte, error) {
+	return open(d.aead, ciphertext, ad)
+}
+
+func dataKey(raw []byte) (DataKey, error) {
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext.
+func seal(aead cipher.AEAD, plaintext, ad []byte) []byte {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		// crypto/rand.Read does not fail on supported platforms
+		panic(err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad)
+}
+
+func open(aead cipher.AEAD, ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ct := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ct, ad)
+}
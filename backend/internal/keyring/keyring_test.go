@@ -0,0 +1,168 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, KeySize) }
+
+func mustNew(t *testing.T, active string, keys map[string][]byte) *Keyring {
+	t.Helper()
+	k, err := New(active, keys)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return k
+}
+
+func TestSealOpen(t *testing.T) {
+	k := mustNew(t, "k1", map[string][]byte{"k1": testKey(1)})
+	dk, env, err := k.NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	if env.KeyId != "k1" {
+		t.Errorf("KeyId = %q, want k1", env.KeyId)
+	}
+	ad := []byte("github\x00gh-1\x00accessToken")
+	ct := dk.Seal([]byte("token"), ad)
+	if bytes.Contains(ct, []byte("token")) {
+		t.Error("ciphertext contains the plaintext")
+	}
+	if bytes.Equal(ct, dk.Seal([]byte("token"), ad)) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+
+	// a data key opened from the envelope decrypts what the original sealed
+	opened, err := k.Open(env)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	pt, err := opened.Open(ct, ad)
+	if err != nil || string(pt) != "token" {
+		t.Fatalf("Open = %q, %v; want token", pt, err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"other column":   []byte("github\x00gh-1\x00refreshToken"),
+		"other user":     []byte("github\x00gh-2\x00accessToken"),
+		"other provider": []byte("google\x00gh-1\x00accessToken"),
+		"no ad":          nil,
+	} {
+		if _, err := opened.Open(ct, bad); err == nil {
+			t.Errorf("%s: Open succeeded with the wrong ad", name)
+		}
+	}
+
+	tampered := bytes.Clone(ct)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := opened.Open(tampered, ad); err == nil {
+		t.Error("Open succeeded on a tampered ciphertext")
+	}
+	if _, err := opened.Open(ct[:5], ad); err == nil {
+		t.Error("Open succeeded on a truncated ciphertext")
+	}
+
+	// another record's data key cannot open it
+	other, _, err := k.NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	if _, err := other.Open(ct, ad); err == nil {
+		t.Error("another data key opened the ciphertext")
+	}
+}
+
+func TestOpenRelabelledEnvelope(t *testing.T) {
+	// both keys are the same bytes, so only the authenticated key id tells
+	// the envelopes apart
+	k := mustNew(t, "k1", map[string][]byte{"k1": testKey(1), "k2": testKey(1)})
+	_, env, err := k.NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	env.KeyId = "k2"
+	if _, err := k.Open(env); err == nil {
+		t.Error("Open accepted an envelope relabelled to another key id")
+	}
+
+	env.KeyId = "gone"
+	if _, err := k.Open(env); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open(unknown key id) err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	keys := map[string][]byte{"old": testKey(1), "new": testKey(2)}
+	before := mustNew(t, "old", keys)
+	dk, env, err := before.NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey: %v", err)
+	}
+	ct := dk.Seal([]byte("token"), []byte("ad"))
+
+	after := mustNew(t, "new", keys)
+	rewrapped, err := after.Rewrap(env)
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if rewrapped.KeyId != "new" || bytes.Equal(rewrapped.Wrapped, env.Wrapped) {
+		t.Fatalf("Rewrap = %+v, want a new envelope under key new", rewrapped)
+	}
+	again, err := after.Rewrap(rewrapped)
+	if err != nil || !bytes.Equal(again.Wrapped, rewrapped.Wrapped) {
+		t.Errorf("Rewrap(active) = %+v, %v; want it unchanged", again, err)
+	}
+
+	// the old key can be retired once everything is rewrapped
+	retired := mustNew(t, "new", map[string][]byte{"new": testKey(2)})
+	opened, err := retired.Open(rewrapped)
+	if err != nil {
+		t.Fatalf("Open after retiring old key: %v", err)
+	}
+	if pt, err := opened.Open(ct, []byte("ad")); err != nil || string(pt) != "token" {
+		t.Errorf("Open = %q, %v; want token", pt, err)
+	}
+	if _, err := retired.Open(env); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open(old envelope) err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("missing", map[string][]byte{"k1": testKey(1)}); err == nil {
+		t.Error("New accepted an active key that is not configured")
+	}
+	if _, err := New("k1", map[string][]byte{"k1": testKey(1)[:16]}); err == nil {
+		t.Error("New accepted a 16 byte key")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	keys, err := ParseKeys(" k1:" + k1 + ", k2:" + k2 + ",")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys["k1"], testKey(1)) || !bytes.Equal(keys["k2"], testKey(2)) {
+		t.Errorf("ParseKeys = %v", keys)
+	}
+
+	for spec, want := range map[string]string{
+		"":                       "no keys",
+		"k1":                     "id:base64key",
+		":" + k1:                 "id:base64key",
+		"k1:" + k1 + ",k1:" + k2: "listed twice",
+		"k1:not base64!":         "not valid base64",
+		"k1:" + base64.StdEncoding.EncodeToString(testKey(1)[:16]): "must be 32 bytes",
+	} {
+		if _, err := ParseKeys(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseKeys(%q) err = %v, want %q", spec, err, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package logging sets up the structured logger used by the hextok binaries
+// and carries a per-request logger in the context.
+//
+// Every logger built by New masks credentials before a record is written,
+// whichever code logs it: attributes whose key names a token, cookie, auth
+// code or secret are replaced outright, and token-looking query parameters and
+// bearer credentials inside strings and errors are masked in place.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const redacted = "[REDACTED]"
+
+// New returns a logger writing to w. format is "json" or "text".
+func New(w io.Writer, format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// sensitiveKeys are attribute keys whose values are never logged. Keys are
+// compared lower case with dashes turned into underscores. A bare "code" is
+// left alone, as it usually holds a problem or status code; log OAuth and
+// login codes as "auth_code".
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"auth_code":     true,
+	"code_verifier": true,
+	"device_code":   true,
+	"state":         true,
+}
+
+// sensitiveSuffixes catch the rest: access_token, client_secret, set_cookie
+// and so on.
+var sensitiveSuffixes = []string{"token", "secret", "password", "cookie"}
+
+func sensitiveKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, s := range sensitiveSuffixes {
+		if strings.HasSuffix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// credentialParam matches the value of a credential carried as a URL
+	// query parameter, form field, cookie or quoted DSN setting, e.g. in a
+	// deep link or in an error that quotes a URL.
+	credentialParam = regexp.MustCompile(`(?i)\b((?:access_token|refresh_token|id_token|token|code|code_verifier|device_code|state|client_secret|password|hextok_session)=['"]?)[^&\s"';]+`)
+	// bearerCredential matches an Authorization header value. The length
+	// floor keeps prose such as "token exchange failed" intact.
+	bearerCredential = regexp.MustCompile(`(?i)\b((?:bearer|token|basic) )[A-Za-z0-9\-._~+/]{16,}=*`)
+	// accessToken matches a hextok personal access token on its own.
+	accessToken = regexp.MustCompile(`\bhxt_[A-Za-z0-9_\-]+`)
+)
+
+// Redact masks credentials inside s.
+func Redact(s string) string {
+	s = credentialParam.ReplaceAllString(s, "${1}"+redacted)
+	s = bearerCredential.ReplaceAllString(s, "${1}"+redacted)
+	return accessToken.ReplaceAllString(s, redacted)
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKey(a.Key) && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); s != "" {
+			a.Value = slog.StringValue(Redact(s))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+type loggerKey struct{}
+
+// WithLogger returns ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or slog.Default when there
+// is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// RequestInfo collects attributes learned while a request is served, such
+// as the matched route and the authenticated user, which only inner
+// handlers know. The access log reads them once the request is done.
+type RequestInfo struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+type infoKey struct{}
+
+// WithRequestInfo returns ctx carrying a new, empty RequestInfo.
+func WithRequestInfo(ctx context.Context) (context.Context, *RequestInfo) {
+	ri := &RequestInfo{}
+	return context.WithValue(ctx, infoKey{}, ri), ri
+}
+
+// RequestInfoFrom returns the RequestInfo in ctx, or nil.
+func RequestInfoFrom(ctx context.Context) *RequestInfo {
+	ri, _ := ctx.Value(infoKey{}).(*RequestInfo)
+	return ri
+}
+
+// Value returns the last value recorded for key, or nil.
+func (ri *RequestInfo) Value(key string) any {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	var v any
+	for i := 0; i+1 < len(ri.attrs); i += 2 {
+		if k, ok := ri.attrs[i].(string); ok && k == key {
+			v = ri.attrs[i+1]
+		}
+	}
+	return v
+}
+
+// Attrs returns what has been recorded so far, as slog key-value pairs.
+func (ri *RequestInfo) Attrs() []any {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+	return append([]any(nil), ri.attrs...)
+}
+
+// With adds the key-value pairs in args to the request's logger and records
+// them for the access log. It returns the context to pass on.
+func With(ctx context.Context, args ...any) context.Context {
+	if ri := RequestInfoFrom(ctx); ri != nil {
+		ri.mu.Lock()
+		ri.attrs = append(ri.attrs, args...)
+		ri.mu.Unlock()
+	}
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
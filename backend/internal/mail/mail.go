@@ -0,0 +1,148 @@
+// Package mail implements domains.Mailer over SMTP, and a development mailer
+// that logs messages and optionally writes them to disk.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+)
+
+// New returns the SMTP mailer when a host is configured and the development
+// mailer otherwise.
+func New(cfg config.Mail) domains.Mailer {
+	if cfg.SMTPHost == "" {
+		return &Dev{From: cfg.From, Dir: cfg.Dir}
+	}
+	return &SMTP{
+		Addr: cfg.SMTPHost + ":" + strconv.Itoa(cfg.SMTPPort),
+		From: cfg.From,
+		Auth: smtpAuth(cfg),
+	}
+}
+
+func smtpAuth(cfg config.Mail) smtp.Auth {
+	if cfg.SMTPUsername == "" {
+		return nil
+	}
+	return smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+}
+
+// SMTP sends mail through a relay. net/smtp upgrades to TLS with STARTTLS
+// when the server offers it, and PlainAuth refuses to send credentials
+// without TLS except to localhost.
+type SMTP struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+var _ domains.Mailer = (*SMTP)(nil)
+
+func (m *SMTP) Send(ctx context.Context, msg domains.Message) error {
+	from, err := netmail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("mail: bad sender: %w", err)
+	}
+	raw, err := compose(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, m.Auth, from.Address, []string{msg.To}, raw)
+}
+
+// Dev logs every message instead of delivering it. With Dir set the full
+// message is written there as an .eml file. Without it the message is
+// printed to Out, os.Stderr by default, through a logger of its own: the
+// process logger would mask the login link and leave nothing to click. Only
+// use it where that output is not kept.
+type Dev struct {
+	From string
+	Dir  string
+	Out  io.Writer
+}
+
+var _ domains.Mailer = (*Dev)(nil)
+
+func (m *Dev) Send(ctx context.Context, msg domains.Message) error {
+	raw, err := compose(m.From, msg)
+	if err != nil {
+		return err
+	}
+	if m.Dir == "" {
+		out := m.Out
+		if out == nil {
+			out = os.Stderr
+		}
+		logging.FromContext(ctx).Info("mail printed", "to", msg.To, "subject", msg.Subject)
+		slog.New(slog.NewTextHandler(out, nil)).Info("mail", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+		return nil
+	}
+	name := filepath.Join(m.Dir, time.Now().UTC().Format("20060102T150405.000000000")+".eml")
+	if err := os.WriteFile(name, raw, 0o600); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	logging.FromContext(ctx).Info("mail written", "to", msg.To, "subject", msg.Subject, "file", name)
+	return nil
+}
+
+// compose renders msg as an RFC 5322 message with a quoted-printable UTF-8
+// body.
+func compose(from string, msg domains.Message) ([]byte, error) {
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("mail: bad recipient: %w", err)
+	}
+	if strings.ContainsAny(from+msg.Subject, "\r\n") {
+		return nil, errors.New("mail: header values must not contain line breaks")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if a, err := netmail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(a.Address, "@"); ok {
+			domain = d
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
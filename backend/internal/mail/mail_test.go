@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+)
+
+const link = "https://api.test/api/v1/auth/email/verify?token=abc123"
+
+var msg = domains.Message{To: "alice@example.com", Subject: "Log in", Text: "Log in: " + link + "\n"}
+
+func TestDevPrintsLinkUnmasked(t *testing.T) {
+	var out, log bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&log, "text", slog.LevelInfo))
+	m := &Dev{From: "hextok <noreply@hextok.test>", Out: &out}
+
+	if err := m.Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), link) {
+		t.Errorf("printed %q, want the link unmasked", out.String())
+	}
+	if strings.Contains(log.String(), "abc123") || !strings.Contains(log.String(), "alice@example.com") {
+		t.Errorf("process log %q, want the recipient without the link", log.String())
+	}
+}
+
+func TestDevWritesFile(t *testing.T) {
+	var out bytes.Buffer
+	dir := t.TempDir()
+	m := &Dev{From: "hextok <noreply@hextok.test>", Dir: dir, Out: &out}
+
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("files = %v, want one .eml", files)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "To: <alice@example.com>") || !strings.Contains(string(raw), "token=3Dabc123") {
+		t.Errorf("message =\n%s", raw)
+	}
+	if out.Len() != 0 {
+		t.Errorf("printed %q with a Dir set", out.String())
+	}
+}
@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// GitHub logs users in with a GitHub OAuth app. GitHub is plain OAuth 2.0:
+// there is no ID token, the profile comes from the REST API.
+type GitHub struct {
+	ClientID     string
+	ClientSecret string
+	// AuthURL, TokenURL, DeviceURL and APIURL are derived from the URLs
+	// given to NewGitHub.
+	AuthURL    string
+	TokenURL   string
+	DeviceURL  string
+	APIURL     string
+	HTTPClient *http.Client
+}
+
+// NewGitHub returns the provider for a GitHub OAuth app. baseURL serves the
+// login endpoints and apiURL the REST API; for github.com they are
+// https://github.com and https://api.github.com, config's defaults.
+func NewGitHub(baseURL, apiURL, clientID, clientSecret string, httpClient *http.Client) *GitHub {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return &GitHub{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AuthURL:      baseURL + "/login/oauth/authorize",
+		TokenURL:     baseURL + "/login/oauth/access_token",
+		DeviceURL:    baseURL + "/login/device/code",
+		APIURL:       strings.TrimSuffix(apiURL, "/"),
+		HTTPClient:   defaultClient(httpClient),
+	}
+}
+
+var (
+	_ domains.OAuthProvider    = (*GitHub)(nil)
+	_ domains.DeviceAuthorizer = (*GitHub)(nil)
+)
+
+func (g *GitHub) Name() string { return "github" }
+
+func (g *GitHub) AuthCodeURL(ctx context.Context, state, redirectURI string) (string, error) {
+	q := url.Values{"client_id": {g.ClientID}, "state": {state}}
+	if redirectURI != "" {
+		q.Set("redirect_uri", redirectURI)
+	}
+	return g.AuthURL + "?" + q.Encode(), nil
+}
+
+func (g *GitHub) Exchange(ctx context.Context, code, state, redirectURI string) (domains.OAuthToken, error) {
+	form := url.Values{
+		"client_id":     {g.ClientID},
+		"client_secret": {g.ClientSecret},
+		"code":          {code},
+	}
+	if redirectURI != "" {
+		form.Set("redirect_uri", redirectURI)
+	}
+	return requestToken(ctx, g.HTTPClient, g.TokenURL, form, "", "", false)
+}
+
+func (g *GitHub) Profile(ctx context.Context, tok domains.OAuthToken) (domains.OAuthProfile, error) {
+	var u struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	if err := getJSON(ctx, g.HTTPClient, g.APIURL+"/user", "token "+string(tok.AccessToken), &u); err != nil {
+		return domains.OAuthProfile{}, err
+	}
+	if u.ID == 0 {
+		return domains.OAuthProfile{}, errors.New("github user has no id")
+	}
+	// the public profile email is whatever the user chose to show, not a
+	// verified address
+	return domains.OAuthProfile{
+		ProviderUserId: strconv.FormatInt(u.ID, 10),
+		Login:          u.Login,
+		Email:          u.Email,
+	}, nil
+}
+
+// StartDevice begins GitHub's device flow. It has to be enabled in the
+// OAuth app's settings.
+func (g *GitHub) StartDevice(ctx context.Context) (domains.DeviceAuth, error) {
+	form := url.Values{"client_id": {g.ClientID}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.DeviceURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domains.DeviceAuth{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return domains.DeviceAuth{}, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		DeviceCode       string `json:"device_code"`
+		UserCode         string `json:"user_code"`
+		VerificationURI  string `json:"verification_uri"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Interval         int64  `json:"interval"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil {
+		return domains.DeviceAuth{}, fmt.Errorf("device code endpoint: %s: %w", resp.Status, err)
+	}
+	if out.Error != "" {
+		return domains.DeviceAuth{}, fmt.Errorf("device code endpoint: %s: %s", out.Error, out.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || out.DeviceCode == "" {
+		return domains.DeviceAuth{}, fmt.Errorf("device code endpoint: %s", resp.Status)
+	}
+	// RFC 8628 says to assume 5 seconds when no interval is given
+	interval := 5 * time.Second
+	if out.Interval > 0 {
+		interval = time.Duration(out.Interval) * time.Second
+	}
+	return domains.DeviceAuth{
+		DeviceCode:      out.DeviceCode,
+		UserCode:        out.UserCode,
+		VerificationURI: out.VerificationURI,
+		ExpiresIn:       time.Duration(out.ExpiresIn) * time.Second,
+		Interval:        interval,
+	}, nil
+}
+
+func (g *GitHub) PollDevice(ctx context.Context, deviceCode string) (domains.OAuthToken, error) {
+	form := url.Values{
+		"client_id":   {g.ClientID},
+		"device_code": {deviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	tok, err := requestToken(ctx, g.HTTPClient, g.TokenURL, form, "", "", false)
+	var te *tokenError
+	if errors.As(err, &te) {
+		return domains.OAuthToken{}, &domains.DevicePollError{Code: te.Code, Description: te.Description, Interval: te.Interval}
+	}
+	return tok, err
+}
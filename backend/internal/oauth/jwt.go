@@ -0,0 +1,260 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far exp, nbf and iat may be off from our clock.
+const clockSkew = time.Minute
+
+// idClaims are the ID token claims we check or use.
+type idClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	NotBefore         int64    `json:"nbf"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience accepts both the single string and the array form of "aud".
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = l
+	return nil
+}
+
+// flexBool accepts true and "true"; some providers send email_verified as a
+// string.
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "true", `"true"`:
+		*f = true
+	default:
+		*f = false
+	}
+	return nil
+}
+
+// verifyIDToken checks the signature and the standard claims of raw. nonce
+// is compared when not empty.
+func (p *OIDC) verifyIDToken(ctx context.Context, raw, nonce string) (idClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return idClaims{}, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return idClaims{}, fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return idClaims{}, errors.New("bad signature encoding")
+	}
+	keys, err := p.signingKeys(ctx, header.Kid)
+	if err != nil {
+		return idClaims{}, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !slices.ContainsFunc(keys, func(k crypto.PublicKey) bool { return verifySignature(header.Alg, k, digest[:], sig) }) {
+		return idClaims{}, errors.New("signature verification failed")
+	}
+
+	var c idClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return idClaims{}, fmt.Errorf("claims: %w", err)
+	}
+	now := p.now()
+	switch {
+	case !p.validIssuer(c.Issuer):
+		return idClaims{}, fmt.Errorf("unexpected issuer %q", c.Issuer)
+	case !slices.Contains(c.Audience, p.clientID):
+		return idClaims{}, errors.New("token is not for this client")
+	case c.AuthorizedParty != "" && c.AuthorizedParty != p.clientID:
+		return idClaims{}, errors.New("token was issued to another party")
+	case c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(clockSkew)):
+		return idClaims{}, errors.New("token expired")
+	case c.NotBefore != 0 && time.Unix(c.NotBefore, 0).After(now.Add(clockSkew)):
+		return idClaims{}, errors.New("token not yet valid")
+	case time.Unix(c.IssuedAt, 0).After(now.Add(clockSkew)):
+		return idClaims{}, errors.New("token issued in the future")
+	case c.Subject == "":
+		return idClaims{}, errors.New("token has no subject")
+	case nonce != "" && c.Nonce != nonce:
+		return idClaims{}, errors.New("nonce mismatch")
+	}
+	return c, nil
+}
+
+func (p *OIDC) validIssuer(iss string) bool {
+	iss = strings.TrimSuffix(iss, "/")
+	return iss == p.issuer || slices.Contains(p.issuerAliases, iss)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifySignature supports RS256 and ES256, the algorithms in practical use
+// for ID tokens. The key type must match the algorithm, so an RSA key can
+// never be used to check an ECDSA signature or the other way round.
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) bool {
+	switch alg {
+	case "RS256":
+		k, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != elliptic.P256() || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest, r, s)
+	}
+	return false
+}
+
+// signingKeys returns the candidate keys for kid, refetching the JWKS when
+// kid is unknown. Without a kid every published key is a candidate.
+func (p *OIDC) signingKeys(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lookup := func() []crypto.PublicKey {
+		if kid != "" {
+			if k, ok := p.keys[kid]; ok {
+				return []crypto.PublicKey{k}
+			}
+			return nil
+		}
+		keys := make([]crypto.PublicKey, 0, len(p.keys))
+		for _, k := range p.keys {
+			keys = append(keys, k)
+		}
+		return keys
+	}
+	if keys := lookup(); len(keys) > 0 {
+		return keys, nil
+	}
+	if !p.keysFetched.IsZero() && p.now().Sub(p.keysFetched) < jwksRefreshInterval {
+		return nil, errors.New("unknown signing key")
+	}
+	keys, err := p.fetchJWKS(ctx, d.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.keysFetched = keys, p.now()
+	if keys := lookup(); len(keys) > 0 {
+		return keys, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchJWKS loads the key set, skipping keys that are not for signatures or
+// that we cannot use.
+func (p *OIDC) fetchJWKS(ctx context.Context, uri string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, uri, "", &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("bad rsa exponent")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("rsa key too small")
+		}
+		return pub, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, errors.New("bad ec x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, errors.New("bad ec y coordinate")
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,265 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testClientID = "client-1"
+
+// testIssuer serves discovery and a JWKS that tests can change.
+type testIssuer struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	keys       []jwk
+	jwksHits   int
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	now        time.Time
+	oidcClient *OIDC
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := &testIssuer{rsaKey: rk, ecKey: ek, now: time.Unix(1_700_000_000, 0)}
+	ti.keys = []jwk{rsaJWK("rsa-1", &rk.PublicKey), ecJWK("ec-1", &ek.PublicKey)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ti.srv.URL,
+			"authorization_endpoint": ti.srv.URL + "/authorize",
+			"token_endpoint":         ti.srv.URL + "/token",
+			"jwks_uri":               ti.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		ti.mu.Lock()
+		defer ti.mu.Unlock()
+		ti.jwksHits++
+		_ = json.NewEncoder(w).Encode(map[string][]jwk{"keys": ti.keys})
+	})
+	ti.srv = httptest.NewServer(mux)
+	t.Cleanup(ti.srv.Close)
+
+	ti.oidcClient = NewOIDC("test", ti.srv.URL, testClientID, "secret", nil, ti.srv.Client())
+	ti.oidcClient.now = func() time.Time { return ti.now }
+	return ti
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) jwk {
+	b, err := k.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return jwk{Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256", X: b64(b[1:33]), Y: b64(b[33:])}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (ti *testIssuer) claims() map[string]any {
+	return map[string]any{
+		"iss":   ti.srv.URL,
+		"sub":   "user-42",
+		"aud":   testClientID,
+		"exp":   ti.now.Add(time.Hour).Unix(),
+		"iat":   ti.now.Unix(),
+		"nonce": "n-1",
+	}
+}
+
+// sign builds a token with the given header alg and kid, signed with key
+// (an *rsa.PrivateKey, *ecdsa.PrivateKey or nil for no signature).
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := b64(h) + "." + b64(c)
+	if key == nil {
+		return input + "."
+	}
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + b64(sig)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	ti := newTestIssuer(t)
+	with := func(mod func(c map[string]any)) map[string]any {
+		c := ti.claims()
+		mod(c)
+		return c
+	}
+	tamper := func(tok string) string {
+		i := strings.LastIndexByte(tok, '.')
+		sig, _ := base64.RawURLEncoding.DecodeString(tok[i+1:])
+		sig[0] ^= 0xff
+		return tok[:i+1] + b64(sig)
+	}
+	now := ti.now
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{"rs256", sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims()), "n-1", ""},
+		{"es256", sign(t, "ES256", "ec-1", ti.ecKey, ti.claims()), "n-1", ""},
+		{"no kid tries every key", sign(t, "RS256", "", ti.rsaKey, ti.claims()), "n-1", ""},
+		{"array aud", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["aud"] = []string{"other", testClientID} })), "", ""},
+		{"issuer with trailing slash", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["iss"] = ti.srv.URL + "/" })), "", ""},
+		{"within clock skew", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() })), "", ""},
+
+		{"bad signature", tamper(sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims())), "", "signature verification failed"},
+		{"claims swapped under signature", func() string {
+			a := strings.Split(sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims()), ".")
+			c, _ := json.Marshal(with(func(c map[string]any) { c["sub"] = "admin" }))
+			return a[0] + "." + b64(c) + "." + a[2]
+		}(), "", "signature verification failed"},
+		{"wrong alg for key", sign(t, "ES256", "rsa-1", ti.rsaKey, ti.claims()), "", "signature verification failed"},
+		{"hs256", sign(t, "HS256", "rsa-1", ti.rsaKey, ti.claims()), "", "signature verification failed"},
+		{"alg none", sign(t, "none", "rsa-1", nil, ti.claims()), "", "signature verification failed"},
+		{"alg none without kid", sign(t, "none", "", nil, ti.claims()), "", "signature verification failed"},
+		{"unknown kid", sign(t, "RS256", "nope", ti.rsaKey, ti.claims()), "", "unknown signing key"},
+		{"malformed", "abc.def", "", "malformed token"},
+
+		{"wrong issuer", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["iss"] = "https://evil.example" })), "", "unexpected issuer"},
+		{"wrong aud", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["aud"] = "other" })), "", "not for this client"},
+		{"wrong azp", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["azp"] = "other" })), "", "another party"},
+		{"expired", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() })), "", "token expired"},
+		{"no exp", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { delete(c, "exp") })), "", "token expired"},
+		{"nbf in future", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["nbf"] = now.Add(5 * time.Minute).Unix() })), "", "not yet valid"},
+		{"iat in future", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["iat"] = now.Add(5 * time.Minute).Unix() })), "", "issued in the future"},
+		{"no subject", sign(t, "RS256", "rsa-1", ti.rsaKey, with(func(c map[string]any) { c["sub"] = "" })), "", "no subject"},
+		{"nonce mismatch", sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims()), "n-2", "nonce mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ti.oidcClient.verifyIDToken(context.Background(), tt.token, tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyIDToken: %v", err)
+				}
+				if c.Subject != "user-42" {
+					t.Errorf("subject = %q, want user-42", c.Subject)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenJWKSRefresh(t *testing.T) {
+	ti := newTestIssuer(t)
+	ctx := context.Background()
+	if _, err := ti.oidcClient.verifyIDToken(ctx, sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims()), ""); err != nil {
+		t.Fatalf("first token: %v", err)
+	}
+
+	// the issuer rotates to a new key
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.mu.Lock()
+	ti.keys = append(ti.keys, rsaJWK("rsa-2", &rotated.PublicKey))
+	ti.mu.Unlock()
+	tok := sign(t, "RS256", "rsa-2", rotated, ti.claims())
+
+	// within the refresh interval an unknown kid does not refetch
+	ti.now = ti.now.Add(jwksRefreshInterval / 2)
+	if _, err := ti.oidcClient.verifyIDToken(ctx, tok, ""); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("before refresh interval: err = %v, want unknown signing key", err)
+	}
+	if ti.jwksHits != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", ti.jwksHits)
+	}
+
+	ti.now = ti.now.Add(jwksRefreshInterval)
+	if _, err := ti.oidcClient.verifyIDToken(ctx, sign(t, "RS256", "rsa-2", rotated, ti.claims()), ""); err != nil {
+		t.Fatalf("after refresh interval: %v", err)
+	}
+	if ti.jwksHits != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", ti.jwksHits)
+	}
+	// known keys are served from the cache
+	if _, err := ti.oidcClient.verifyIDToken(ctx, sign(t, "RS256", "rsa-1", ti.rsaKey, ti.claims()), ""); err != nil {
+		t.Fatalf("cached key: %v", err)
+	}
+	if ti.jwksHits != 2 {
+		t.Errorf("jwks fetched %d times, want 2", ti.jwksHits)
+	}
+}
+
+func TestGoogleIssuerForms(t *testing.T) {
+	p := NewGoogle(googleIssuer, testClientID, "secret", nil)
+	for iss, want := range map[string]bool{
+		"https://accounts.google.com":  true,
+		"https://accounts.google.com/": true,
+		"accounts.google.com":          true,
+		"http://accounts.google.com":   false,
+		"https://accounts.google.co":   false,
+		"":                             false,
+	} {
+		if got := p.validIssuer(iss); got != want {
+			t.Errorf("validIssuer(%q) = %v, want %v", iss, got, want)
+		}
+	}
+
+	// a stand-in issuer gets no aliases
+	p = NewGoogle("http://127.0.0.1:9999", testClientID, "secret", nil)
+	if p.validIssuer("accounts.google.com") {
+		t.Error("stand-in issuer accepted accounts.google.com")
+	}
+}
@@ -0,0 +1,129 @@
+// Package oauth implements domains.OAuthProvider for GitHub and for OpenID
+// Connect providers, Google among them.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// maxResponseSize caps how much of a provider response is read.
+const maxResponseSize = 1 << 20
+
+func defaultClient(c *http.Client) *http.Client {
+	if c == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return c
+}
+
+// tokenResponse is the RFC 6749 access token response. GitHub also uses the
+// error fields with a 200 status.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	IDToken          string `json:"id_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	// Interval comes with the device flow's slow_down error.
+	Interval int64 `json:"interval"`
+}
+
+// tokenError is an error response from a token endpoint.
+type tokenError struct {
+	Code        string
+	Description string
+	Interval    time.Duration
+}
+
+func (e *tokenError) Error() string {
+	return fmt.Sprintf("token endpoint: %s: %s", e.Code, e.Description)
+}
+
+// requestToken posts form to the token endpoint and decodes the response.
+// When basicAuth is set the client credentials go in the Authorization
+// header, otherwise they must already be in form.
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values, clientID, clientSecret string, basicAuth bool) (domains.OAuthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domains.OAuthToken{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return domains.OAuthToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return domains.OAuthToken{}, err
+	}
+
+	var out tokenResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		// the body is not echoed; a form encoded success response would
+		// carry the token
+		return domains.OAuthToken{}, fmt.Errorf("token endpoint: %s: unexpected %s response", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if out.Error != "" {
+		return domains.OAuthToken{}, &tokenError{
+			Code:        out.Error,
+			Description: out.ErrorDescription,
+			Interval:    time.Duration(out.Interval) * time.Second,
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return domains.OAuthToken{}, fmt.Errorf("token endpoint: %s", resp.Status)
+	}
+	if out.AccessToken == "" {
+		return domains.OAuthToken{}, errors.New("no access token returned")
+	}
+
+	tok := domains.OAuthToken{
+		AccessToken:  domains.Secret(out.AccessToken),
+		RefreshToken: domains.Secret(out.RefreshToken),
+		IDToken:      domains.Secret(out.IDToken),
+	}
+	if out.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// getJSON fetches url into out. authorization, if set, is sent verbatim in
+// the Authorization header.
+func getJSON(ctx context.Context, client *http.Client, url, authorization string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, b)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out)
+}
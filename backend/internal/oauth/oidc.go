@@ -0,0 +1,219 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// jwksRefreshInterval limits how often an unknown key id triggers a JWKS
+// refetch, so forged tokens cannot be used to hammer the provider.
+const jwksRefreshInterval = time.Minute
+
+// OIDC is a generic OpenID Connect provider. Endpoints come from the
+// issuer's discovery document, fetched on first use, and every ID token is
+// verified against the issuer's published keys.
+type OIDC struct {
+	name   string
+	issuer string
+	// issuerAliases are further "iss" values accepted for issuer.
+	issuerAliases []string
+	clientID      string
+	clientSecret  string
+	scopes        []string
+	client        *http.Client
+	now           func() time.Time
+
+	mu          sync.Mutex
+	disc        *discovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// discovery is the subset of the provider metadata document we use.
+type discovery struct {
+	Issuer                 string   `json:"issuer"`
+	AuthorizationEndpoint  string   `json:"authorization_endpoint"`
+	TokenEndpoint          string   `json:"token_endpoint"`
+	UserinfoEndpoint       string   `json:"userinfo_endpoint"`
+	JWKSURI                string   `json:"jwks_uri"`
+	TokenEndpointAuthTypes []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// NewOIDC returns a provider for issuer. The "openid" scope is always
+// requested; scopes adds to it.
+func NewOIDC(name, issuer, clientID, clientSecret string, scopes []string, httpClient *http.Client) *OIDC {
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return &OIDC{
+		name:         name,
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		client:       defaultClient(httpClient),
+		now:          time.Now,
+	}
+}
+
+// googleIssuer is the issuer of Google accounts, config's default.
+const googleIssuer = "https://accounts.google.com"
+
+// NewGoogle returns the OpenID Connect provider for Google accounts. issuer
+// is https://accounts.google.com outside of tests. Google documents both
+// that and the scheme-less accounts.google.com as the "iss" of its ID
+// tokens, so both are accepted.
+func NewGoogle(issuer, clientID, clientSecret string, httpClient *http.Client) *OIDC {
+	p := NewOIDC("google", issuer, clientID, clientSecret, []string{"email", "profile"}, httpClient)
+	if p.issuer == googleIssuer {
+		p.issuerAliases = []string{strings.TrimPrefix(googleIssuer, "https://")}
+	}
+	return p
+}
+
+var _ domains.OAuthProvider = (*OIDC)(nil)
+
+func (p *OIDC) Name() string { return p.name }
+
+// nonceFor derives the ID token nonce from the flow's state. The state is
+// already single-use and checked by the caller, so binding the nonce to it
+// ties the ID token to this flow without storing anything else.
+func nonceFor(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *OIDC) AuthCodeURL(ctx context.Context, state, redirectURI string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.clientID},
+		"scope":         {strings.Join(p.scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonceFor(state)},
+	}
+	if redirectURI != "" {
+		q.Set("redirect_uri", redirectURI)
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+func (p *OIDC) Exchange(ctx context.Context, code, state, redirectURI string) (domains.OAuthToken, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return domains.OAuthToken{}, err
+	}
+	form := url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+	}
+	if redirectURI != "" {
+		form.Set("redirect_uri", redirectURI)
+	}
+	// client_secret_basic is the default when the provider does not list
+	// its supported methods
+	basic := len(d.TokenEndpointAuthTypes) == 0 || slices.Contains(d.TokenEndpointAuthTypes, "client_secret_basic")
+	if !basic {
+		form.Set("client_id", p.clientID)
+		form.Set("client_secret", p.clientSecret)
+	}
+	tok, err := requestToken(ctx, p.client, d.TokenEndpoint, form, p.clientID, p.clientSecret, basic)
+	if err != nil {
+		return domains.OAuthToken{}, err
+	}
+	if tok.IDToken == "" {
+		return domains.OAuthToken{}, errors.New("no id token returned")
+	}
+	if _, err := p.verifyIDToken(ctx, string(tok.IDToken), nonceFor(state)); err != nil {
+		return domains.OAuthToken{}, fmt.Errorf("id token: %w", err)
+	}
+	return tok, nil
+}
+
+// Profile reads the identity from the ID token, falling back to the
+// userinfo endpoint for an email the token does not carry.
+func (p *OIDC) Profile(ctx context.Context, tok domains.OAuthToken) (domains.OAuthProfile, error) {
+	c, err := p.verifyIDToken(ctx, string(tok.IDToken), "")
+	if err != nil {
+		return domains.OAuthProfile{}, fmt.Errorf("id token: %w", err)
+	}
+	d, err := p.discover(ctx)
+	if err != nil {
+		return domains.OAuthProfile{}, err
+	}
+	if c.Email == "" && d.UserinfoEndpoint != "" {
+		var info idClaims
+		if err := getJSON(ctx, p.client, d.UserinfoEndpoint, "Bearer "+string(tok.AccessToken), &info); err != nil {
+			return domains.OAuthProfile{}, err
+		}
+		// the userinfo response must describe the same user
+		if info.Subject != c.Subject {
+			return domains.OAuthProfile{}, errors.New("userinfo subject does not match the id token")
+		}
+		c.Email, c.EmailVerified = info.Email, info.EmailVerified
+		if c.Name == "" {
+			c.Name = info.Name
+		}
+		if c.PreferredUsername == "" {
+			c.PreferredUsername = info.PreferredUsername
+		}
+	}
+
+	login := c.PreferredUsername
+	if login == "" {
+		login = c.Name
+	}
+	if login == "" {
+		login, _, _ = strings.Cut(c.Email, "@")
+	}
+	if login == "" {
+		login = p.name + "-user"
+	}
+	return domains.OAuthProfile{
+		ProviderUserId: c.Subject,
+		Login:          login,
+		Email:          c.Email,
+		EmailVerified:  bool(c.EmailVerified),
+	}, nil
+}
+
+func (p *OIDC) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.disc != nil {
+		return p.disc, nil
+	}
+
+	var d discovery
+	if err := getJSON(ctx, p.client, p.issuer+"/.well-known/openid-configuration", "", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", d.Issuer, p.issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document is missing required endpoints")
+	}
+	// failures are not cached, so a provider outage at startup heals itself
+	p.disc = &d
+	return p.disc, nil
+}
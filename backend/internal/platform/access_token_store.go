@@ -0,0 +1,111 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/lib/pq"
+)
+
+type AccessTokenStore struct {
+	DB *sql.DB
+}
+
+func NewAccessTokenStore(db *sql.DB) *AccessTokenStore {
+	return &AccessTokenStore{DB: db}
+}
+
+var _ domains.AccessTokenRepo = (*AccessTokenStore)(nil)
+
+const accessTokenColumns = `id, userId, name, tokenHash, scopes, createdAt, lastUsedAt, expiresAt`
+
+func scanAccessToken(row rowScanner) (domains.AccessToken, error) {
+	var t domains.AccessToken
+	var lastUsedAt, expiresAt sql.NullTime
+	if err := row.Scan(&t.Id, &t.UserId, &t.Name, &t.TokenHash, pq.Array(&t.Scopes), &t.CreatedAt, &lastUsedAt, &expiresAt); err != nil {
+		return domains.AccessToken{}, err
+	}
+	t.LastUsedAt = lastUsedAt.Time
+	t.ExpiresAt = expiresAt.Time
+	return t, nil
+}
+
+func (r *AccessTokenStore) CreateAccessToken(ctx context.Context, t domains.AccessToken) (int64, error) {
+	ctx, end := observe(ctx, "access_token", "CreateAccessToken")
+	defer end()
+	var id int64
+	expiresAt := sql.NullTime{Time: t.ExpiresAt, Valid: !t.ExpiresAt.IsZero()}
+	// a nil slice would be sent as NULL
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	query := `INSERT INTO access_token (userId, name, tokenHash, scopes, expiresAt)
+              VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, t.UserId, t.Name, t.TokenHash, pq.Array(scopes), expiresAt).Scan(&id)
+	if err != nil {
+		return 0, mapErr(err)
+	}
+	return id, nil
+}
+
+func (r *AccessTokenStore) GetAccessTokenByHash(ctx context.Context, tokenHash string) (domains.AccessToken, error) {
+	ctx, end := observe(ctx, "access_token", "GetAccessTokenByHash")
+	defer end()
+	query := `SELECT ` + accessTokenColumns + ` FROM access_token WHERE tokenHash=$1`
+	t, err := scanAccessToken(r.DB.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		return domains.AccessToken{}, mapErr(err)
+	}
+	return t, nil
+}
+
+func (r *AccessTokenStore) GetAccessTokensByUser(ctx context.Context, userId int64) ([]domains.AccessToken, error) {
+	ctx, end := observe(ctx, "access_token", "GetAccessTokensByUser")
+	defer end()
+	query := `SELECT ` + accessTokenColumns + ` FROM access_token WHERE userId=$1 ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []domains.AccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *AccessTokenStore) DeleteAccessToken(ctx context.Context, userId, id int64) error {
+	ctx, end := observe(ctx, "access_token", "DeleteAccessToken")
+	defer end()
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM access_token WHERE id=$1 AND userId=$2`, id, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domains.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AccessTokenStore) UpdateAccessTokenLastUsed(ctx context.Context, id int64, t time.Time) error {
+	ctx, end := observe(ctx, "access_token", "UpdateAccessTokenLastUsed")
+	defer end()
+	_, err := r.DB.ExecContext(ctx, `UPDATE access_token SET lastUsedAt=$1 WHERE id=$2`, t, id)
+	return err
+}
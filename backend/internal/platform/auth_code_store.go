@@ -0,0 +1,71 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+type AuthCodeStore struct {
+	DB *sql.DB
+}
+
+func NewAuthCodeStore(db *sql.DB) *AuthCodeStore {
+	return &AuthCodeStore{DB: db}
+}
+
+var _ domains.AuthCodeRepo = (*AuthCodeStore)(nil)
+
+func (r *AuthCodeStore) CreateAuthCode(ctx context.Context, userId int64, codeHash, state, codeChallenge string, expiresAt time.Time) (int64, error) {
+	ctx, end := observe(ctx, "auth_code", "CreateAuthCode")
+	defer end()
+	var id int64
+	query := `INSERT INTO auth_code (userId, codeHash, state, codeChallenge, expiresAt) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	if err := r.DB.QueryRowContext(ctx, query, userId, codeHash, state, codeChallenge, expiresAt).Scan(&id); err != nil {
+		return 0, mapErr(err)
+	}
+	return id, nil
+}
+
+func (r *AuthCodeStore) RedeemAuthCode(ctx context.Context, codeHash string, now time.Time) (domains.AuthCode, error) {
+	ctx, end := observe(ctx, "auth_code", "RedeemAuthCode")
+	defer end()
+	// the conditional UPDATE is the single point of truth: of any number of
+	// concurrent redemptions exactly one sees a row come back
+	query := `UPDATE auth_code SET usedAt = $2
+              WHERE codeHash = $1 AND usedAt IS NULL AND expiresAt > $2
+              RETURNING id, userId, codeHash, state, codeChallenge, createdAt, expiresAt, usedAt`
+	var c domains.AuthCode
+	err := r.DB.QueryRowContext(ctx, query, codeHash, now).
+		Scan(&c.Id, &c.UserId, &c.CodeHash, &c.State, &c.CodeChallenge, &c.CreatedAt, &c.ExpiresAt, &c.UsedAt)
+	if err == nil {
+		return c, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return domains.AuthCode{}, err
+	}
+
+	// classify the failure for the caller's logs
+	var usedAt sql.NullTime
+	err = r.DB.QueryRowContext(ctx, `SELECT usedAt FROM auth_code WHERE codeHash = $1`, codeHash).Scan(&usedAt)
+	switch {
+	case err != nil:
+		return domains.AuthCode{}, mapErr(err)
+	case usedAt.Valid:
+		return domains.AuthCode{}, domains.ErrAuthCodeUsed
+	}
+	return domains.AuthCode{}, domains.ErrAuthCodeExpired
+}
+
+func (r *AuthCodeStore) DeleteExpiredAuthCodes(ctx context.Context, before time.Time) (int64, error) {
+	ctx, end := observe(ctx, "auth_code", "DeleteExpiredAuthCodes")
+	defer end()
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM auth_code WHERE expiresAt < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
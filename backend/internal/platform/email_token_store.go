@@ -0,0 +1,70 @@
+package platform
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+type EmailTokenStore struct {
+	DB *sql.DB
+}
+
+func NewEmailTokenStore(db *sql.DB) *EmailTokenStore {
+	return &EmailTokenStore{DB: db}
+}
+
+var _ domains.EmailTokenRepo = (*EmailTokenStore)(nil)
+
+func (r *EmailTokenStore) CreateEmailToken(ctx context.Context, t domains.EmailToken) (int64, error) {
+	ctx, end := observe(ctx, "email_token", "CreateEmailToken")
+	defer end()
+	var id int64
+	query := `INSERT INTO email_token (email, tokenHash, mobile, state, codeChallenge, expiresAt)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, t.Email, t.TokenHash, t.Mobile, t.State, t.CodeChallenge, t.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, mapErr(err)
+	}
+	return id, nil
+}
+
+func (r *EmailTokenStore) RedeemEmailToken(ctx context.Context, tokenHash string, now time.Time) (domains.EmailToken, error) {
+	ctx, end := observe(ctx, "email_token", "RedeemEmailToken")
+	defer end()
+	query := `UPDATE email_token SET usedAt = $2
+              WHERE tokenHash = $1 AND usedAt IS NULL AND expiresAt > $2
+              RETURNING id, email, tokenHash, mobile, state, codeChallenge, createdAt, expiresAt, usedAt`
+	var t domains.EmailToken
+	err := r.DB.QueryRowContext(ctx, query, tokenHash, now).
+		Scan(&t.Id, &t.Email, &t.TokenHash, &t.Mobile, &t.State, &t.CodeChallenge, &t.CreatedAt, &t.ExpiresAt, &t.UsedAt)
+	if err == nil {
+		return t, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return domains.EmailToken{}, err
+	}
+
+	var usedAt sql.NullTime
+	err = r.DB.QueryRowContext(ctx, `SELECT usedAt FROM email_token WHERE tokenHash = $1`, tokenHash).Scan(&usedAt)
+	switch {
+	case err != nil:
+		return domains.EmailToken{}, mapErr(err)
+	case usedAt.Valid:
+		return domains.EmailToken{}, domains.ErrEmailTokenUsed
+	}
+	return domains.EmailToken{}, domains.ErrEmailTokenExpired
+}
+
+func (r *EmailTokenStore) DeleteExpiredEmailTokens(ctx context.Context, before time.Time) (int64, error) {
+	ctx, end := observe(ctx, "email_token", "DeleteExpiredEmailTokens")
+	defer end()
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM email_token WHERE expiresAt < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/lib/pq"
+)
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// mapErr translates driver errors into the domains sentinels while keeping
+// the original error in the chain.
+func mapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", domains.ErrNotFound, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return fmt.Errorf("%w: %w", domains.ErrAlreadyExists, err)
+		case pqForeignKeyViolation:
+			return fmt.Errorf("%w: %w", domains.ErrNotFound, err)
+		}
+	}
+	return err
+}
@@ -18,15 +18,19 @@ func NewFollowStore(db *sql.DB) *FollowStore {
 var _ domains.FollowRepo = (*FollowStore)(nil)
 
 func (r *FollowStore) FollowUser(ctx context.Context, followerId int64, followingId int64) error {
+	ctx, end := observe(ctx, "follow", "FollowUser")
+	defer end()
 	query := `INSERT INTO followed (followerId,followingId,createdAt) values($1,$2,NOW())`
 	_, err := r.DB.ExecContext(ctx, query, followerId, followingId)
 	if err != nil {
-		return err
+		return mapErr(err)
 	}
 	return nil
 }
 
 func (r *FollowStore) UnfollowUser(ctx context.Context, followerId int64, followingId int64) error {
+	ctx, end := observe(ctx, "follow", "UnfollowUser")
+	defer end()
 	query := `DELETE FROM followed WHERE followerId=$1 AND followingId=$2`
 	_, err := r.DB.ExecContext(ctx, query, followerId, followingId)
 	if err != nil {
@@ -36,6 +40,8 @@ func (r *FollowStore) UnfollowUser(ctx context.Context, followerId int64, follow
 }
 
 func (r *FollowStore) GetFollowers(ctx context.Context, userId int64) ([]domains.User, error) {
+	ctx, end := observe(ctx, "follow", "GetFollowers")
+	defer end()
 	query := `SELECT id,userName,createdAt,updatedAt FROM users WHERE id in (SELECT followerId FROM followed WHERE followingId=$1)`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
@@ -58,6 +64,8 @@ func (r *FollowStore) GetFollowers(ctx context.Context, userId int64) ([]domains
 }
 
 func (r *FollowStore) GetFollowing(ctx context.Context, userId int64) ([]domains.User, error) {
+	ctx, end := observe(ctx, "follow", "GetFollowing")
+	defer end()
 	query := `SELECT id,userName,createdAt,updatedAt FROM users WHERE id in (SELECT followingId FROM followed WHERE followerId=$1)`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
@@ -17,46 +17,135 @@ func NewHexStore(db *sql.DB) *HexStore {
 
 var _ domains.HexRepo = (*HexStore)(nil)
 
-func (r *HexStore) CreateHex(ctx context.Context, hexValue string) (int64, error) {
+// hexColumns is the column list scanned by scanHex.
+const hexColumns = `id,hexValue,createdBy,createdAt`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHex(row rowScanner) (domains.Hex, error) {
+	var h domains.Hex
+	var createdBy sql.NullInt64
+	if err := row.Scan(&h.Id, &h.HexValue, &createdBy, &h.CreatedAt); err != nil {
+		return domains.Hex{}, err
+	}
+	h.CreatedBy = createdBy.Int64
+	return h, nil
+}
+
+func scanHexes(rows *sql.Rows) ([]domains.Hex, error) {
+	defer rows.Close()
+
+	var hexColors []domains.Hex
+	for rows.Next() {
+		h, err := scanHex(rows)
+		if err != nil {
+			return nil, err
+		}
+		hexColors = append(hexColors, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return hexColors, nil
+}
+
+func (r *HexStore) CreateHex(ctx context.Context, hexValue string, createdBy int64) (int64, error) {
+	ctx, end := observe(ctx, "hex", "CreateHex")
+	defer end()
 	var id int64
-	query := `INSERT INTO hex (hexValue) values ($1) RETURNING id`
-	err := r.DB.QueryRowContext(ctx, query, hexValue).Scan(&id)
+	var labL, labA, labB sql.NullFloat64
+	if c, err := domains.ParseColor(hexValue); err == nil {
+		lab := c.Lab()
+		labL = sql.NullFloat64{Float64: lab.L, Valid: true}
+		labA = sql.NullFloat64{Float64: lab.A, Valid: true}
+		labB = sql.NullFloat64{Float64: lab.B, Valid: true}
+	}
+	author := sql.NullInt64{Int64: createdBy, Valid: createdBy != 0}
+	query := `INSERT INTO hex (hexValue, labL, labA, labB, createdBy) values ($1, $2, $3, $4, $5) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, hexValue, labL, labA, labB, author).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, mapErr(err)
 	}
 	return id, nil
 }
 
 func (r *HexStore) GetHexById(ctx context.Context, hexId int64) (domains.Hex, error) {
-	query := `SELECT id,hexValue FROM hex WHERE id=$1`
-	row := r.DB.QueryRowContext(ctx, query, hexId)
-	var hex domains.Hex
-	if err := row.Scan(&hex.Id, &hex.HexValue); err != nil {
-		return domains.Hex{}, err
+	ctx, end := observe(ctx, "hex", "GetHexById")
+	defer end()
+	query := `SELECT ` + hexColumns + ` FROM hex WHERE id=$1`
+	hex, err := scanHex(r.DB.QueryRowContext(ctx, query, hexId))
+	if err != nil {
+		return domains.Hex{}, mapErr(err)
+	}
+	return hex, nil
+}
 
+func (r *HexStore) GetHexByValue(ctx context.Context, hexValue string) (domains.Hex, error) {
+	ctx, end := observe(ctx, "hex", "GetHexByValue")
+	defer end()
+	query := `SELECT ` + hexColumns + ` FROM hex WHERE hexValue=$1`
+	hex, err := scanHex(r.DB.QueryRowContext(ctx, query, hexValue))
+	if err != nil {
+		return domains.Hex{}, mapErr(err)
 	}
 	return hex, nil
 }
 
 func (r *HexStore) ListAllHexColors(ctx context.Context) ([]domains.Hex, error) {
-	query := `SELECT id,hexValue from hex`
+	ctx, end := observe(ctx, "hex", "ListAllHexColors")
+	defer end()
+	query := `SELECT ` + hexColumns + ` from hex`
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return scanHexes(rows)
+}
+
+func (r *HexStore) ListHexFeed(ctx context.Context, afterId int64, limit int) ([]domains.Hex, error) {
+	ctx, end := observe(ctx, "hex", "ListHexFeed")
+	defer end()
+	query := `SELECT ` + hexColumns + ` FROM hex WHERE ($1 = 0 OR id < $1) ORDER BY id DESC LIMIT $2`
+	rows, err := r.DB.QueryContext(ctx, query, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanHexes(rows)
+}
+
+func (r *HexStore) ListHexesByCreator(ctx context.Context, userId int64, afterId int64, limit int) ([]domains.Hex, error) {
+	ctx, end := observe(ctx, "hex", "ListHexesByCreator")
+	defer end()
+	query := `SELECT ` + hexColumns + ` FROM hex WHERE createdBy = $1 AND ($2 = 0 OR id < $2) ORDER BY id DESC LIMIT $3`
+	rows, err := r.DB.QueryContext(ctx, query, userId, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanHexes(rows)
+}
+
+func (r *HexStore) ListHexLabs(ctx context.Context) ([]domains.HexLab, error) {
+	ctx, end := observe(ctx, "hex", "ListHexLabs")
+	defer end()
+	query := `SELECT id,hexValue,labL,labA,labB FROM hex WHERE labL IS NOT NULL`
 	rows, err := r.DB.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var hexColors []domains.Hex
+	var points []domains.HexLab
 	for rows.Next() {
-		var h domains.Hex
-		if err := rows.Scan(&h.Id, &h.HexValue); err != nil {
+		var p domains.HexLab
+		if err := rows.Scan(&p.Id, &p.HexValue, &p.Lab.L, &p.Lab.A, &p.Lab.B); err != nil {
 			return nil, err
 		}
-		hexColors = append(hexColors, h)
+		points = append(points, p)
 	}
 	if err := rows.Err(); err != nil {
-
 		return nil, err
 	}
-	return hexColors, nil
+	return points, nil
 }
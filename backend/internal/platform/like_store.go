@@ -19,15 +19,19 @@ func NewLikeStore(db *sql.DB) *LikeStore {
 var _ domains.LikeRepo = (*LikeStore)(nil)
 
 func (r *LikeStore) AddLike(ctx context.Context, userId int64, hexId int64) error {
+	ctx, end := observe(ctx, "like", "AddLike")
+	defer end()
 	query := `INSERT INTO liked (userId,hexId,createdAt) values($1,$2,NOW())`
 	_, err := r.DB.ExecContext(ctx, query, userId, hexId)
 	if err != nil {
-		return err
+		return mapErr(err)
 	}
 	return nil
 }
 
 func (r *LikeStore) RemoveLike(ctx context.Context, userId int64, hexId int64) error {
+	ctx, end := observe(ctx, "like", "RemoveLike")
+	defer end()
 	query := `DELETE FROM liked where userId=$1 AND hexId=$2`
 	_, err := r.DB.ExecContext(ctx, query, userId, hexId)
 	if err != nil {
@@ -37,6 +41,8 @@ func (r *LikeStore) RemoveLike(ctx context.Context, userId int64, hexId int64) e
 }
 
 func (r *LikeStore) GetLikesForHex(ctx context.Context, hexId int64) ([]domains.Liked, error) {
+	ctx, end := observe(ctx, "like", "GetLikesForHex")
+	defer end()
 	query := `SELECT userId,hexId,createdAt FROM liked WHERE hexId=$1`
 	rows, err := r.DB.QueryContext(ctx, query, hexId)
 	if err != nil {
@@ -59,29 +65,19 @@ func (r *LikeStore) GetLikesForHex(ctx context.Context, hexId int64) ([]domains.
 }
 
 func (r *LikeStore) GetLikedHexesByUser(ctx context.Context, userId int64) ([]domains.Hex, error) {
-	query := `SELECT id,hexValue FROM hex WHERE id IN (SELECT hexId FROM liked WHERE userId=$1)`
+	ctx, end := observe(ctx, "like", "GetLikedHexesByUser")
+	defer end()
+	query := `SELECT ` + hexColumns + ` FROM hex WHERE id IN (SELECT hexId FROM liked WHERE userId=$1)`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var likedHexColors []domains.Hex
-	for rows.Next() {
-		var h domains.Hex
-		if err := rows.Scan(&h.Id, &h.HexValue); err != nil {
-			return nil, err
-		}
-		likedHexColors = append(likedHexColors, h)
-
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return likedHexColors, nil
+	return scanHexes(rows)
 }
 
 func (r *LikeStore) GetLikeCountsForHexes(ctx context.Context, hexIds []int64) (map[int64]int, error) {
+	ctx, end := observe(ctx, "like", "GetLikeCountsForHexes")
+	defer end()
 	// return map[hexId]count
 	if len(hexIds) == 0 {
 		return map[int64]int{}, nil
@@ -109,5 +105,32 @@ func (r *LikeStore) GetLikeCountsForHexes(ctx context.Context, hexIds []int64) (
 	return counts, nil
 }
 
+func (r *LikeStore) GetLikedHexIds(ctx context.Context, userId int64, hexIds []int64) (map[int64]bool, error) {
+	ctx, end := observe(ctx, "like", "GetLikedHexIds")
+	defer end()
+	liked := make(map[int64]bool)
+	if len(hexIds) == 0 {
+		return liked, nil
+	}
+	query := `SELECT hexId FROM liked WHERE userId=$1 AND hexId = ANY($2)`
+	rows, err := r.DB.QueryContext(ctx, query, userId, pq.Array(hexIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hexId int64
+		if err := rows.Scan(&hexId); err != nil {
+			return nil, err
+		}
+		liked[hexId] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return liked, nil
+}
+
 // pqArray converts a slice of int64 into a driver-friendly array for postgres ANY($1)
 // (helper removed; using pq.Array directly)
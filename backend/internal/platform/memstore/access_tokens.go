@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateAccessToken(ctx context.Context, t domains.AccessToken) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[t.UserId]; !ok {
+		return 0, domains.ErrNotFound
+	}
+	for _, e := range s.accessTokens {
+		if e.TokenHash == t.TokenHash {
+			return 0, domains.ErrAlreadyExists
+		}
+	}
+	t.Id = s.id("access_token")
+	t.Scopes = slices.Clone(t.Scopes)
+	t.CreatedAt = s.now()
+	t.LastUsedAt = time.Time{}
+	s.accessTokens[t.Id] = t
+	return t.Id, nil
+}
+
+func (s *Store) GetAccessTokenByHash(ctx context.Context, tokenHash string) (domains.AccessToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.accessTokens {
+		if t.TokenHash == tokenHash {
+			t.Scopes = slices.Clone(t.Scopes)
+			return t, nil
+		}
+	}
+	return domains.AccessToken{}, domains.ErrNotFound
+}
+
+func (s *Store) GetAccessTokensByUser(ctx context.Context, userId int64) ([]domains.AccessToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []domains.AccessToken
+	for _, t := range s.accessTokens {
+		if t.UserId == userId {
+			t.Scopes = slices.Clone(t.Scopes)
+			tokens = append(tokens, t)
+		}
+	}
+	return sortBy(tokens, func(t domains.AccessToken) int64 { return t.Id }), nil
+}
+
+func (s *Store) DeleteAccessToken(ctx context.Context, userId, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.accessTokens[id]
+	if !ok || t.UserId != userId {
+		return domains.ErrNotFound
+	}
+	delete(s.accessTokens, id)
+	return nil
+}
+
+func (s *Store) UpdateAccessTokenLastUsed(ctx context.Context, id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.accessTokens[id]; ok {
+		t.LastUsedAt = at
+		s.accessTokens[id] = t
+	}
+	return nil
+}
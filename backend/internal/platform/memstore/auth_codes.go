@@ -0,0 +1,68 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateAuthCode(ctx context.Context, userId int64, codeHash, state, codeChallenge string, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userId]; !ok {
+		return 0, domains.ErrNotFound
+	}
+	for _, c := range s.authCodes {
+		if c.CodeHash == codeHash {
+			return 0, domains.ErrAlreadyExists
+		}
+	}
+	c := domains.AuthCode{
+		Id:            s.id("auth_code"),
+		UserId:        userId,
+		CodeHash:      codeHash,
+		State:         state,
+		CodeChallenge: codeChallenge,
+		CreatedAt:     s.now(),
+		ExpiresAt:     expiresAt,
+	}
+	s.authCodes[c.Id] = c
+	return c.Id, nil
+}
+
+func (s *Store) RedeemAuthCode(ctx context.Context, codeHash string, now time.Time) (domains.AuthCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, c := range s.authCodes {
+		if c.CodeHash != codeHash {
+			continue
+		}
+		if !c.UsedAt.IsZero() {
+			return domains.AuthCode{}, domains.ErrAuthCodeUsed
+		}
+		if !c.ExpiresAt.After(now) {
+			return domains.AuthCode{}, domains.ErrAuthCodeExpired
+		}
+		c.UsedAt = now
+		s.authCodes[id] = c
+		return c, nil
+	}
+	return domains.AuthCode{}, domains.ErrNotFound
+}
+
+func (s *Store) DeleteExpiredAuthCodes(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, c := range s.authCodes {
+		if c.ExpiresAt.Before(before) {
+			delete(s.authCodes, id)
+			n++
+		}
+	}
+	return n, nil
+}
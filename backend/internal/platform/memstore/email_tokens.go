@@ -0,0 +1,59 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateEmailToken(ctx context.Context, t domains.EmailToken) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.emailTokens {
+		if e.TokenHash == t.TokenHash {
+			return 0, domains.ErrAlreadyExists
+		}
+	}
+	t.Id = s.id("email_token")
+	t.CreatedAt = s.now()
+	t.UsedAt = time.Time{}
+	s.emailTokens[t.Id] = t
+	return t.Id, nil
+}
+
+func (s *Store) RedeemEmailToken(ctx context.Context, tokenHash string, now time.Time) (domains.EmailToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.emailTokens {
+		if t.TokenHash != tokenHash {
+			continue
+		}
+		if !t.UsedAt.IsZero() {
+			return domains.EmailToken{}, domains.ErrEmailTokenUsed
+		}
+		if !t.ExpiresAt.After(now) {
+			return domains.EmailToken{}, domains.ErrEmailTokenExpired
+		}
+		t.UsedAt = now
+		s.emailTokens[id] = t
+		return t, nil
+	}
+	return domains.EmailToken{}, domains.ErrNotFound
+}
+
+func (s *Store) DeleteExpiredEmailTokens(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, t := range s.emailTokens {
+		if t.ExpiresAt.Before(before) {
+			delete(s.emailTokens, id)
+			n++
+		}
+	}
+	return n, nil
+}
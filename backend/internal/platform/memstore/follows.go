@@ -0,0 +1,59 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) FollowUser(ctx context.Context, followerId int64, followingId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[followerId]; !ok {
+		return domains.ErrNotFound
+	}
+	if _, ok := s.users[followingId]; !ok {
+		return domains.ErrNotFound
+	}
+	k := followKey{followerId: followerId, followingId: followingId}
+	if _, ok := s.follows[k]; ok {
+		return domains.ErrAlreadyExists
+	}
+	s.follows[k] = s.now()
+	return nil
+}
+
+func (s *Store) UnfollowUser(ctx context.Context, followerId int64, followingId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.follows, followKey{followerId: followerId, followingId: followingId})
+	return nil
+}
+
+func (s *Store) GetFollowers(ctx context.Context, userId int64) ([]domains.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []domains.User
+	for k := range s.follows {
+		if k.followingId == userId {
+			users = append(users, s.users[k.followerId])
+		}
+	}
+	return sortBy(users, func(u domains.User) int64 { return u.Id }), nil
+}
+
+func (s *Store) GetFollowing(ctx context.Context, userId int64) ([]domains.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []domains.User
+	for k := range s.follows {
+		if k.followerId == userId {
+			users = append(users, s.users[k.followingId])
+		}
+	}
+	return sortBy(users, func(u domains.User) int64 { return u.Id }), nil
+}
@@ -0,0 +1,103 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateHex(ctx context.Context, hexValue string, createdBy int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range s.hexes {
+		if h.HexValue == hexValue {
+			return 0, domains.ErrAlreadyExists
+		}
+	}
+	if _, ok := s.users[createdBy]; createdBy != 0 && !ok {
+		return 0, domains.ErrNotFound
+	}
+	h := domains.Hex{Id: s.id("hex"), HexValue: hexValue, CreatedBy: createdBy, CreatedAt: s.now()}
+	s.hexes[h.Id] = h
+	return h.Id, nil
+}
+
+func (s *Store) GetHexById(ctx context.Context, hexId int64) (domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.hexes[hexId]
+	if !ok {
+		return domains.Hex{}, domains.ErrNotFound
+	}
+	return h, nil
+}
+
+func (s *Store) GetHexByValue(ctx context.Context, hexValue string) (domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, h := range s.hexes {
+		if h.HexValue == hexValue {
+			return h, nil
+		}
+	}
+	return domains.Hex{}, domains.ErrNotFound
+}
+
+func (s *Store) ListAllHexColors(ctx context.Context) ([]domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.hexes, func(h domains.Hex) int64 { return h.Id }), nil
+}
+
+func (s *Store) ListHexFeed(ctx context.Context, afterId int64, limit int) ([]domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := sortedValues(s.hexes, func(h domains.Hex) int64 { return -h.Id })
+	var out []domains.Hex
+	for _, h := range all {
+		if len(out) == limit {
+			break
+		}
+		if afterId == 0 || h.Id < afterId {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListHexesByCreator(ctx context.Context, userId int64, afterId int64, limit int) ([]domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := sortedValues(s.hexes, func(h domains.Hex) int64 { return -h.Id })
+	var out []domains.Hex
+	for _, h := range all {
+		if len(out) == limit {
+			break
+		}
+		if h.CreatedBy == userId && (afterId == 0 || h.Id < afterId) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) ListHexLabs(ctx context.Context) ([]domains.HexLab, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var points []domains.HexLab
+	for _, h := range sortedValues(s.hexes, func(h domains.Hex) int64 { return h.Id }) {
+		c, err := domains.ParseColor(h.HexValue)
+		if err != nil {
+			continue
+		}
+		points = append(points, domains.HexLab{Hex: h, Lab: c.Lab()})
+	}
+	return points, nil
+}
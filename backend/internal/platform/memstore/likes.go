@@ -0,0 +1,89 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) AddLike(ctx context.Context, userId int64, hexId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userId]; !ok {
+		return domains.ErrNotFound
+	}
+	if _, ok := s.hexes[hexId]; !ok {
+		return domains.ErrNotFound
+	}
+	k := likeKey{userId: userId, hexId: hexId}
+	if _, ok := s.likes[k]; ok {
+		return domains.ErrAlreadyExists
+	}
+	s.likes[k] = domains.Liked{UserId: userId, HexId: hexId, CreatedAt: s.now()}
+	return nil
+}
+
+func (s *Store) RemoveLike(ctx context.Context, userId int64, hexId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.likes, likeKey{userId: userId, hexId: hexId})
+	return nil
+}
+
+func (s *Store) GetLikesForHex(ctx context.Context, hexId int64) ([]domains.Liked, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var liked []domains.Liked
+	for k, l := range s.likes {
+		if k.hexId == hexId {
+			liked = append(liked, l)
+		}
+	}
+	return sortBy(liked, func(l domains.Liked) int64 { return l.UserId }), nil
+}
+
+func (s *Store) GetLikedHexesByUser(ctx context.Context, userId int64) ([]domains.Hex, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hexes []domains.Hex
+	for k := range s.likes {
+		if k.userId == userId {
+			hexes = append(hexes, s.hexes[k.hexId])
+		}
+	}
+	return sortBy(hexes, func(h domains.Hex) int64 { return h.Id }), nil
+}
+
+func (s *Store) GetLikeCountsForHexes(ctx context.Context, hexIds []int64) (map[int64]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	want := make(map[int64]bool, len(hexIds))
+	for _, id := range hexIds {
+		want[id] = true
+	}
+	counts := make(map[int64]int)
+	for k := range s.likes {
+		if want[k.hexId] {
+			counts[k.hexId]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *Store) GetLikedHexIds(ctx context.Context, userId int64, hexIds []int64) (map[int64]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	liked := make(map[int64]bool)
+	for _, id := range hexIds {
+		if _, ok := s.likes[likeKey{userId: userId, hexId: id}]; ok {
+			liked[id] = true
+		}
+	}
+	return liked, nil
+}
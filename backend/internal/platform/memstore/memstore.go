@@ -0,0 +1,108 @@
+// Package memstore is an in-memory, concurrency-safe implementation of every
+// domains repository. It mirrors the Postgres schema's constraints (unique
+// keys, foreign keys and ON DELETE CASCADE) so handlers can be exercised
+// without a database.
+package memstore
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+type likeKey struct {
+	userId int64
+	hexId  int64
+}
+
+type followKey struct {
+	followerId  int64
+	followingId int64
+}
+
+// Store holds all tables behind a single lock so that cross-table rules such
+// as cascading deletes stay atomic.
+type Store struct {
+	mu sync.RWMutex
+
+	// now is the clock used for createdAt style columns.
+	now func() time.Time
+
+	nextId map[string]int64
+
+	users     map[int64]domains.User
+	hexes     map[int64]domains.Hex
+	sessions  map[int64]domains.Session
+	oauths    map[int64]domains.Oauth
+	likes     map[likeKey]domains.Liked
+	follows   map[followKey]time.Time
+	authCodes map[int64]domains.AuthCode
+	// emailTokens has no foreign keys: a login link may be for an address
+	// that has no user yet.
+	emailTokens  map[int64]domains.EmailToken
+	accessTokens map[int64]domains.AccessToken
+}
+
+func New() *Store {
+	return &Store{
+		now:          time.Now,
+		nextId:       make(map[string]int64),
+		users:        make(map[int64]domains.User),
+		hexes:        make(map[int64]domains.Hex),
+		sessions:     make(map[int64]domains.Session),
+		oauths:       make(map[int64]domains.Oauth),
+		likes:        make(map[likeKey]domains.Liked),
+		follows:      make(map[followKey]time.Time),
+		authCodes:    make(map[int64]domains.AuthCode),
+		emailTokens:  make(map[int64]domains.EmailToken),
+		accessTokens: make(map[int64]domains.AccessToken),
+	}
+}
+
+var (
+	_ domains.UserRepo        = (*Store)(nil)
+	_ domains.HexRepo         = (*Store)(nil)
+	_ domains.LikeRepo        = (*Store)(nil)
+	_ domains.FollowRepo      = (*Store)(nil)
+	_ domains.SessionRepo     = (*Store)(nil)
+	_ domains.OauthRepo       = (*Store)(nil)
+	_ domains.AuthCodeRepo    = (*Store)(nil)
+	_ domains.EmailTokenRepo  = (*Store)(nil)
+	_ domains.AccessTokenRepo = (*Store)(nil)
+)
+
+// SetClock replaces the clock used for createdAt style columns, so tests can
+// create rows in the past.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// id hands out identity values per table, starting at 1 like Postgres.
+// Callers must hold the write lock.
+func (s *Store) id(table string) int64 {
+	s.nextId[table]++
+	return s.nextId[table]
+}
+
+// sortedValues returns the map values ordered by the key function.
+func sortedValues[K comparable, V any](m map[K]V, key func(V) int64) []V {
+	out := make([]V, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return sortBy(out, key)
+}
+
+// sortBy orders vs in place by the key function, mimicking ORDER BY id so
+// results are deterministic.
+func sortBy[V any](vs []V, key func(V) int64) []V {
+	slices.SortFunc(vs, func(a, b V) int {
+		return cmp.Compare(key(a), key(b))
+	})
+	return vs
+}
@@ -0,0 +1,13 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/storetest"
+)
+
+func TestMemstore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return storetest.Memstore()
+	})
+}
@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateOauth(ctx context.Context, userId int64, provider, providerUserId string, accessToken, refreshToken domains.Secret) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userId]; !ok {
+		return 0, domains.ErrNotFound
+	}
+	// (provider, providerUserId) carries a UNIQUE constraint in the oauth table
+	for _, o := range s.oauths {
+		if o.Provider == provider && o.ProviderUserId == providerUserId {
+			return 0, domains.ErrAlreadyExists
+		}
+	}
+	o := domains.Oauth{
+		Id:             s.id("oauth"),
+		UserId:         userId,
+		Provider:       provider,
+		ProviderUserId: providerUserId,
+		AccessToken:    accessToken,
+		RefreshToken:   refreshToken,
+		CreatedAt:      s.now(),
+	}
+	s.oauths[o.Id] = o
+	return o.Id, nil
+}
+
+func (s *Store) GetOauthByProviderUserID(ctx context.Context, provider, providerUserId string) (domains.Oauth, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, o := range s.oauths {
+		if o.Provider == provider && o.ProviderUserId == providerUserId {
+			return o, nil
+		}
+	}
+	return domains.Oauth{}, domains.ErrNotFound
+}
+
+func (s *Store) GetOauthsByUser(ctx context.Context, userId int64) ([]domains.Oauth, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var oauths []domains.Oauth
+	for _, o := range s.oauths {
+		if o.UserId == userId {
+			oauths = append(oauths, o)
+		}
+	}
+	return sortBy(oauths, func(o domains.Oauth) int64 { return o.Id }), nil
+}
+
+func (s *Store) DeleteOauth(ctx context.Context, userId, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.oauths[id]
+	if !ok || o.UserId != userId {
+		return domains.ErrNotFound
+	}
+	n := 0
+	for _, o := range s.oauths {
+		if o.UserId == userId {
+			n++
+		}
+	}
+	if n == 1 {
+		return domains.ErrLastIdentity
+	}
+	delete(s.oauths, id)
+	return nil
+}
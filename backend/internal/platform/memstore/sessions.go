@@ -0,0 +1,86 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateSession(ctx context.Context, userId int64, secretHash, userAgent, ip string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userId]; !ok {
+		return 0, domains.ErrNotFound
+	}
+	now := s.now()
+	sess := domains.Session{
+		Id:             s.id("session"),
+		UserId:         userId,
+		SecretHash:     []byte(secretHash),
+		CreatedAt:      now,
+		LastVerifiedAt: now,
+		UserAgent:      userAgent,
+		IP:             ip,
+	}
+	s.sessions[sess.Id] = sess
+	return sess.Id, nil
+}
+
+func (s *Store) GetSessionById(ctx context.Context, id int64) (domains.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return domains.Session{}, domains.ErrNotFound
+	}
+	return sess, nil
+}
+
+func (s *Store) GetSessionsByUser(ctx context.Context, userId int64) ([]domains.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []domains.Session
+	for _, sess := range s.sessions {
+		if sess.UserId == userId {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sortBy(sessions, func(sess domains.Session) int64 { return sess.Id }), nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *Store) UpdateLastVerified(ctx context.Context, id int64, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[id]; ok {
+		sess.LastVerifiedAt = t
+		s.sessions[id] = sess
+	}
+	return nil
+}
+
+func (s *Store) DeleteExpiredSessions(ctx context.Context, createdBefore, verifiedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, sess := range s.sessions {
+		if sess.CreatedAt.Before(createdBefore) || sess.LastVerifiedAt.Before(verifiedBefore) {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+func (s *Store) CreateUser(ctx context.Context, username string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	u := domains.User{Id: s.id("users"), UserName: username, CreatedAt: now, UpdatedAt: now}
+	s.users[u.Id] = u
+	return u.Id, nil
+}
+
+func (s *Store) GetAllUser(ctx context.Context) ([]domains.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.users, func(u domains.User) int64 { return u.Id }), nil
+}
+
+func (s *Store) GetUserById(ctx context.Context, userId int64) (domains.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[userId]
+	if !ok {
+		return domains.User{}, domains.ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userId]; !ok {
+		return domains.ErrNotFound
+	}
+	delete(s.users, userId)
+
+	// ON DELETE CASCADE for every table referencing users(id)
+	for id, sess := range s.sessions {
+		if sess.UserId == userId {
+			delete(s.sessions, id)
+		}
+	}
+	for id, o := range s.oauths {
+		if o.UserId == userId {
+			delete(s.oauths, id)
+		}
+	}
+	for id, c := range s.authCodes {
+		if c.UserId == userId {
+			delete(s.authCodes, id)
+		}
+	}
+	for id, t := range s.accessTokens {
+		if t.UserId == userId {
+			delete(s.accessTokens, id)
+		}
+	}
+	for k := range s.likes {
+		if k.userId == userId {
+			delete(s.likes, k)
+		}
+	}
+	for k := range s.follows {
+		if k.followerId == userId || k.followingId == userId {
+			delete(s.follows, k)
+		}
+	}
+	// ON DELETE SET NULL for hex authorship
+	for id, h := range s.hexes {
+		if h.CreatedBy == userId {
+			h.CreatedBy = 0
+			s.hexes[id] = h
+		}
+	}
+	return nil
+}
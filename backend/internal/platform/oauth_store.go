@@ -3,45 +3,64 @@ package platform
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"slices"
 
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/keyring"
 )
 
+// OauthStore persists linked provider identities. Provider tokens are
+// encrypted with Keys before they reach the database; without a keyring
+// they are stored in plaintext, as rows written before encryption was
+// introduced are.
 type OauthStore struct {
-	DB *sql.DB
+	DB   *sql.DB
+	Keys *keyring.Keyring
 }
 
-func NewOauthStore(db *sql.DB) *OauthStore {
-	return &OauthStore{DB: db}
+func NewOauthStore(db *sql.DB, keys *keyring.Keyring) *OauthStore {
+	return &OauthStore{DB: db, Keys: keys}
 }
 
 var _ domains.OauthRepo = (*OauthStore)(nil)
 
-func (r *OauthStore) CreateOauth(ctx context.Context, userId int64, provider, providerUserId, accessToken, refreshToken string) (int64, error) {
+const oauthColumns = `id, userId, provider, providerUserId, accessToken, refreshToken, keyId, dataKey, createdAt`
+
+func (r *OauthStore) CreateOauth(ctx context.Context, userId int64, provider, providerUserId string, accessToken, refreshToken domains.Secret) (int64, error) {
+	ctx, end := observe(ctx, "oauth", "CreateOauth")
+	defer end()
+	enc, err := r.seal(provider, providerUserId, accessToken, refreshToken)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	query := `INSERT INTO oauth (userId, provider, providerUserId, accessToken, refreshToken, createdAt)
-              VALUES ($1,$2,$3,$4,$5,NOW())
+	query := `INSERT INTO oauth (userId, provider, providerUserId, accessToken, refreshToken, keyId, dataKey, createdAt)
+              VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())
               RETURNING id`
-	err := r.DB.QueryRowContext(ctx, query, userId, provider, providerUserId, accessToken, refreshToken).Scan(&id)
+	err = r.DB.QueryRowContext(ctx, query, userId, provider, providerUserId, enc.accessToken, enc.refreshToken, enc.keyId, enc.dataKey).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, mapErr(err)
 	}
 	return id, nil
 }
 
 func (r *OauthStore) GetOauthByProviderUserID(ctx context.Context, provider, providerUserId string) (domains.Oauth, error) {
-	query := `SELECT id, userId, provider, providerUserId, accessToken, refreshToken, createdAt
-              FROM oauth WHERE provider=$1 AND providerUserId=$2`
-	row := r.DB.QueryRowContext(ctx, query, provider, providerUserId)
-	var o domains.Oauth
-	if err := row.Scan(&o.Id, &o.UserId, &o.Provider, &o.ProviderUserId, &o.AccessToken, &o.RefreshToken, &o.CreatedAt); err != nil {
-		return domains.Oauth{}, err
+	ctx, end := observe(ctx, "oauth", "GetOauthByProviderUserID")
+	defer end()
+	query := `SELECT ` + oauthColumns + ` FROM oauth WHERE provider=$1 AND providerUserId=$2`
+	o, err := r.scanOauth(r.DB.QueryRowContext(ctx, query, provider, providerUserId))
+	if err != nil {
+		return domains.Oauth{}, mapErr(err)
 	}
 	return o, nil
 }
 
 func (r *OauthStore) GetOauthsByUser(ctx context.Context, userId int64) ([]domains.Oauth, error) {
-	query := `SELECT id, userId, provider, providerUserId, accessToken, refreshToken, createdAt FROM oauth WHERE userId=$1`
+	ctx, end := observe(ctx, "oauth", "GetOauthsByUser")
+	defer end()
+	query := `SELECT ` + oauthColumns + ` FROM oauth WHERE userId=$1 ORDER BY id`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, err
@@ -50,8 +69,8 @@ func (r *OauthStore) GetOauthsByUser(ctx context.Context, userId int64) ([]domai
 
 	var oauths []domains.Oauth
 	for rows.Next() {
-		var o domains.Oauth
-		if err := rows.Scan(&o.Id, &o.UserId, &o.Provider, &o.ProviderUserId, &o.AccessToken, &o.RefreshToken, &o.CreatedAt); err != nil {
+		o, err := r.scanOauth(rows)
+		if err != nil {
 			return nil, err
 		}
 		oauths = append(oauths, o)
@@ -61,3 +80,212 @@ func (r *OauthStore) GetOauthsByUser(ctx context.Context, userId int64) ([]domai
 	}
 	return oauths, nil
 }
+
+func (r *OauthStore) DeleteOauth(ctx context.Context, userId, id int64) error {
+	ctx, end := observe(ctx, "oauth", "DeleteOauth")
+	defer end()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// locking every identity of the user serialises concurrent unlinks, which
+	// could otherwise each see two rows and remove both
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM oauth WHERE userId=$1 FOR UPDATE`, userId)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var oid int64
+		if err := rows.Scan(&oid); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, oid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !slices.Contains(ids, id) {
+		return domains.ErrNotFound
+	}
+	if len(ids) == 1 {
+		return domains.ErrLastIdentity
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM oauth WHERE id=$1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RotateKeys moves every row onto the active key, batch rows per
+// transaction: data keys wrapped by an older key are rewrapped and rows
+// still holding plaintext tokens are encrypted. It returns the number of
+// rows changed. Every key that wrapped a row must still be in the keyring.
+func (r *OauthStore) RotateKeys(ctx context.Context, batch int) (int, error) {
+	if r.Keys == nil {
+		return 0, fmt.Errorf("rotate oauth keys: no token encryption keys configured")
+	}
+	total := 0
+	for {
+		n, err := r.rotateBatch(ctx, batch)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < batch {
+			return total, nil
+		}
+	}
+}
+
+func (r *OauthStore) rotateBatch(ctx context.Context, batch int) (int, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+oauthColumns+` FROM oauth WHERE keyId <> $1 ORDER BY id LIMIT $2 FOR UPDATE`, r.Keys.Active(), batch)
+	if err != nil {
+		return 0, err
+	}
+	var updates []sealedTokens
+	var ids []int64
+	for rows.Next() {
+		var o domains.Oauth
+		var enc sealedTokens
+		if err := rows.Scan(&o.Id, &o.UserId, &o.Provider, &o.ProviderUserId, &enc.accessToken, &enc.refreshToken, &enc.keyId, &enc.dataKey, &o.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if enc.keyId == "" {
+			enc, err = r.seal(o.Provider, o.ProviderUserId, domains.Secret(enc.accessToken), domains.Secret(enc.refreshToken))
+		} else {
+			enc, err = r.rewrap(enc)
+		}
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("oauth row %d: %w", o.Id, err)
+		}
+		updates = append(updates, enc)
+		ids = append(ids, o.Id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, enc := range updates {
+		_, err := tx.ExecContext(ctx, `UPDATE oauth SET accessToken=$1, refreshToken=$2, keyId=$3, dataKey=$4 WHERE id=$5`,
+			enc.accessToken, enc.refreshToken, enc.keyId, enc.dataKey, ids[i])
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
+
+// sealedTokens is how a row's tokens are stored: base64 ciphertexts and
+// the wrapped data key, or plaintext with an empty keyId.
+type sealedTokens struct {
+	accessToken, refreshToken string
+	keyId, dataKey            string
+}
+
+// tokenAD binds a ciphertext to the identity and column it was written for.
+func tokenAD(provider, providerUserId, column string) []byte {
+	return []byte(provider + "\x00" + providerUserId + "\x00" + column)
+}
+
+func (r *OauthStore) seal(provider, providerUserId string, accessToken, refreshToken domains.Secret) (sealedTokens, error) {
+	if r.Keys == nil {
+		return sealedTokens{accessToken: string(accessToken), refreshToken: string(refreshToken)}, nil
+	}
+	dk, env, err := r.Keys.NewDataKey()
+	if err != nil {
+		return sealedTokens{}, err
+	}
+	return sealedTokens{
+		accessToken:  sealToken(dk, accessToken, tokenAD(provider, providerUserId, "accessToken")),
+		refreshToken: sealToken(dk, refreshToken, tokenAD(provider, providerUserId, "refreshToken")),
+		keyId:        env.KeyId,
+		dataKey:      base64.StdEncoding.EncodeToString(env.Wrapped),
+	}, nil
+}
+
+func (r *OauthStore) rewrap(enc sealedTokens) (sealedTokens, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(enc.dataKey)
+	if err != nil {
+		return sealedTokens{}, fmt.Errorf("decode data key: %w", err)
+	}
+	env, err := r.Keys.Rewrap(keyring.Envelope{KeyId: enc.keyId, Wrapped: wrapped})
+	if err != nil {
+		return sealedTokens{}, err
+	}
+	enc.keyId = env.KeyId
+	enc.dataKey = base64.StdEncoding.EncodeToString(env.Wrapped)
+	return enc, nil
+}
+
+// scanOauth reads a row selected with oauthColumns and decrypts its tokens.
+func (r *OauthStore) scanOauth(row rowScanner) (domains.Oauth, error) {
+	var o domains.Oauth
+	var enc sealedTokens
+	if err := row.Scan(&o.Id, &o.UserId, &o.Provider, &o.ProviderUserId, &enc.accessToken, &enc.refreshToken, &enc.keyId, &enc.dataKey, &o.CreatedAt); err != nil {
+		return domains.Oauth{}, err
+	}
+	if enc.keyId == "" {
+		o.AccessToken = domains.Secret(enc.accessToken)
+		o.RefreshToken = domains.Secret(enc.refreshToken)
+		return o, nil
+	}
+	if r.Keys == nil {
+		return domains.Oauth{}, fmt.Errorf("oauth row %d is encrypted but no token encryption keys are configured", o.Id)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(enc.dataKey)
+	if err != nil {
+		return domains.Oauth{}, fmt.Errorf("oauth row %d: decode data key: %w", o.Id, err)
+	}
+	dk, err := r.Keys.Open(keyring.Envelope{KeyId: enc.keyId, Wrapped: wrapped})
+	if err != nil {
+		return domains.Oauth{}, fmt.Errorf("oauth row %d: %w", o.Id, err)
+	}
+	if o.AccessToken, err = openToken(dk, enc.accessToken, tokenAD(o.Provider, o.ProviderUserId, "accessToken")); err != nil {
+		return domains.Oauth{}, fmt.Errorf("oauth row %d: access token: %w", o.Id, err)
+	}
+	if o.RefreshToken, err = openToken(dk, enc.refreshToken, tokenAD(o.Provider, o.ProviderUserId, "refreshToken")); err != nil {
+		return domains.Oauth{}, fmt.Errorf("oauth row %d: refresh token: %w", o.Id, err)
+	}
+	return o, nil
+}
+
+// sealToken leaves an empty token empty; there is nothing to hide.
+func sealToken(dk keyring.DataKey, tok domains.Secret, ad []byte) string {
+	if tok == "" {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(dk.Seal([]byte(tok), ad))
+}
+
+func openToken(dk keyring.DataKey, enc string, ad []byte) (domains.Secret, error) {
+	if enc == "" {
+		return "", nil
+	}
+	ct, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", err
+	}
+	pt, err := dk.Open(ct, ad)
+	if err != nil {
+		return "", err
+	}
+	return domains.Secret(pt), nil
+}
@@ -0,0 +1,141 @@
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/keyring"
+)
+
+// oauthRow stands in for a row selected with oauthColumns.
+type oauthRow struct {
+	provider, providerUserId string
+	enc                      sealedTokens
+}
+
+func (r oauthRow) Scan(dest ...any) error {
+	vals := []any{int64(7), int64(1), r.provider, r.providerUserId, r.enc.accessToken, r.enc.refreshToken, r.enc.keyId, r.enc.dataKey, time.Time{}}
+	if len(dest) != len(vals) {
+		return errors.New("column count mismatch")
+	}
+	for i, v := range vals {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func testKeys(t *testing.T, active string, ids ...string) *keyring.Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		// the same id always gets the same key
+		keys[id] = bytes.Repeat([]byte(id[len(id)-1:]), keyring.KeySize)
+	}
+	k, err := keyring.New(active, keys)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return k
+}
+
+func TestOauthTokenSealing(t *testing.T) {
+	r := NewOauthStore(nil, testKeys(t, "k1", "k1", "k2"))
+	enc, err := r.seal("github", "gh-1", "access", "refresh")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if enc.keyId != "k1" || enc.accessToken == "access" || enc.refreshToken == "refresh" {
+		t.Fatalf("seal = %+v, want ciphertexts under k1", enc)
+	}
+
+	o, err := r.scanOauth(oauthRow{"github", "gh-1", enc})
+	if err != nil {
+		t.Fatalf("scanOauth: %v", err)
+	}
+	if o.AccessToken != "access" || o.RefreshToken != "refresh" {
+		t.Errorf("tokens = %q, %q; want access, refresh", string(o.AccessToken), string(o.RefreshToken))
+	}
+
+	swapped := enc
+	swapped.accessToken, swapped.refreshToken = enc.refreshToken, enc.accessToken
+	relabelled := enc
+	relabelled.keyId = "k2"
+	other, err := r.seal("github", "gh-2", "access", "refresh")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	foreignKey := enc
+	foreignKey.dataKey = other.dataKey
+	for name, row := range map[string]oauthRow{
+		"columns swapped":      {"github", "gh-1", swapped},
+		"other user id":        {"github", "gh-2", enc},
+		"other provider":       {"google", "gh-1", enc},
+		"key id relabelled":    {"github", "gh-1", relabelled},
+		"another row data key": {"github", "gh-1", foreignKey},
+	} {
+		if o, err := r.scanOauth(row); err == nil {
+			t.Errorf("%s: scanOauth = %+v, want an error", name, o)
+		}
+	}
+
+	// a store without keys refuses encrypted rows rather than returning
+	// ciphertext as the token
+	if _, err := NewOauthStore(nil, nil).scanOauth(oauthRow{"github", "gh-1", enc}); err == nil {
+		t.Error("scanOauth without keys accepted an encrypted row")
+	}
+}
+
+func TestOauthEmptyAndPlaintextTokens(t *testing.T) {
+	r := NewOauthStore(nil, testKeys(t, "k1", "k1"))
+	enc, err := r.seal("github", "gh-1", "access", "")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if enc.refreshToken != "" {
+		t.Errorf("empty refresh token sealed to %q", enc.refreshToken)
+	}
+	o, err := r.scanOauth(oauthRow{"github", "gh-1", enc})
+	if err != nil || o.AccessToken != "access" || o.RefreshToken != "" {
+		t.Errorf("scanOauth = %+v, %v", o, err)
+	}
+
+	// rows written before encryption are read as they are
+	plain := sealedTokens{accessToken: "old-access", refreshToken: "old-refresh"}
+	o, err = r.scanOauth(oauthRow{"github", "gh-1", plain})
+	if err != nil || o.AccessToken != "old-access" || o.RefreshToken != "old-refresh" {
+		t.Errorf("scanOauth(plaintext) = %+v, %v", o, err)
+	}
+}
+
+func TestOauthRewrap(t *testing.T) {
+	before := NewOauthStore(nil, testKeys(t, "k1", "k1", "k2"))
+	enc, err := before.seal("github", "gh-1", "access", "refresh")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	after := NewOauthStore(nil, testKeys(t, "k2", "k1", "k2"))
+	rewrapped, err := after.rewrap(enc)
+	if err != nil {
+		t.Fatalf("rewrap: %v", err)
+	}
+	if rewrapped.keyId != "k2" || rewrapped.dataKey == enc.dataKey {
+		t.Fatalf("rewrap = %+v, want a data key wrapped by k2", rewrapped)
+	}
+	if rewrapped.accessToken != enc.accessToken || rewrapped.refreshToken != enc.refreshToken {
+		t.Error("rewrap changed the token ciphertexts")
+	}
+
+	// the tokens decrypt after k1 is retired
+	retired := NewOauthStore(nil, testKeys(t, "k2", "k2"))
+	o, err := retired.scanOauth(oauthRow{"github", "gh-1", rewrapped})
+	if err != nil || o.AccessToken != "access" || o.RefreshToken != "refresh" {
+		t.Errorf("scanOauth after rotation = %+v, %v", o, err)
+	}
+	if _, err := retired.scanOauth(oauthRow{"github", "gh-1", enc}); !errors.Is(err, keyring.ErrUnknownKey) {
+		t.Errorf("scanOauth(unrotated row) err = %v, want ErrUnknownKey", err)
+	}
+}
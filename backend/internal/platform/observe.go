@@ -0,0 +1,24 @@
+package platform
+
+import (
+	"context"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
+	"github.com/HimanshuKumarDutt094/hextok/internal/tracing"
+)
+
+// observe starts a span named store.method and times the call for
+// metrics.StoreQueryDuration. The returned context parents the query spans
+// of the statements the method runs; call end when it returns:
+//
+//	ctx, end := observe(ctx, "user", "CreateUser")
+//	defer end()
+func observe(ctx context.Context, store, method string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Tracer.Start(ctx, store+"."+method)
+	return ctx, func() {
+		span.End()
+		metrics.StoreQueryDuration.WithLabelValues(store, method).Observe(time.Since(start).Seconds())
+	}
+}
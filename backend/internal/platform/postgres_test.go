@@ -0,0 +1,112 @@
+package platform_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/db"
+	"github.com/HimanshuKumarDutt094/hextok/internal/db/migrations"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/keyring"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/storetest"
+)
+
+// testDSNEnv names a disposable database the Postgres stores are checked
+// against. Every table in it is truncated.
+const testDSNEnv = "HEXTOK_TEST_DATABASE_URL"
+
+// testDB connects to the test database and migrates it, skipping the test
+// when none is configured.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+	d, err := db.New(config.Database{DSN: dsn, PingTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	if _, err := migrations.NewMigrator(d).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return d
+}
+
+func TestPostgres(t *testing.T) {
+	d := testDB(t)
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return storetest.Postgres(t, d)
+	})
+}
+
+func TestOauthRotateKeys(t *testing.T) {
+	d := testDB(t)
+	s := storetest.Postgres(t, d)
+	ctx := context.Background()
+	user, err := s.Users.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	keys := map[string][]byte{"old": bytes.Repeat([]byte{1}, keyring.KeySize), "new": bytes.Repeat([]byte{2}, keyring.KeySize)}
+	ring := func(active string, ids ...string) *keyring.Keyring {
+		m := make(map[string][]byte)
+		for _, id := range ids {
+			m[id] = keys[id]
+		}
+		k, err := keyring.New(active, m)
+		if err != nil {
+			t.Fatalf("keyring: %v", err)
+		}
+		return k
+	}
+
+	// one row from before encryption, three under the old key
+	if _, err := d.ExecContext(ctx, `INSERT INTO oauth (userId, provider, providerUserId, accessToken, refreshToken, createdAt) VALUES ($1, 'github', 'plain', 'plain-access', 'plain-refresh', NOW())`, user); err != nil {
+		t.Fatalf("insert plaintext row: %v", err)
+	}
+	old := platform.NewOauthStore(d, ring("old", "old"))
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := old.CreateOauth(ctx, user, "google", id, domains.Secret("access-"+id), domains.Secret("refresh-"+id)); err != nil {
+			t.Fatalf("CreateOauth(%s): %v", id, err)
+		}
+	}
+
+	rotator := platform.NewOauthStore(d, ring("new", "old", "new"))
+	n, err := rotator.RotateKeys(ctx, 2)
+	if err != nil || n != 4 {
+		t.Fatalf("RotateKeys = %d, %v; want 4 rows", n, err)
+	}
+	if n, err := rotator.RotateKeys(ctx, 2); err != nil || n != 0 {
+		t.Errorf("RotateKeys again = %d, %v; want 0", n, err)
+	}
+
+	var plaintext int
+	if err := d.QueryRowContext(ctx, `SELECT COUNT(*) FROM oauth WHERE keyId <> 'new' OR accessToken LIKE '%access%'`).Scan(&plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != 0 {
+		t.Errorf("%d rows are not encrypted under the new key", plaintext)
+	}
+
+	// everything reads back with the old key retired
+	current := platform.NewOauthStore(d, ring("new", "new"))
+	o, err := current.GetOauthByProviderUserID(ctx, "github", "plain")
+	if err != nil || o.AccessToken != "plain-access" || o.RefreshToken != "plain-refresh" {
+		t.Errorf("migrated plaintext row = %+v, %v", o, err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		o, err := current.GetOauthByProviderUserID(ctx, "google", id)
+		if err != nil || string(o.AccessToken) != "access-"+id || string(o.RefreshToken) != "refresh-"+id {
+			t.Errorf("rewrapped row %s = %+v, %v", id, o, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Reap runs fn once at start and then every interval until ctx is
+// cancelled. fn deletes whatever has expired as of now and reports how many
+// rows it removed; name only labels the log lines. Reapers keep tables from
+// growing, they are never what enforces expiry.
+func Reap(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context, now time.Time) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := fn(ctx, time.Now())
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.ErrorContext(ctx, "reaper failed", "reaper", name, "err", err)
+		case n > 0:
+			slog.InfoContext(ctx, "reaper deleted expired rows", "reaper", name, "rows", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -18,32 +18,38 @@ func NewSessionStore(db *sql.DB) *SessionStore {
 
 var _ domains.SessionRepo = (*SessionStore)(nil)
 
-func (r *SessionStore) CreateSession(ctx context.Context, userId int64, secretHash string) (int64, error) {
+func (r *SessionStore) CreateSession(ctx context.Context, userId int64, secretHash, userAgent, ip string) (int64, error) {
+	ctx, end := observe(ctx, "session", "CreateSession")
+	defer end()
 	var id int64
 
-	query := `INSERT INTO session (userId, secretHash, createdAt, lastVerifiedAt)
-              VALUES ($1, $2, NOW(), NOW()) RETURNING id`
-	err := r.DB.QueryRowContext(ctx, query, userId, string(secretHash)).Scan(&id)
+	query := `INSERT INTO session (userId, secretHash, createdAt, lastVerifiedAt, userAgent, ip)
+              VALUES ($1, $2, NOW(), NOW(), $3, $4) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, userId, string(secretHash), userAgent, ip).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, mapErr(err)
 	}
 	return id, nil
 }
 
 func (r *SessionStore) GetSessionById(ctx context.Context, id int64) (domains.Session, error) {
-	query := `SELECT id, userId, secretHash, createdAt, lastVerifiedAt FROM session WHERE id=$1`
+	ctx, end := observe(ctx, "session", "GetSessionById")
+	defer end()
+	query := `SELECT id, userId, secretHash, createdAt, lastVerifiedAt, userAgent, ip FROM session WHERE id=$1`
 	row := r.DB.QueryRowContext(ctx, query, id)
 	var s domains.Session
 	var secretStr string
-	if err := row.Scan(&s.Id, &s.UserId, &secretStr, &s.CreatedAt, &s.LastVerifiedAt); err != nil {
-		return domains.Session{}, err
+	if err := row.Scan(&s.Id, &s.UserId, &secretStr, &s.CreatedAt, &s.LastVerifiedAt, &s.UserAgent, &s.IP); err != nil {
+		return domains.Session{}, mapErr(err)
 	}
 	s.SecretHash = []byte(secretStr)
 	return s, nil
 }
 
 func (r *SessionStore) GetSessionsByUser(ctx context.Context, userId int64) ([]domains.Session, error) {
-	query := `SELECT id, userId, secretHash, createdAt, lastVerifiedAt FROM session WHERE userId=$1`
+	ctx, end := observe(ctx, "session", "GetSessionsByUser")
+	defer end()
+	query := `SELECT id, userId, secretHash, createdAt, lastVerifiedAt, userAgent, ip FROM session WHERE userId=$1 ORDER BY id`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, err
@@ -54,7 +60,7 @@ func (r *SessionStore) GetSessionsByUser(ctx context.Context, userId int64) ([]d
 	for rows.Next() {
 		var s domains.Session
 		var secretStr string
-		if err := rows.Scan(&s.Id, &s.UserId, &secretStr, &s.CreatedAt, &s.LastVerifiedAt); err != nil {
+		if err := rows.Scan(&s.Id, &s.UserId, &secretStr, &s.CreatedAt, &s.LastVerifiedAt, &s.UserAgent, &s.IP); err != nil {
 			return nil, err
 		}
 		s.SecretHash = []byte(secretStr)
@@ -67,13 +73,28 @@ func (r *SessionStore) GetSessionsByUser(ctx context.Context, userId int64) ([]d
 }
 
 func (r *SessionStore) DeleteSession(ctx context.Context, id int64) error {
+	ctx, end := observe(ctx, "session", "DeleteSession")
+	defer end()
 	query := `DELETE FROM session WHERE id=$1`
 	_, err := r.DB.ExecContext(ctx, query, id)
 	return err
 }
 
 func (r *SessionStore) UpdateLastVerified(ctx context.Context, id int64, t time.Time) error {
+	ctx, end := observe(ctx, "session", "UpdateLastVerified")
+	defer end()
 	query := `UPDATE session SET lastVerifiedAt=$1 WHERE id=$2`
 	_, err := r.DB.ExecContext(ctx, query, t, id)
 	return err
 }
+
+func (r *SessionStore) DeleteExpiredSessions(ctx context.Context, createdBefore, verifiedBefore time.Time) (int64, error) {
+	ctx, end := observe(ctx, "session", "DeleteExpiredSessions")
+	defer end()
+	query := `DELETE FROM session WHERE createdAt < $1 OR lastVerifiedAt < $2`
+	res, err := r.DB.ExecContext(ctx, query, createdBefore, verifiedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,774 @@
+// Package storetest is a contract suite for domains repository
+// implementations. The same checks run against the Postgres stores in
+// platform and the in-memory stores in memstore so both keep identical
+// semantics:
+//
+//	func TestMemstore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storetest.Stores {
+//			return storetest.Memstore()
+//		})
+//	}
+//
+//	func TestPostgres(t *testing.T) {
+//		db := openTestDB(t) // skips unless HEXTOK_TEST_DATABASE_URL is set
+//		storetest.Run(t, func(t *testing.T) storetest.Stores {
+//			return storetest.Postgres(t, db)
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/keyring"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+	"github.com/lib/pq"
+)
+
+// Stores bundles one implementation of every repository, all backed by the
+// same storage so cross-table rules can be checked.
+type Stores struct {
+	Users        domains.UserRepo
+	Hexes        domains.HexRepo
+	Likes        domains.LikeRepo
+	Follows      domains.FollowRepo
+	Sessions     domains.SessionRepo
+	Oauths       domains.OauthRepo
+	AuthCodes    domains.AuthCodeRepo
+	EmailTokens  domains.EmailTokenRepo
+	AccessTokens domains.AccessTokenRepo
+}
+
+// Memstore returns a fresh in-memory set of stores.
+func Memstore() Stores {
+	s := memstore.New()
+	return Stores{Users: s, Hexes: s, Likes: s, Follows: s, Sessions: s, Oauths: s, AuthCodes: s, EmailTokens: s, AccessTokens: s}
+}
+
+// Postgres empties every application table in db and returns the Postgres
+// stores. db must point at a disposable, fully migrated database.
+func Postgres(t *testing.T, db *sql.DB) Stores {
+	t.Helper()
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, `SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table name: %v", err)
+		}
+		tables = append(tables, pq.QuoteIdentifier(name))
+	}
+	rows.Close()
+	if len(tables) > 0 {
+		query := `TRUNCATE TABLE ` + strings.Join(tables, ", ") + ` RESTART IDENTITY CASCADE`
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+	}
+	return Stores{
+		Users:        platform.NewUserStore(db),
+		Hexes:        platform.NewHexStore(db),
+		Likes:        platform.NewLikeStore(db),
+		Follows:      platform.NewFollowStore(db),
+		Sessions:     platform.NewSessionStore(db),
+		Oauths:       platform.NewOauthStore(db, testKeyring(t)),
+		AuthCodes:    platform.NewAuthCodeStore(db),
+		EmailTokens:  platform.NewEmailTokenStore(db),
+		AccessTokens: platform.NewAccessTokenStore(db),
+	}
+}
+
+// testKeyring encrypts provider tokens in the Postgres stores so the
+// contract covers the encrypted round trip.
+func testKeyring(t *testing.T) *keyring.Keyring {
+	t.Helper()
+	k, err := keyring.New("test", map[string][]byte{"test": make([]byte, keyring.KeySize)})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return k
+}
+
+// Run executes the whole contract. open must return empty stores each time
+// it is called; it is invoked once per subtest.
+func Run(t *testing.T, open func(t *testing.T) Stores) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, open(t)) })
+	t.Run("Hexes", func(t *testing.T) { testHexes(t, open(t)) })
+	t.Run("HexAuthors", func(t *testing.T) { testHexAuthors(t, open(t)) })
+	t.Run("Likes", func(t *testing.T) { testLikes(t, open(t)) })
+	t.Run("Follows", func(t *testing.T) { testFollows(t, open(t)) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, open(t)) })
+	t.Run("Oauths", func(t *testing.T) { testOauths(t, open(t)) })
+	t.Run("AuthCodes", func(t *testing.T) { testAuthCodes(t, open(t)) })
+	t.Run("EmailTokens", func(t *testing.T) { testEmailTokens(t, open(t)) })
+	t.Run("AccessTokens", func(t *testing.T) { testAccessTokens(t, open(t)) })
+	t.Run("DeleteUserCascades", func(t *testing.T) { testDeleteUserCascades(t, open(t)) })
+}
+
+func testUsers(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+
+	u, err := s.Users.GetUserById(ctx, a)
+	if err != nil {
+		t.Fatalf("GetUserById: %v", err)
+	}
+	if u.UserName != "alice" || u.CreatedAt.IsZero() {
+		t.Errorf("GetUserById = %+v, want alice with createdAt", u)
+	}
+	all, err := s.Users.GetAllUser(ctx)
+	if err != nil {
+		t.Fatalf("GetAllUser: %v", err)
+	}
+	if got := userIds(all); !slices.Equal(got, []int64{a, b}) {
+		t.Errorf("GetAllUser ids = %v, want [%d %d] in id order", got, a, b)
+	}
+	if _, err := s.Users.GetUserById(ctx, b+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetUserById(missing) err = %v, want ErrNotFound", err)
+	}
+	if err := s.Users.DeleteUser(ctx, b+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("DeleteUser(missing) err = %v, want ErrNotFound", err)
+	}
+}
+
+func testHexes(t *testing.T, s Stores) {
+	ctx := context.Background()
+	first := mustHex(t, s, "#112233")
+	second := mustHex(t, s, "#445566")
+	third := mustHex(t, s, "#778899")
+
+	h, err := s.Hexes.GetHexById(ctx, first)
+	if err != nil || h.HexValue != "#112233" || h.CreatedBy != 0 || h.CreatedAt.IsZero() {
+		t.Errorf("GetHexById = %+v, %v, want #112233 without author and with createdAt", h, err)
+	}
+	if _, err := s.Hexes.CreateHex(ctx, "#112233", 0); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("CreateHex(duplicate) err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.Hexes.GetHexById(ctx, third+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetHexById(missing) err = %v, want ErrNotFound", err)
+	}
+	if h, err := s.Hexes.GetHexByValue(ctx, "#445566"); err != nil || h.Id != second {
+		t.Errorf("GetHexByValue = %+v, %v, want id %d", h, err, second)
+	}
+	if _, err := s.Hexes.GetHexByValue(ctx, "#000000"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetHexByValue(missing) err = %v, want ErrNotFound", err)
+	}
+
+	all, err := s.Hexes.ListAllHexColors(ctx)
+	if err != nil {
+		t.Fatalf("ListAllHexColors: %v", err)
+	}
+	if got := sorted(hexIds(all)); !slices.Equal(got, []int64{first, second, third}) {
+		t.Errorf("ListAllHexColors ids = %v", got)
+	}
+
+	labs, err := s.Hexes.ListHexLabs(ctx)
+	if err != nil {
+		t.Fatalf("ListHexLabs: %v", err)
+	}
+	if len(labs) != 3 {
+		t.Errorf("ListHexLabs returned %d points, want 3", len(labs))
+	}
+	for _, p := range labs {
+		c, _ := domains.ParseColor(p.HexValue)
+		if domains.DeltaE76(p.Lab, c.Lab()) > 1e-6 {
+			t.Errorf("ListHexLabs lab for %s = %+v, want %+v", p.HexValue, p.Lab, c.Lab())
+		}
+	}
+
+	page, err := s.Hexes.ListHexFeed(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("ListHexFeed: %v", err)
+	}
+	if got := hexIds(page); !slices.Equal(got, []int64{third, second}) {
+		t.Errorf("ListHexFeed first page = %v, want [%d %d]", got, third, second)
+	}
+	page, err = s.Hexes.ListHexFeed(ctx, second, 2)
+	if err != nil {
+		t.Fatalf("ListHexFeed: %v", err)
+	}
+	if got := hexIds(page); !slices.Equal(got, []int64{first}) {
+		t.Errorf("ListHexFeed second page = %v, want [%d]", got, first)
+	}
+}
+
+func testHexAuthors(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+
+	var mine []int64
+	for _, v := range []string{"#010101", "#020202", "#030303"} {
+		id, err := s.Hexes.CreateHex(ctx, v, a)
+		if err != nil {
+			t.Fatalf("CreateHex(%q, %d): %v", v, a, err)
+		}
+		mine = append(mine, id)
+	}
+	theirs, err := s.Hexes.CreateHex(ctx, "#040404", b)
+	if err != nil {
+		t.Fatalf("CreateHex: %v", err)
+	}
+	if _, err := s.Hexes.CreateHex(ctx, "#050505", b+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("CreateHex(unknown author) err = %v, want ErrNotFound", err)
+	}
+
+	if h, err := s.Hexes.GetHexById(ctx, theirs); err != nil || h.CreatedBy != b {
+		t.Errorf("GetHexById = %+v, %v, want createdBy %d", h, err, b)
+	}
+	page, err := s.Hexes.ListHexesByCreator(ctx, a, 0, 2)
+	if err != nil {
+		t.Fatalf("ListHexesByCreator: %v", err)
+	}
+	if got := hexIds(page); !slices.Equal(got, []int64{mine[2], mine[1]}) {
+		t.Errorf("ListHexesByCreator first page = %v, want [%d %d]", got, mine[2], mine[1])
+	}
+	page, err = s.Hexes.ListHexesByCreator(ctx, a, mine[1], 2)
+	if err != nil {
+		t.Fatalf("ListHexesByCreator: %v", err)
+	}
+	if got := hexIds(page); !slices.Equal(got, []int64{mine[0]}) {
+		t.Errorf("ListHexesByCreator second page = %v, want [%d]", got, mine[0])
+	}
+
+	if err := s.Users.DeleteUser(ctx, b); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if h, err := s.Hexes.GetHexById(ctx, theirs); err != nil || h.CreatedBy != 0 {
+		t.Errorf("GetHexById after author deletion = %+v, %v, want kept with no author", h, err)
+	}
+	if page, _ := s.Hexes.ListHexesByCreator(ctx, b, 0, 10); len(page) != 0 {
+		t.Errorf("ListHexesByCreator(deleted) = %v, want empty", hexIds(page))
+	}
+}
+
+func testLikes(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+	h1 := mustHex(t, s, "#000001")
+	h2 := mustHex(t, s, "#000002")
+
+	for _, like := range [][2]int64{{a, h1}, {b, h1}, {a, h2}} {
+		if err := s.Likes.AddLike(ctx, like[0], like[1]); err != nil {
+			t.Fatalf("AddLike(%d, %d): %v", like[0], like[1], err)
+		}
+	}
+	if err := s.Likes.AddLike(ctx, a, h1); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("AddLike(duplicate) err = %v, want ErrAlreadyExists", err)
+	}
+	if err := s.Likes.AddLike(ctx, a, h2+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("AddLike(missing hex) err = %v, want ErrNotFound", err)
+	}
+
+	counts, err := s.Likes.GetLikeCountsForHexes(ctx, []int64{h1, h2})
+	if err != nil {
+		t.Fatalf("GetLikeCountsForHexes: %v", err)
+	}
+	if counts[h1] != 2 || counts[h2] != 1 {
+		t.Errorf("GetLikeCountsForHexes = %v", counts)
+	}
+	liked, err := s.Likes.GetLikedHexIds(ctx, b, []int64{h1, h2})
+	if err != nil {
+		t.Fatalf("GetLikedHexIds: %v", err)
+	}
+	if !liked[h1] || liked[h2] {
+		t.Errorf("GetLikedHexIds = %v, want only %d", liked, h1)
+	}
+	hexes, err := s.Likes.GetLikedHexesByUser(ctx, a)
+	if err != nil {
+		t.Fatalf("GetLikedHexesByUser: %v", err)
+	}
+	if got := sorted(hexIds(hexes)); !slices.Equal(got, []int64{h1, h2}) {
+		t.Errorf("GetLikedHexesByUser = %v", got)
+	}
+	likes, err := s.Likes.GetLikesForHex(ctx, h1)
+	if err != nil {
+		t.Fatalf("GetLikesForHex: %v", err)
+	}
+	if len(likes) != 2 {
+		t.Errorf("GetLikesForHex returned %d likes, want 2", len(likes))
+	}
+
+	if err := s.Likes.RemoveLike(ctx, a, h1); err != nil {
+		t.Fatalf("RemoveLike: %v", err)
+	}
+	if err := s.Likes.RemoveLike(ctx, a, h1); err != nil {
+		t.Errorf("RemoveLike(again) err = %v, want nil", err)
+	}
+	counts, _ = s.Likes.GetLikeCountsForHexes(ctx, []int64{h1})
+	if counts[h1] != 1 {
+		t.Errorf("count after RemoveLike = %d, want 1", counts[h1])
+	}
+}
+
+func testFollows(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+	c := mustUser(t, s, "carol")
+
+	for _, f := range [][2]int64{{a, b}, {c, b}, {b, a}} {
+		if err := s.Follows.FollowUser(ctx, f[0], f[1]); err != nil {
+			t.Fatalf("FollowUser(%d, %d): %v", f[0], f[1], err)
+		}
+	}
+	if err := s.Follows.FollowUser(ctx, a, b); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("FollowUser(duplicate) err = %v, want ErrAlreadyExists", err)
+	}
+	if err := s.Follows.FollowUser(ctx, a, c+1000); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("FollowUser(missing) err = %v, want ErrNotFound", err)
+	}
+
+	followers, err := s.Follows.GetFollowers(ctx, b)
+	if err != nil {
+		t.Fatalf("GetFollowers: %v", err)
+	}
+	if got := sorted(userIds(followers)); !slices.Equal(got, []int64{a, c}) {
+		t.Errorf("GetFollowers = %v, want [%d %d]", got, a, c)
+	}
+	following, err := s.Follows.GetFollowing(ctx, b)
+	if err != nil {
+		t.Fatalf("GetFollowing: %v", err)
+	}
+	if got := userIds(following); !slices.Equal(got, []int64{a}) {
+		t.Errorf("GetFollowing = %v, want [%d]", got, a)
+	}
+
+	if err := s.Follows.UnfollowUser(ctx, a, b); err != nil {
+		t.Fatalf("UnfollowUser: %v", err)
+	}
+	if err := s.Follows.UnfollowUser(ctx, a, b); err != nil {
+		t.Errorf("UnfollowUser(again) err = %v, want nil", err)
+	}
+	followers, _ = s.Follows.GetFollowers(ctx, b)
+	if got := userIds(followers); !slices.Equal(got, []int64{c}) {
+		t.Errorf("GetFollowers after unfollow = %v, want [%d]", got, c)
+	}
+}
+
+func testSessions(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+
+	id1, err := s.Sessions.CreateSession(ctx, a, "hash-1", "test-agent/1.0", "203.0.113.7")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	id2, err := s.Sessions.CreateSession(ctx, a, "hash-2", "", "")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := s.Sessions.CreateSession(ctx, a+1000, "hash-3", "", ""); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("CreateSession(missing user) err = %v, want ErrNotFound", err)
+	}
+
+	sess, err := s.Sessions.GetSessionById(ctx, id1)
+	if err != nil {
+		t.Fatalf("GetSessionById: %v", err)
+	}
+	if sess.UserId != a || string(sess.SecretHash) != "hash-1" || sess.UserAgent != "test-agent/1.0" || sess.IP != "203.0.113.7" {
+		t.Errorf("GetSessionById = %+v", sess)
+	}
+
+	bumped := sess.LastVerifiedAt.Add(time.Hour).UTC().Truncate(time.Microsecond)
+	if err := s.Sessions.UpdateLastVerified(ctx, id1, bumped); err != nil {
+		t.Fatalf("UpdateLastVerified: %v", err)
+	}
+	sess, _ = s.Sessions.GetSessionById(ctx, id1)
+	if !sess.LastVerifiedAt.Equal(bumped) {
+		t.Errorf("LastVerifiedAt = %v, want %v", sess.LastVerifiedAt, bumped)
+	}
+
+	all, err := s.Sessions.GetSessionsByUser(ctx, a)
+	if err != nil {
+		t.Fatalf("GetSessionsByUser: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("GetSessionsByUser returned %d sessions, want 2", len(all))
+	}
+
+	if err := s.Sessions.DeleteSession(ctx, id2); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := s.Sessions.GetSessionById(ctx, id2); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetSessionById(deleted) err = %v, want ErrNotFound", err)
+	}
+
+	testDeleteExpiredSessions(t, s, a)
+}
+
+// testDeleteExpiredSessions derives its cutoffs from the stored timestamps so
+// it does not depend on the database clock.
+func testDeleteExpiredSessions(t *testing.T, s Stores, userId int64) {
+	ctx := context.Background()
+	idle, err := s.Sessions.CreateSession(ctx, userId, "idle", "", "")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	active, err := s.Sessions.CreateSession(ctx, userId, "active", "", "")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	sess, err := s.Sessions.GetSessionById(ctx, active)
+	if err != nil {
+		t.Fatalf("GetSessionById: %v", err)
+	}
+	old := sess.LastVerifiedAt.Add(-48 * time.Hour).UTC().Truncate(time.Microsecond)
+	if err := s.Sessions.UpdateLastVerified(ctx, idle, old); err != nil {
+		t.Fatalf("UpdateLastVerified: %v", err)
+	}
+
+	longAgo := sess.CreatedAt.Add(-time.Hour)
+	n, err := s.Sessions.DeleteExpiredSessions(ctx, longAgo, old.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteExpiredSessions(idle cutoff) deleted %d, want 1", n)
+	}
+	if _, err := s.Sessions.GetSessionById(ctx, idle); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("idle session survived: %v", err)
+	}
+	if _, err := s.Sessions.GetSessionById(ctx, active); err != nil {
+		t.Errorf("active session was deleted: %v", err)
+	}
+
+	n, err = s.Sessions.DeleteExpiredSessions(ctx, sess.CreatedAt.Add(time.Hour), longAgo)
+	if err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	if n < 1 {
+		t.Errorf("DeleteExpiredSessions(absolute cutoff) deleted %d, want at least 1", n)
+	}
+	if _, err := s.Sessions.GetSessionById(ctx, active); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("session past its absolute lifetime survived: %v", err)
+	}
+}
+
+func testOauths(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+
+	id, err := s.Oauths.CreateOauth(ctx, a, "github", "gh-1", "access", "refresh")
+	if err != nil {
+		t.Fatalf("CreateOauth: %v", err)
+	}
+	if _, err := s.Oauths.CreateOauth(ctx, a, "github", "gh-1", "access", ""); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("CreateOauth(duplicate) err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.Oauths.CreateOauth(ctx, a+1000, "github", "gh-2", "", ""); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("CreateOauth(missing user) err = %v, want ErrNotFound", err)
+	}
+
+	o, err := s.Oauths.GetOauthByProviderUserID(ctx, "github", "gh-1")
+	if err != nil {
+		t.Fatalf("GetOauthByProviderUserID: %v", err)
+	}
+	if o.Id != id || o.UserId != a || o.AccessToken != "access" || o.RefreshToken != "refresh" {
+		t.Errorf("GetOauthByProviderUserID = %+v", o)
+	}
+	if _, err := s.Oauths.GetOauthByProviderUserID(ctx, "google", "gh-1"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetOauthByProviderUserID(other provider) err = %v, want ErrNotFound", err)
+	}
+	// provider user ids only have to be unique within their provider
+	other, err := s.Oauths.CreateOauth(ctx, a, "google", "gh-1", "", "")
+	if err != nil {
+		t.Fatalf("CreateOauth(same id, other provider): %v", err)
+	}
+
+	all, err := s.Oauths.GetOauthsByUser(ctx, a)
+	if err != nil {
+		t.Fatalf("GetOauthsByUser: %v", err)
+	}
+	if len(all) != 2 || all[0].Id != id || all[1].Id != other {
+		t.Errorf("GetOauthsByUser = %+v, want ids [%d %d]", all, id, other)
+	}
+
+	b := mustUser(t, s, "bob")
+	if err := s.Oauths.DeleteOauth(ctx, b, id); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("DeleteOauth(other user's identity) err = %v, want ErrNotFound", err)
+	}
+	if err := s.Oauths.DeleteOauth(ctx, a, other); err != nil {
+		t.Fatalf("DeleteOauth: %v", err)
+	}
+	if err := s.Oauths.DeleteOauth(ctx, a, other); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("DeleteOauth(already deleted) err = %v, want ErrNotFound", err)
+	}
+	if err := s.Oauths.DeleteOauth(ctx, a, id); !errors.Is(err, domains.ErrLastIdentity) {
+		t.Errorf("DeleteOauth(last identity) err = %v, want ErrLastIdentity", err)
+	}
+	if all, _ := s.Oauths.GetOauthsByUser(ctx, a); len(all) != 1 || all[0].Id != id {
+		t.Errorf("GetOauthsByUser after unlink = %+v, want only %d", all, id)
+	}
+}
+
+func testAuthCodes(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	if _, err := s.AuthCodes.CreateAuthCode(ctx, a, "live", "state-1", "challenge", now.Add(5*time.Minute)); err != nil {
+		t.Fatalf("CreateAuthCode: %v", err)
+	}
+	if _, err := s.AuthCodes.CreateAuthCode(ctx, a, "stale", "state-2", "", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateAuthCode: %v", err)
+	}
+	if _, err := s.AuthCodes.CreateAuthCode(ctx, a, "live", "state-3", "", now.Add(5*time.Minute)); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("CreateAuthCode(duplicate hash) err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.AuthCodes.CreateAuthCode(ctx, a+1000, "orphan", "state", "", now.Add(time.Minute)); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("CreateAuthCode(missing user) err = %v, want ErrNotFound", err)
+	}
+
+	c, err := s.AuthCodes.RedeemAuthCode(ctx, "live", now)
+	if err != nil {
+		t.Fatalf("RedeemAuthCode: %v", err)
+	}
+	if c.UserId != a || c.State != "state-1" || c.CodeChallenge != "challenge" || !c.UsedAt.Equal(now) {
+		t.Errorf("RedeemAuthCode = %+v, want alice's code with its challenge used at %v", c, now)
+	}
+	if _, err := s.AuthCodes.RedeemAuthCode(ctx, "live", now); !errors.Is(err, domains.ErrAuthCodeUsed) {
+		t.Errorf("RedeemAuthCode(replay) err = %v, want ErrAuthCodeUsed", err)
+	}
+	if _, err := s.AuthCodes.RedeemAuthCode(ctx, "stale", now); !errors.Is(err, domains.ErrAuthCodeExpired) {
+		t.Errorf("RedeemAuthCode(expired) err = %v, want ErrAuthCodeExpired", err)
+	}
+	if _, err := s.AuthCodes.RedeemAuthCode(ctx, "unknown", now); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("RedeemAuthCode(unknown) err = %v, want ErrNotFound", err)
+	}
+
+	n, err := s.AuthCodes.DeleteExpiredAuthCodes(ctx, now)
+	if err != nil {
+		t.Fatalf("DeleteExpiredAuthCodes: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteExpiredAuthCodes deleted %d, want 1", n)
+	}
+	if _, err := s.AuthCodes.RedeemAuthCode(ctx, "stale", now); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("RedeemAuthCode(reaped) err = %v, want ErrNotFound", err)
+	}
+}
+
+func testEmailTokens(t *testing.T, s Stores) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	live := domains.EmailToken{
+		Email:         "alice@example.com",
+		TokenHash:     "live",
+		Mobile:        true,
+		State:         "state",
+		CodeChallenge: "challenge",
+		ExpiresAt:     now.Add(15 * time.Minute),
+	}
+	if _, err := s.EmailTokens.CreateEmailToken(ctx, live); err != nil {
+		t.Fatalf("CreateEmailToken: %v", err)
+	}
+	if _, err := s.EmailTokens.CreateEmailToken(ctx, domains.EmailToken{Email: "bob@example.com", TokenHash: "stale", ExpiresAt: now.Add(-time.Minute)}); err != nil {
+		t.Fatalf("CreateEmailToken: %v", err)
+	}
+	if _, err := s.EmailTokens.CreateEmailToken(ctx, live); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("CreateEmailToken(duplicate hash) err = %v, want ErrAlreadyExists", err)
+	}
+
+	got, err := s.EmailTokens.RedeemEmailToken(ctx, "live", now)
+	if err != nil {
+		t.Fatalf("RedeemEmailToken: %v", err)
+	}
+	if got.Email != live.Email || !got.Mobile || got.State != live.State || got.CodeChallenge != live.CodeChallenge || !got.UsedAt.Equal(now) {
+		t.Errorf("RedeemEmailToken = %+v, want %+v used at %v", got, live, now)
+	}
+	if _, err := s.EmailTokens.RedeemEmailToken(ctx, "live", now); !errors.Is(err, domains.ErrEmailTokenUsed) {
+		t.Errorf("RedeemEmailToken(replay) err = %v, want ErrEmailTokenUsed", err)
+	}
+	if _, err := s.EmailTokens.RedeemEmailToken(ctx, "stale", now); !errors.Is(err, domains.ErrEmailTokenExpired) {
+		t.Errorf("RedeemEmailToken(expired) err = %v, want ErrEmailTokenExpired", err)
+	}
+	if _, err := s.EmailTokens.RedeemEmailToken(ctx, "unknown", now); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("RedeemEmailToken(unknown) err = %v, want ErrNotFound", err)
+	}
+
+	n, err := s.EmailTokens.DeleteExpiredEmailTokens(ctx, now)
+	if err != nil {
+		t.Fatalf("DeleteExpiredEmailTokens: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteExpiredEmailTokens deleted %d, want 1", n)
+	}
+}
+
+func testAccessTokens(t *testing.T, s Stores) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+
+	ci := domains.AccessToken{
+		UserId:    a,
+		Name:      "ci",
+		TokenHash: "hash-ci",
+		Scopes:    []string{domains.ScopeReadHexes, domains.ScopeWriteLikes},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	ciId, err := s.AccessTokens.CreateAccessToken(ctx, ci)
+	if err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+	bare, err := s.AccessTokens.CreateAccessToken(ctx, domains.AccessToken{UserId: a, Name: "bare", TokenHash: "hash-bare"})
+	if err != nil {
+		t.Fatalf("CreateAccessToken(no scopes): %v", err)
+	}
+	if _, err := s.AccessTokens.CreateAccessToken(ctx, domains.AccessToken{UserId: b, Name: "dup", TokenHash: "hash-ci"}); !errors.Is(err, domains.ErrAlreadyExists) {
+		t.Errorf("CreateAccessToken(duplicate hash) err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.AccessTokens.CreateAccessToken(ctx, domains.AccessToken{UserId: b + 1000, Name: "x", TokenHash: "hash-x"}); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("CreateAccessToken(missing user) err = %v, want ErrNotFound", err)
+	}
+
+	got, err := s.AccessTokens.GetAccessTokenByHash(ctx, "hash-ci")
+	if err != nil {
+		t.Fatalf("GetAccessTokenByHash: %v", err)
+	}
+	if got.Id != ciId || got.UserId != a || got.Name != "ci" || !slices.Equal(got.Scopes, ci.Scopes) ||
+		!got.ExpiresAt.Equal(ci.ExpiresAt) || !got.LastUsedAt.IsZero() || got.CreatedAt.IsZero() {
+		t.Errorf("GetAccessTokenByHash = %+v, want %+v", got, ci)
+	}
+	if got, err := s.AccessTokens.GetAccessTokenByHash(ctx, "hash-bare"); err != nil || len(got.Scopes) != 0 || !got.ExpiresAt.IsZero() {
+		t.Errorf("GetAccessTokenByHash(bare) = %+v, %v, want no scopes and no expiry", got, err)
+	}
+	if _, err := s.AccessTokens.GetAccessTokenByHash(ctx, "missing"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetAccessTokenByHash(missing) err = %v, want ErrNotFound", err)
+	}
+
+	if err := s.AccessTokens.UpdateAccessTokenLastUsed(ctx, ciId, now); err != nil {
+		t.Fatalf("UpdateAccessTokenLastUsed: %v", err)
+	}
+	list, err := s.AccessTokens.GetAccessTokensByUser(ctx, a)
+	if err != nil {
+		t.Fatalf("GetAccessTokensByUser: %v", err)
+	}
+	if len(list) != 2 || list[0].Id != ciId || list[1].Id != bare || !list[0].LastUsedAt.Equal(now) {
+		t.Errorf("GetAccessTokensByUser = %+v, want [%d %d] with lastUsedAt on the first", list, ciId, bare)
+	}
+	if list, _ := s.AccessTokens.GetAccessTokensByUser(ctx, b); len(list) != 0 {
+		t.Errorf("GetAccessTokensByUser(other user) = %+v, want none", list)
+	}
+
+	if err := s.AccessTokens.DeleteAccessToken(ctx, b, ciId); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("DeleteAccessToken(other user) err = %v, want ErrNotFound", err)
+	}
+	if err := s.AccessTokens.DeleteAccessToken(ctx, a, ciId); err != nil {
+		t.Fatalf("DeleteAccessToken: %v", err)
+	}
+	if _, err := s.AccessTokens.GetAccessTokenByHash(ctx, "hash-ci"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("GetAccessTokenByHash after delete err = %v, want ErrNotFound", err)
+	}
+	if err := s.AccessTokens.DeleteAccessToken(ctx, a, ciId); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("DeleteAccessToken(twice) err = %v, want ErrNotFound", err)
+	}
+}
+
+func testDeleteUserCascades(t *testing.T, s Stores) {
+	ctx := context.Background()
+	a := mustUser(t, s, "alice")
+	b := mustUser(t, s, "bob")
+	h := mustHex(t, s, "#abcdef")
+
+	sid, err := s.Sessions.CreateSession(ctx, a, "hash", "", "")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := s.Oauths.CreateOauth(ctx, a, "github", "gh-a", "", ""); err != nil {
+		t.Fatalf("CreateOauth: %v", err)
+	}
+	if err := s.Likes.AddLike(ctx, a, h); err != nil {
+		t.Fatalf("AddLike: %v", err)
+	}
+	if err := s.Follows.FollowUser(ctx, b, a); err != nil {
+		t.Fatalf("FollowUser: %v", err)
+	}
+	if _, err := s.AuthCodes.CreateAuthCode(ctx, a, "code", "state", "", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("CreateAuthCode: %v", err)
+	}
+	if _, err := s.AccessTokens.CreateAccessToken(ctx, domains.AccessToken{UserId: a, Name: "ci", TokenHash: "pat"}); err != nil {
+		t.Fatalf("CreateAccessToken: %v", err)
+	}
+
+	if err := s.Users.DeleteUser(ctx, a); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.Sessions.GetSessionById(ctx, sid); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("session survived user deletion: %v", err)
+	}
+	if _, err := s.Oauths.GetOauthByProviderUserID(ctx, "github", "gh-a"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("oauth row survived user deletion: %v", err)
+	}
+	if counts, _ := s.Likes.GetLikeCountsForHexes(ctx, []int64{h}); counts[h] != 0 {
+		t.Errorf("like survived user deletion: count %d", counts[h])
+	}
+	if following, _ := s.Follows.GetFollowing(ctx, b); len(following) != 0 {
+		t.Errorf("follow survived user deletion: %v", userIds(following))
+	}
+	if _, err := s.AuthCodes.RedeemAuthCode(ctx, "code", time.Now()); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("auth code survived user deletion: %v", err)
+	}
+	if _, err := s.AccessTokens.GetAccessTokenByHash(ctx, "pat"); !errors.Is(err, domains.ErrNotFound) {
+		t.Errorf("access token survived user deletion: %v", err)
+	}
+	if _, err := s.Hexes.GetHexById(ctx, h); err != nil {
+		t.Errorf("hex should not be deleted with the user: %v", err)
+	}
+}
+
+func mustUser(t *testing.T, s Stores, name string) int64 {
+	t.Helper()
+	id, err := s.Users.CreateUser(context.Background(), name)
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", name, err)
+	}
+	return id
+}
+
+func mustHex(t *testing.T, s Stores, value string) int64 {
+	t.Helper()
+	id, err := s.Hexes.CreateHex(context.Background(), value, 0)
+	if err != nil {
+		t.Fatalf("CreateHex(%q): %v", value, err)
+	}
+	return id
+}
+
+func userIds(us []domains.User) []int64 {
+	ids := make([]int64, 0, len(us))
+	for _, u := range us {
+		ids = append(ids, u.Id)
+	}
+	return ids
+}
+
+func hexIds(hs []domains.Hex) []int64 {
+	ids := make([]int64, 0, len(hs))
+	for _, h := range hs {
+		ids = append(ids, h.Id)
+	}
+	return ids
+}
+
+func sorted(ids []int64) []int64 {
+	slices.Sort(ids)
+	return ids
+}
@@ -16,6 +16,8 @@ func NewUserStore(db *sql.DB) *UserStore {
 }
 
 func (r *UserStore) CreateUser(ctx context.Context, username string) (int64, error) {
+	ctx, end := observe(ctx, "user", "CreateUser")
+	defer end()
 	var id int64
 	query := `INSERT INTO users (userName, createdAt, updatedAt) VALUES ($1, NOW(), NOW()) RETURNING id`
 	err := r.DB.QueryRowContext(ctx, query, username).Scan(&id)
@@ -26,6 +28,8 @@ func (r *UserStore) CreateUser(ctx context.Context, username string) (int64, err
 }
 
 func (r *UserStore) GetAllUser(ctx context.Context) ([]domains.User, error) {
+	ctx, end := observe(ctx, "user", "GetAllUser")
+	defer end()
 	query := `SELECT id, userName, createdAt, updatedAt FROM users ORDER BY id`
 	rows, err := r.DB.QueryContext(ctx, query)
 	if err != nil {
@@ -47,14 +51,36 @@ func (r *UserStore) GetAllUser(ctx context.Context) ([]domains.User, error) {
 	return users, nil
 }
 func (r *UserStore) GetUserById(ctx context.Context, userId int64) (domains.User, error) {
+	ctx, end := observe(ctx, "user", "GetUserById")
+	defer end()
 	query := `SELECT id, userName, createdAt, updatedAt FROM users WHERE id=$1`
 	row := r.DB.QueryRowContext(ctx, query, userId)
 	var user domains.User
 	if err := row.Scan(&user.Id, &user.UserName, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return domains.User{}, err
+		return domains.User{}, mapErr(err)
 	}
 
 	return user, nil
 }
 
+// DeleteUser removes the user; sessions, oauth rows, likes and follows are
+// removed by the ON DELETE CASCADE foreign keys.
+func (r *UserStore) DeleteUser(ctx context.Context, userId int64) error {
+	ctx, end := observe(ctx, "user", "DeleteUser")
+	defer end()
+	query := `DELETE FROM users WHERE id=$1`
+	res, err := r.DB.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domains.ErrNotFound
+	}
+	return nil
+}
+
 var _ domains.UserRepo = (*UserStore)(nil)
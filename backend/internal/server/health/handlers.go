@@ -0,0 +1,116 @@
+// Package health serves the endpoints orchestrators and operators poll:
+// liveness, readiness and the build the server is running.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+// Pinger is satisfied by *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Migrations is satisfied by *migrations.Migrator.
+type Migrations interface {
+	Pending(ctx context.Context) (int, error)
+}
+
+type Handler struct {
+	db         Pinger
+	migrations Migrations
+	// timeout bounds each readiness check.
+	timeout  time.Duration
+	started  time.Time
+	draining atomic.Bool
+}
+
+func NewHandler(db Pinger, m Migrations, timeout time.Duration) *Handler {
+	return &Handler{db: db, migrations: m, timeout: timeout, started: time.Now()}
+}
+
+// Drain makes /readyz fail from now on, so load balancers stop sending new
+// requests while the server shuts down.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// livenessHandler only shows the process is serving requests. It checks no
+// dependencies, so a database outage does not get the server restarted.
+func (h *Handler) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, schema.OkResponse{Message: "ok"})
+}
+
+// readinessHandler reports whether the server should receive traffic: the
+// database answers, its schema is current and the server is not draining.
+// Failures are logged in full but reported with a short reason, since the
+// endpoint is public.
+func (h *Handler) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+	log := logging.FromContext(ctx)
+
+	checks := map[string]string{"database": "ok", "migrations": "ok", "draining": "ok"}
+	ready := true
+	if err := h.db.PingContext(ctx); err != nil {
+		log.Warn("health: database ping failed", "err", err)
+		checks["database"] = "unreachable"
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if n, err := h.migrations.Pending(ctx); err != nil {
+		log.Warn("health: checking migrations failed", "err", err)
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if n > 0 {
+		checks["migrations"] = strconv.Itoa(n) + " pending"
+		ready = false
+	}
+	if h.draining.Load() {
+		checks["draining"] = "shutting down"
+		ready = false
+	}
+
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, schema.ReadinessResponse{Status: "not ready", Checks: checks})
+		return
+	}
+	writeJSON(w, http.StatusOK, schema.ReadinessResponse{Status: "ready", Checks: checks})
+}
+
+// versionHandler reports the Go build info and VCS revision embedded by
+// go build, and when the process started.
+func (h *Handler) versionHandler(w http.ResponseWriter, r *http.Request) {
+	res := schema.VersionResponse{Version: "unknown", StartedAt: h.started}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		res.Version = info.Main.Version
+		res.GoVersion = info.GoVersion
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				res.Revision = s.Value
+			case "vcs.time":
+				res.RevisionTime, _ = time.Parse(time.RFC3339, s.Value)
+			case "vcs.modified":
+				res.Modified = s.Value == "true"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	// probes must see the current state, not a cached one
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
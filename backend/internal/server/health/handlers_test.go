@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+type fakeDB struct{ err error }
+
+func (f fakeDB) PingContext(ctx context.Context) error { return f.err }
+
+type fakeMigrations struct {
+	pending int
+	err     error
+}
+
+func (f fakeMigrations) Pending(ctx context.Context) (int, error) { return f.pending, f.err }
+
+func get(t *testing.T, h *Handler, target string) *http.Response {
+	t.Helper()
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w.Result()
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     fakeDB
+		m      fakeMigrations
+		drain  bool
+		status int
+		checks map[string]string
+	}{
+		{"ready", fakeDB{}, fakeMigrations{}, false, 200,
+			map[string]string{"database": "ok", "migrations": "ok", "draining": "ok"}},
+		{"database down", fakeDB{errors.New("dial tcp: connection refused")}, fakeMigrations{}, false, 503,
+			map[string]string{"database": "unreachable", "migrations": "unknown", "draining": "ok"}},
+		{"pending migrations", fakeDB{}, fakeMigrations{pending: 2}, false, 503,
+			map[string]string{"database": "ok", "migrations": "2 pending", "draining": "ok"}},
+		{"migrations unknown", fakeDB{}, fakeMigrations{err: errors.New("relation does not exist")}, false, 503,
+			map[string]string{"database": "ok", "migrations": "unknown", "draining": "ok"}},
+		{"draining", fakeDB{}, fakeMigrations{}, true, 503,
+			map[string]string{"database": "ok", "migrations": "ok", "draining": "shutting down"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.db, tt.m, time.Second)
+			if tt.drain {
+				h.Drain()
+			}
+			resp := get(t, h, "/readyz")
+			var got schema.ReadinessResponse
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if want := map[bool]string{true: "ready", false: "not ready"}[tt.status == 200]; got.Status != want {
+				t.Errorf("status field = %q, want %q", got.Status, want)
+			}
+			for k, v := range tt.checks {
+				if got.Checks[k] != v {
+					t.Errorf("checks[%s] = %q, want %q", k, got.Checks[k], v)
+				}
+			}
+			if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", cc)
+			}
+		})
+	}
+}
+
+func TestReadinessDoesNotLeakErrors(t *testing.T) {
+	h := NewHandler(fakeDB{errors.New("dial tcp 10.0.0.5:5432: password authentication failed")}, fakeMigrations{}, time.Second)
+	b, _ := io.ReadAll(get(t, h, "/readyz").Body)
+	if s := string(b); strings.Contains(s, "10.0.0.5") || strings.Contains(s, "password") {
+		t.Errorf("body = %s, want only a short reason", s)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	// liveness checks no dependencies
+	h := NewHandler(fakeDB{errors.New("down")}, fakeMigrations{pending: 1}, time.Second)
+	h.Drain()
+	if resp := get(t, h, "/healthz"); resp.StatusCode != 200 {
+		t.Errorf("/healthz = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	h := NewHandler(fakeDB{}, fakeMigrations{}, time.Second)
+	resp := get(t, h, "/version")
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != 200 || ct != "application/json" {
+		t.Fatalf("/version = %d %s, want 200 JSON", resp.StatusCode, ct)
+	}
+	var v schema.VersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Version == "" || v.GoVersion == "" || !v.StartedAt.Equal(h.started) {
+		t.Errorf("version = %+v", v)
+	}
+}
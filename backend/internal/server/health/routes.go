@@ -0,0 +1,9 @@
+package health
+
+import "net/http"
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.livenessHandler)
+	mux.HandleFunc("GET /readyz", h.readinessHandler)
+	mux.HandleFunc("GET /version", h.versionHandler)
+}
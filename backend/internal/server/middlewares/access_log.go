@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"maps"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// requestIDPattern limits which incoming request ids are kept, so a client
+// cannot inject arbitrary text into the logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewAccessLogMiddleware gives every request an id and a logger carrying
+// it, stored in the context for handlers to use, and writes one line per
+// request once it completes. A well-formed X-Request-Id from the client is
+// kept, otherwise one is generated; either way it is echoed in the
+// response.
+//
+// The route, user and anything else recorded with logging.With further in
+// are added to the line. The query string is left out since it can carry
+// login codes. When the request is traced, the logger also carries the
+// trace id, so it must run inside the tracing handler.
+func NewAccessLogMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(requestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, id)
+
+			ctx, info := logging.WithRequestInfo(r.Context())
+			l := logger.With("request_id", id)
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				l = l.With("trace_id", sc.TraceID().String())
+			}
+			ctx = logging.WithLogger(ctx, l)
+			rec := &statusRecorder{ResponseWriter: w}
+			handler.ServeHTTP(rec, r.WithContext(ctx))
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			args := []any{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", rec.bytes,
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"ip", ClientIP(r),
+				"user_agent", r.UserAgent(),
+			}
+			l.Log(r.Context(), level, "request", append(args, info.Attrs()...)...)
+		})
+	}
+}
+
+// RecordRoute records the pattern mux matches for each request with
+// logging.With before serving it, and names the request's span after it.
+// Requests no pattern matches are answered as by ProblemMux. Subtree
+// patterns such as "/api/" mount another mux, which records the route
+// itself, so they are not recorded.
+func RecordRoute(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		switch {
+		case pattern == "":
+			serveUnmatched(w, r, h)
+			return
+		case strings.HasSuffix(pattern, "/"):
+			mux.ServeHTTP(w, r)
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(pattern)
+		span.SetAttributes(attribute.String("http.route", pattern))
+		mux.ServeHTTP(w, r.WithContext(logging.With(r.Context(), "route", pattern)))
+	})
+}
+
+// ProblemMux serves mux, answering requests no pattern matches with a
+// problem rather than the mux's plain text 404 or 405.
+func ProblemMux(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern == "" {
+			serveUnmatched(w, r, h)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveUnmatched runs the handler a mux returned for an unmatched request
+// to learn whether it is a 404 or a 405, and writes that as a problem.
+// Anything else, such as a redirect to the canonical path, is copied to w
+// as the handler wrote it.
+func serveUnmatched(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	rec := &bufferedResponse{header: http.Header{}}
+	h.ServeHTTP(rec, r)
+	switch rec.status {
+	case http.StatusNotFound:
+		problem.Write(w, http.StatusNotFound, problem.NotFound, "no route matches "+r.URL.Path)
+	case http.StatusMethodNotAllowed:
+		w.Header().Set("Allow", rec.header.Get("Allow"))
+		problem.Write(w, http.StatusMethodNotAllowed, problem.MethodNotAllowed, r.Method+" is not allowed here")
+	default:
+		maps.Copy(w.Header(), rec.header)
+		if rec.status != 0 {
+			w.WriteHeader(rec.status)
+		}
+		_, _ = w.Write(rec.body.Bytes())
+	}
+}
+
+// bufferedResponse holds a whole response. It is only used for the mux's
+// own small responses to unmatched requests.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+func testMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hexes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hex " + r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /hexes", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /dir/", func(w http.ResponseWriter, r *http.Request) {})
+	return mux
+}
+
+func TestProblemMux(t *testing.T) {
+	srv := ProblemMux(testMux())
+	tests := []struct {
+		method, path string
+		status       int
+		code         problem.Code
+		allow        string
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound, problem.NotFound, ""},
+		{http.MethodDelete, "/hexes", http.StatusMethodNotAllowed, problem.MethodNotAllowed, "POST"},
+		{http.MethodPut, "/hexes/7", http.StatusMethodNotAllowed, problem.MethodNotAllowed, "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			w.Header().Set(requestIDHeader, "req-1")
+			srv.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+			var p schema.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("body %q: %v", w.Body.String(), err)
+			}
+			if p.Status != tt.status || p.Code != string(tt.code) || p.Title != http.StatusText(tt.status) || p.RequestId != "req-1" || p.Detail == "" {
+				t.Errorf("problem = %+v", p)
+			}
+		})
+	}
+}
+
+func TestProblemMuxPassesThrough(t *testing.T) {
+	srv := ProblemMux(testMux())
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hexes/7", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hex 7" {
+		t.Errorf("matched route = %d %q", w.Code, w.Body.String())
+	}
+
+	// the mux's redirect to the canonical path is kept
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dir", nil))
+	plain := httptest.NewRecorder()
+	testMux().ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/dir", nil))
+	if w.Code != plain.Code || w.Header().Get("Location") != "/dir/" {
+		t.Errorf("redirect = %d %q, want %d /dir/", w.Code, w.Header().Get("Location"), plain.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NewForwardedForMiddleware rewrites r.RemoteAddr to the client address
+// appended by a reverse proxy in X-Forwarded-For. Only install it when the
+// server is reachable solely through such a proxy: the header is otherwise
+// trivially spoofed.
+func NewForwardedForMiddleware() func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			values := r.Header.Values("X-Forwarded-For")
+			if len(values) > 0 {
+				// the proxy in front of us appends last, so the rightmost
+				// entry is the only one it vouches for
+				hops := strings.Split(values[len(values)-1], ",")
+				if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+					r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+				}
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that sent r, without port.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+func TestCSRF(t *testing.T) {
+	csrf, err := NewCSRFMiddleware([]string{"https://app.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := csrf(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	tests := []struct {
+		name     string
+		method   string
+		header   map[string]string
+		noCookie bool
+		status   int
+	}{
+		{"cross-site post", http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.test"}, false, 403},
+		{"same-site post", http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://www.api.test"}, false, 403},
+		{"cross-origin post without Sec-Fetch-Site", http.MethodPost, map[string]string{"Origin": "https://evil.test"}, false, 403},
+		{"cross-site delete", http.MethodDelete, map[string]string{"Sec-Fetch-Site": "cross-site"}, false, 403},
+		{"cross-site post with a bearer token", http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.test", "Authorization": "Bearer hxt_abc"}, false, 200},
+		{"cross-site post without the session cookie", http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.test"}, true, 200},
+		{"trusted origin", http.MethodPost, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://app.test"}, false, 200},
+		{"same-origin post", http.MethodPost, map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "https://api.test"}, false, 200},
+		{"same-origin post without Sec-Fetch-Site", http.MethodPost, map[string]string{"Origin": "https://api.test"}, false, 200},
+		{"post from a non-browser client", http.MethodPost, nil, false, 200},
+		{"cross-site get", http.MethodGet, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.test"}, false, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "https://api.test/api/v1/hexes", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			if !tt.noCookie {
+				r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session"})
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == 403 {
+				var p schema.Problem
+				if err := json.NewDecoder(w.Body).Decode(&p); err != nil || p.Code != "cross_origin_rejected" {
+					t.Errorf("problem = %+v, %v, want cross_origin_rejected", p, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCSRFBadTrustedOrigin(t *testing.T) {
+	if _, err := NewCSRFMiddleware([]string{"https://app.test/path"}); err == nil {
+		t.Error("NewCSRFMiddleware accepted an origin with a path")
+	}
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
+)
+
+// unmatchedRoute labels requests no route pattern matched, so arbitrary
+// paths cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// NewMetricsMiddleware counts and times requests by route pattern. The
+// pattern comes from RecordRoute, so it must run inside the access log
+// middleware, which sets up the request info RecordRoute writes to.
+func NewMetricsMiddleware() func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			handler.ServeHTTP(rec, r)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := unmatchedRoute
+			if ri := logging.RequestInfoFrom(r.Context()); ri != nil {
+				if p, ok := ri.Value("route").(string); ok {
+					route = p
+				}
+			}
+			metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			metrics.HTTPDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
+)
+
+// nestedMuxes mirrors cmd/api: root routes next to /api/, which strips
+// its prefix into the API mux and then into v1.
+func nestedMuxes() http.Handler {
+	v1 := http.NewServeMux()
+	v1.HandleFunc("GET /hexes/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	api := http.NewServeMux()
+	api.Handle("/v1/", http.StripPrefix("/v1", RecordRoute(v1)))
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	root.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {})
+	root.Handle("/api/", http.StripPrefix("/api", ProblemMux(api)))
+
+	h := NewMetricsMiddleware()(RecordRoute(root))
+	return NewAccessLogMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)))(h)
+}
+
+func TestMetricsRouteLabels(t *testing.T) {
+	srv := nestedMuxes()
+	tests := []struct {
+		method, path, route, code string
+	}{
+		{"GET", "/healthz", "GET /healthz", "200"},
+		{"GET", "/metrics", "GET /metrics", "200"},
+		{"GET", "/api/v1/hexes/7", "GET /hexes/{id}", "200"},
+		{"POST", "/api/v1/hexes/7", unmatchedRoute, "405"},
+		{"GET", "/api/v1/nope", unmatchedRoute, "404"},
+		{"GET", "/api/nope", unmatchedRoute, "404"},
+		{"GET", "/nope", unmatchedRoute, "404"},
+		{"POST", "/healthz", unmatchedRoute, "405"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			c := metrics.HTTPRequests.WithLabelValues(tt.method, tt.route, tt.code)
+			before := testutil.ToFloat64(c)
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if got := testutil.ToFloat64(c) - before; got != 1 {
+				t.Errorf("requests{route=%q, code=%s} went up by %v, want 1", tt.route, tt.code, got)
+			}
+		})
+	}
+}
+
+func TestServeUnmatchedRunsHandlerOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		status  int
+	}{
+		{"not found", http.NotFound, http.StatusNotFound},
+		{"method not allowed", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", "GET")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}, http.StatusMethodNotAllowed},
+		{"redirect", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/dir/", http.StatusMovedPermanently)
+		}, http.StatusMovedPermanently},
+		{"body without status", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				tt.handler(w, r)
+			})
+			w := httptest.NewRecorder()
+			serveUnmatched(w, httptest.NewRequest(http.MethodGet, "/dir", nil), h)
+			if calls != 1 {
+				t.Errorf("handler ran %d times, want 1", calls)
+			}
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+
+	// a passed-through response is copied whole
+	w := httptest.NewRecorder()
+	serveUnmatched(w, httptest.NewRequest(http.MethodGet, "/dir", nil), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/dir/", http.StatusMovedPermanently)
+	}))
+	if w.Header().Get("Location") != "/dir/" || w.Body.Len() == 0 {
+		t.Errorf("redirect = %v %q", w.Header(), w.Body.String())
+	}
+}
@@ -5,24 +5,43 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
-	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
 )
 
 type contextKey string
 
-const authedUserIDKey contextKey = "authedUserId"
+const (
+	authedUserIDKey    contextKey = "authedUserId"
+	authedSessionIDKey contextKey = "authedSessionId"
+	authedTokenIDKey   contextKey = "authedTokenId"
+	authedScopesKey    contextKey = "authedScopes"
+)
+
+const sessionCookieName = "hextok_session"
 
-func NewAuthMiddleware(sessionRepo domains.SessionRepo) func(http.Handler) http.Handler {
+// NewAuthMiddleware authenticates requests by session token, read from the
+// Authorization header or the session cookie, and enforces policy: expired
+// sessions are deleted and rejected, and live ones have lastVerifiedAt bumped
+// at most once per policy.RenewAfter.
+//
+// When tokenRepo is set, personal access tokens are accepted in the
+// Authorization header too. Those requests carry the token's scopes, which
+// RequireScope checks per route.
+func NewAuthMiddleware(sessionRepo domains.SessionRepo, tokenRepo domains.AccessTokenRepo, policy domains.SessionPolicy) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Try Authorization header first (preferred for mobile apps)
 			authHeader := r.Header.Get("Authorization")
 			var sessionToken string
+			fromCookie := false
 
 			if authHeader != "" {
 				// Check for Bearer token format
@@ -34,36 +53,45 @@ func NewAuthMiddleware(sessionRepo domains.SessionRepo) func(http.Handler) http.
 				}
 			} else {
 				// Fallback to cookie for web clients
-				hexttokCookie, err := r.Cookie("hextok_session")
+				hexttokCookie, err := r.Cookie(sessionCookieName)
 				if err != nil {
-					writeJSONError(w, http.StatusUnauthorized, "authentication required - no token or session cookie")
+					problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "authentication required - no token or session cookie")
 					return
 				}
 				sessionToken = hexttokCookie.Value
+				fromCookie = true
 			}
 
 			if sessionToken == "" {
-				writeJSONError(w, http.StatusUnauthorized, "authentication required - empty token")
+				problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "authentication required - empty token")
+				return
+			}
+			if !fromCookie && strings.HasPrefix(sessionToken, domains.AccessTokenPrefix) {
+				if tokenRepo == nil {
+					problem.Write(w, http.StatusUnauthorized, problem.AccessTokenNotAccepted, "access tokens are not accepted here")
+					return
+				}
+				serveAccessToken(w, r, handler, tokenRepo, policy, sessionToken)
 				return
 			}
 
 			// Decode the session token
 			decodedVals, err := base64.RawURLEncoding.DecodeString(sessionToken)
 			if err != nil {
-				writeJSONError(w, http.StatusUnauthorized, "invalid session encoding")
+				problem.Write(w, http.StatusUnauthorized, problem.InvalidSession, "invalid session encoding")
 				return
 			}
 
 			decodedString := string(decodedVals)
 			parts := strings.Split(decodedString, "|")
 			if len(parts) != 2 {
-				writeJSONError(w, http.StatusUnauthorized, "bad session format")
+				problem.Write(w, http.StatusUnauthorized, problem.InvalidSession, "bad session format")
 				return
 			}
 
 			id, err := strconv.ParseInt(parts[0], 10, 64)
 			if err != nil {
-				writeJSONError(w, http.StatusUnauthorized, "bad session id")
+				problem.Write(w, http.StatusUnauthorized, problem.InvalidSession, "bad session id")
 				return
 			}
 
@@ -73,29 +101,134 @@ func NewAuthMiddleware(sessionRepo domains.SessionRepo) func(http.Handler) http.
 
 			s, err := sessionRepo.GetSessionById(r.Context(), id)
 			if err != nil {
-				writeJSONError(w, http.StatusUnauthorized, "session not found")
+				problem.Write(w, http.StatusUnauthorized, problem.InvalidSession, "session not found")
 				return
 			}
 
 			if subtle.ConstantTimeCompare(s.SecretHash, []byte(hash)) != 1 {
-				writeJSONError(w, http.StatusUnauthorized, "invalid session")
+				problem.Write(w, http.StatusUnauthorized, problem.InvalidSession, "invalid session")
 				return
 			}
 
-			authContext := context.WithValue(r.Context(), authedUserIDKey, s.UserId)
+			now := time.Now()
+			if policy.Expired(s, now) {
+				if err := sessionRepo.DeleteSession(r.Context(), s.Id); err != nil {
+					logging.FromContext(r.Context()).Error("delete expired session", "session_id", s.Id, "err", err)
+				}
+				if fromCookie {
+					http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+				}
+				problem.Write(w, http.StatusUnauthorized, problem.SessionExpired, "session expired")
+				return
+			}
+			if policy.NeedsRenewal(s, now) {
+				// a failed renewal only shortens the idle window; serve the request
+				if err := sessionRepo.UpdateLastVerified(r.Context(), s.Id, now); err != nil {
+					logging.FromContext(r.Context()).Error("renew session", "session_id", s.Id, "err", err)
+				}
+			}
+
+			authContext := logging.With(r.Context(), "user_id", s.UserId)
+			authContext = context.WithValue(authContext, authedUserIDKey, s.UserId)
+			authContext = context.WithValue(authContext, authedSessionIDKey, s.Id)
 			newR := r.WithContext(authContext)
 			handler.ServeHTTP(w, newR)
 		})
 	}
 }
 
-func writeJSONError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+// serveAccessToken authenticates r by the personal access token raw and
+// serves it with the token's user and scopes in the context.
+func serveAccessToken(w http.ResponseWriter, r *http.Request, handler http.Handler, tokenRepo domains.AccessTokenRepo, policy domains.SessionPolicy, raw string) {
+	t, err := tokenRepo.GetAccessTokenByHash(r.Context(), HashAccessToken(raw))
+	if err != nil {
+		problem.Write(w, http.StatusUnauthorized, problem.InvalidAccessToken, "invalid access token")
+		return
+	}
+	now := time.Now()
+	if t.Expired(now) {
+		problem.Write(w, http.StatusUnauthorized, problem.AccessTokenExpired, "access token expired")
+		return
+	}
+	// like session renewal, lastUsedAt is written at most once per
+	// RenewAfter so scripts do not cause a write per request
+	if now.Sub(t.LastUsedAt) >= policy.RenewAfter {
+		if err := tokenRepo.UpdateAccessTokenLastUsed(r.Context(), t.Id, now); err != nil {
+			logging.FromContext(r.Context()).Error("update access token last use", "token_id", t.Id, "err", err)
+		}
+	}
+
+	ctx := logging.With(r.Context(), "user_id", t.UserId, "token_id", t.Id)
+	ctx = context.WithValue(ctx, authedUserIDKey, t.UserId)
+	ctx = context.WithValue(ctx, authedTokenIDKey, t.Id)
+	ctx = context.WithValue(ctx, authedScopesKey, t.Scopes)
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// HashAccessToken is how personal access tokens are stored and looked up.
+func HashAccessToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return base64.StdEncoding.EncodeToString(sum[:])
 }
+
+// RequireScope rejects requests authenticated by an access token that was
+// not granted scope. Session requests are not limited by scope. It must run
+// after the auth middleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				problem.Write(w, http.StatusForbidden, problem.InsufficientScope, "access token lacks the "+scope+" scope")
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests authenticated by an access token, for
+// account management routes a token must not be able to reach. It must run
+// after the auth middleware.
+func RequireSession(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetAuthedSessionID(r.Context()); !ok {
+			problem.Write(w, http.StatusForbidden, problem.SessionRequired, "this endpoint requires a login session")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 func GetAuthedUserID(ctx context.Context) (int64, bool) {
 	v := ctx.Value(authedUserIDKey)
 	id, ok := v.(int64)
 	return id, ok
 }
+
+// GetAuthedSessionID returns the id of the session that authenticated the
+// request.
+func GetAuthedSessionID(ctx context.Context) (int64, bool) {
+	v := ctx.Value(authedSessionIDKey)
+	id, ok := v.(int64)
+	return id, ok
+}
+
+// GetAuthedTokenID returns the id of the access token that authenticated
+// the request; ok is false for session requests.
+func GetAuthedTokenID(ctx context.Context) (int64, bool) {
+	v := ctx.Value(authedTokenIDKey)
+	id, ok := v.(int64)
+	return id, ok
+}
+
+// HasScope reports whether the request may use scope: always for sessions,
+// and for access tokens when the token was granted it.
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ctx.Value(authedScopesKey).([]string)
+	if !ok {
+		_, ok := GetAuthedSessionID(ctx)
+		return ok
+	}
+	return slices.Contains(scopes, scope)
+}
@@ -0,0 +1,197 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+var testPolicy = domains.SessionPolicy{AbsoluteTTL: 30 * 24 * time.Hour, IdleTTL: 7 * 24 * time.Hour, RenewAfter: time.Hour}
+
+// authServer serves routes behind NewAuthMiddleware over a memstore with one
+// user. Each route answers with the authenticated user id.
+type authServer struct {
+	store  *memstore.Store
+	srv    http.Handler
+	userId int64
+}
+
+func newAuthServer(t *testing.T, policy domains.SessionPolicy) *authServer {
+	t.Helper()
+	s := memstore.New()
+	userId, err := s.CreateUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuthMiddleware(s, s, policy)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := GetAuthedUserID(r.Context())
+		_, _ = w.Write([]byte(strconv.FormatInt(id, 10)))
+	})
+	mux := http.NewServeMux()
+	mux.Handle("GET /hexes", auth(RequireScope(domains.ScopeReadHexes)(ok)))
+	mux.Handle("POST /hexes", auth(RequireScope(domains.ScopeWriteHexes)(ok)))
+	mux.Handle("GET /me/tokens", auth(RequireSession(ok)))
+	return &authServer{store: s, srv: mux, userId: userId}
+}
+
+// session creates a session for the user and returns its bearer token.
+func (a *authServer) session(t *testing.T) (int64, string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte("secret"))
+	id, err := a.store.CreateSession(context.Background(), a.userId, base64.StdEncoding.EncodeToString(sum[:]), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10) + "|secret"))
+}
+
+// token stores raw as an access token with scopes and returns its id.
+func (a *authServer) token(t *testing.T, raw string, expiresAt time.Time, scopes ...string) int64 {
+	t.Helper()
+	id, err := a.store.CreateAccessToken(context.Background(), domains.AccessToken{
+		UserId:    a.userId,
+		Name:      "script",
+		TokenHash: HashAccessToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func (a *authServer) do(t *testing.T, method, target, auth string) (*http.Response, schema.Problem) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	a.srv.ServeHTTP(w, r)
+	resp := w.Result()
+	var p schema.Problem
+	if resp.StatusCode != http.StatusOK {
+		_ = json.NewDecoder(resp.Body).Decode(&p)
+	}
+	return resp, p
+}
+
+func TestAccessTokenAuth(t *testing.T) {
+	a := newAuthServer(t, testPolicy)
+	now := time.Now()
+	a.token(t, "hxt_read", time.Time{}, domains.ScopeReadHexes)
+	a.token(t, "hxt_both", now.Add(time.Hour), domains.ScopeReadHexes, domains.ScopeWriteHexes)
+	a.token(t, "hxt_expired", now.Add(-time.Second), domains.ScopeReadHexes)
+	revoked := a.token(t, "hxt_revoked", time.Time{}, domains.ScopeReadHexes)
+	if err := a.store.DeleteAccessToken(context.Background(), a.userId, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		auth   string
+		status int
+		code   string
+	}{
+		{"read token reads", http.MethodGet, "Bearer hxt_read", 200, ""},
+		{"without the Bearer prefix", http.MethodGet, "hxt_read", 200, ""},
+		{"read token writes", http.MethodPost, "Bearer hxt_read", 403, "insufficient_scope"},
+		{"write token writes", http.MethodPost, "Bearer hxt_both", 200, ""},
+		{"unknown token", http.MethodGet, "Bearer hxt_unknown", 401, "invalid_access_token"},
+		{"revoked token", http.MethodGet, "Bearer hxt_revoked", 401, "invalid_access_token"},
+		{"expired token", http.MethodGet, "Bearer hxt_expired", 401, "access_token_expired"},
+		{"no credentials", http.MethodGet, "", 401, "unauthenticated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, p := a.do(t, tt.method, "/hexes", tt.auth)
+			if resp.StatusCode != tt.status || p.Code != tt.code {
+				t.Errorf("%s /hexes = %d %q, want %d %q", tt.method, resp.StatusCode, p.Code, tt.status, tt.code)
+			}
+		})
+	}
+
+	resp, _ := a.do(t, http.MethodPost, "/hexes", "Bearer hxt_read")
+	if got, want := resp.Header.Get("WWW-Authenticate"), `Bearer error="insufficient_scope", scope="write:hexes"`; got != want {
+		t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+	}
+}
+
+func TestAccessTokenLastUsedThrottled(t *testing.T) {
+	a := newAuthServer(t, testPolicy)
+	id := a.token(t, "hxt_read", time.Time{}, domains.ScopeReadHexes)
+	lastUsed := func() time.Time {
+		t.Helper()
+		tok, err := a.store.GetAccessTokenByHash(context.Background(), HashAccessToken("hxt_read"))
+		if err != nil || tok.Id != id {
+			t.Fatalf("GetAccessTokenByHash = %+v, %v", tok, err)
+		}
+		return tok.LastUsedAt
+	}
+
+	a.do(t, http.MethodGet, "/hexes", "Bearer hxt_read")
+	first := lastUsed()
+	if first.IsZero() {
+		t.Fatal("first use not recorded")
+	}
+	a.do(t, http.MethodGet, "/hexes", "Bearer hxt_read")
+	if got := lastUsed(); !got.Equal(first) {
+		t.Errorf("lastUsedAt moved from %v to %v within RenewAfter", first, got)
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	a := newAuthServer(t, testPolicy)
+	a.token(t, "hxt_all", time.Time{}, domains.Scopes...)
+	_, sess := a.session(t)
+
+	if resp, p := a.do(t, http.MethodGet, "/me/tokens", "Bearer hxt_all"); resp.StatusCode != 403 || p.Code != "session_required" {
+		t.Errorf("token on a session route = %d %q, want 403 session_required", resp.StatusCode, p.Code)
+	}
+	if resp, _ := a.do(t, http.MethodGet, "/me/tokens", "Bearer "+sess); resp.StatusCode != 200 {
+		t.Errorf("session on a session route = %d, want 200", resp.StatusCode)
+	}
+	// sessions are not limited by scope
+	if resp, _ := a.do(t, http.MethodPost, "/hexes", "Bearer "+sess); resp.StatusCode != 200 {
+		t.Errorf("session on a scoped route = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestAccessTokenNotAccepted(t *testing.T) {
+	a := newAuthServer(t, testPolicy)
+	a.token(t, "hxt_read", time.Time{}, domains.ScopeReadHexes)
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	// without a token repo, as on routes that take sessions only
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer hxt_read")
+	w := httptest.NewRecorder()
+	NewAuthMiddleware(a.store, nil, testPolicy)(ok).ServeHTTP(w, r)
+	var p schema.Problem
+	_ = json.NewDecoder(w.Body).Decode(&p)
+	if w.Code != 401 || p.Code != "access_token_not_accepted" {
+		t.Errorf("token without a token repo = %d %q, want 401 access_token_not_accepted", w.Code, p.Code)
+	}
+
+	// a token in the session cookie is not looked up as one
+	r = httptest.NewRequest(http.MethodGet, "/hexes", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "hxt_read"})
+	w = httptest.NewRecorder()
+	a.srv.ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("token in the session cookie = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// backdatedSession creates a session that was created createdAgo and last
+// verified verifiedAgo, and returns its id and token.
+func (a *authServer) backdatedSession(t *testing.T, createdAgo, verifiedAgo time.Duration) (int64, string) {
+	t.Helper()
+	now := time.Now()
+	a.store.SetClock(func() time.Time { return now.Add(-createdAgo) })
+	defer a.store.SetClock(time.Now)
+	id, tok := a.session(t)
+	if err := a.store.UpdateLastVerified(context.Background(), id, now.Add(-verifiedAgo)); err != nil {
+		t.Fatal(err)
+	}
+	return id, tok
+}
+
+func TestSessionExpiry(t *testing.T) {
+	policy := domains.SessionPolicy{AbsoluteTTL: 24 * time.Hour, IdleTTL: 2 * time.Hour, RenewAfter: 10 * time.Minute}
+	tests := []struct {
+		name                    string
+		createdAgo, verifiedAgo time.Duration
+		status                  int
+		code                    string
+		renewed                 bool
+	}{
+		{"fresh", time.Minute, time.Minute, 200, "", false},
+		{"just under renewal", time.Hour, 9 * time.Minute, 200, "", false},
+		{"due for renewal", time.Hour, 30 * time.Minute, 200, "", true},
+		{"nearly idle", 3 * time.Hour, 2*time.Hour - time.Minute, 200, "", true},
+		{"idle", 3 * time.Hour, 2 * time.Hour, 401, "session_expired", false},
+		{"nearly absolute", 24*time.Hour - time.Minute, time.Minute, 200, "", false},
+		{"absolute, though active", 24 * time.Hour, time.Minute, 401, "session_expired", false},
+	}
+	for _, tt := range tests {
+		for _, viaCookie := range []bool{false, true} {
+			name := tt.name + " bearer"
+			if viaCookie {
+				name = tt.name + " cookie"
+			}
+			t.Run(name, func(t *testing.T) {
+				a := newAuthServer(t, policy)
+				id, tok := a.backdatedSession(t, tt.createdAgo, tt.verifiedAgo)
+				before, _ := a.store.GetSessionById(context.Background(), id)
+
+				r := httptest.NewRequest(http.MethodGet, "/hexes", nil)
+				if viaCookie {
+					r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: tok})
+				} else {
+					r.Header.Set("Authorization", "Bearer "+tok)
+				}
+				w := httptest.NewRecorder()
+				began := time.Now()
+				a.srv.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d (%s)", w.Code, tt.status, w.Body)
+				}
+				after, err := a.store.GetSessionById(context.Background(), id)
+				if tt.code != "" {
+					if err == nil {
+						t.Error("expired session was not deleted")
+					}
+					var cleared bool
+					for _, c := range w.Result().Cookies() {
+						cleared = cleared || c.Name == sessionCookieName && c.MaxAge < 0
+					}
+					if cleared != viaCookie {
+						t.Errorf("session cookie cleared = %v, want %v", cleared, viaCookie)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("session gone after a valid request: %v", err)
+				}
+				renewed := !after.LastVerifiedAt.Equal(before.LastVerifiedAt)
+				if renewed != tt.renewed {
+					t.Errorf("renewed = %v, want %v", renewed, tt.renewed)
+				}
+				if renewed && after.LastVerifiedAt.Before(began) {
+					t.Errorf("lastVerifiedAt = %v, want the request time", after.LastVerifiedAt)
+				}
+				if !after.CreatedAt.Equal(before.CreatedAt) {
+					t.Error("renewal moved createdAt")
+				}
+			})
+		}
+	}
+}
+
+func b64(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+func TestSessionTokenRejected(t *testing.T) {
+	a := newAuthServer(t, testPolicy)
+	id, _ := a.session(t)
+	for _, tt := range []struct{ name, auth string }{
+		{"not base64", "Bearer !!!"},
+		{"no separator", "Bearer " + b64("1")},
+		{"bad id", "Bearer " + b64("x|secret")},
+		{"unknown id", "Bearer " + b64("999|secret")},
+		{"wrong secret", "Bearer " + b64(strconv.FormatInt(id, 10)+"|guess")},
+	} {
+		if resp, p := a.do(t, http.MethodGet, "/hexes", tt.auth); resp.StatusCode != 401 || p.Code != "invalid_session" {
+			t.Errorf("%s: %d %q, want 401 invalid_session", tt.name, resp.StatusCode, p.Code)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package problem writes the API's error responses. Every error, from the
+// auth middleware to the login flows, is an RFC 9457 problem details object
+// (schema.Problem) with one of the codes below, so clients can parse
+// failures the same way everywhere.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// requestIDHeader is set on the response by the access log middleware
+// before any handler runs.
+const requestIDHeader = "X-Request-Id"
+
+// Code identifies the kind of failure. Codes are part of the API: once
+// released they keep their meaning, and new failures get new codes.
+type Code string
+
+// Request errors.
+const (
+	// InvalidBody is a request body that is not the expected JSON.
+	InvalidBody      Code = "invalid_body"
+	ValidationFailed Code = "validation_failed"
+	// InvalidID is a missing or malformed id in the path.
+	InvalidID Code = "invalid_id"
+	// NotFound is a path no route matches.
+	NotFound         Code = "not_found"
+	MethodNotAllowed Code = "method_not_allowed"
+	// TooManyRequests is sent with a Retry-After header.
+	TooManyRequests Code = "too_many_requests"
+	Internal        Code = "internal_error"
+)
+
+// Authentication and authorization errors.
+const (
+	Unauthenticated        Code = "unauthenticated"
+	InvalidSession         Code = "invalid_session"
+	SessionExpired         Code = "session_expired"
+	SessionRequired        Code = "session_required"
+	InvalidAccessToken     Code = "invalid_access_token"
+	AccessTokenExpired     Code = "access_token_expired"
+	AccessTokenNotAccepted Code = "access_token_not_accepted"
+	InsufficientScope      Code = "insufficient_scope"
+	CrossOriginRejected    Code = "cross_origin_rejected"
+)
+
+// Resource errors.
+const (
+	UserNotFound     Code = "user_not_found"
+	HexNotFound      Code = "hex_not_found"
+	SessionNotFound  Code = "session_not_found"
+	IdentityNotFound Code = "identity_not_found"
+	TokenNotFound    Code = "token_not_found"
+	HexExists        Code = "hex_exists"
+	AlreadyFollowing Code = "already_following"
+	LastLoginMethod  Code = "last_login_method"
+)
+
+// Login errors.
+const (
+	UnknownProvider        Code = "unknown_provider"
+	ProviderUnavailable    Code = "provider_unavailable"
+	DeviceLoginUnavailable Code = "device_login_unavailable"
+	InvalidState           Code = "invalid_state"
+	LinkUserMismatch       Code = "link_user_mismatch"
+	IdentityInUse          Code = "identity_in_use"
+	LoginLinkInvalid       Code = "login_link_invalid"
+	LoginLinkUsed          Code = "login_link_used"
+	MailUnavailable        Code = "mail_unavailable"
+	InvalidExchangeToken   Code = "invalid_exchange_token"
+)
+
+// Device flow poll errors, named as in RFC 8628 so device clients can
+// handle them as they would the provider's. Any other provider error is
+// ProviderUnavailable.
+const (
+	AuthorizationPending Code = "authorization_pending"
+	SlowDown             Code = "slow_down"
+	AccessDenied         Code = "access_denied"
+	ExpiredToken         Code = "expired_token"
+)
+
+// New builds the problem for a response, for handlers that add members
+// before sending it with Send. Most use Write.
+func New(w http.ResponseWriter, status int, code Code, detail string) schema.Problem {
+	return schema.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Code:      string(code),
+		Detail:    detail,
+		RequestId: w.Header().Get(requestIDHeader),
+	}
+}
+
+// Send writes body, a schema.Problem or a struct embedding one, with
+// status.
+func Send(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Write responds with status and a problem of code.
+func Write(w http.ResponseWriter, status int, code Code, detail string) {
+	Send(w, status, New(w, status, code, detail))
+}
+
+// Invalid responds 400 validation_failed listing errs.
+func Invalid(w http.ResponseWriter, errs ...schema.FieldError) {
+	p := New(w, http.StatusBadRequest, ValidationFailed, "the request has invalid fields")
+	p.Errors = errs
+	Send(w, http.StatusBadRequest, p)
+}
+
+// Field is shorthand for a schema.FieldError.
+func Field(field, detail string) schema.FieldError {
+	return schema.FieldError{Field: field, Detail: detail}
+}
@@ -0,0 +1,62 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) schema.Problem {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	var p schema.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	return p
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "req-1")
+	Write(w, http.StatusNotFound, HexNotFound, "no hex 7")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	want := schema.Problem{Type: "about:blank", Title: "Not Found", Status: 404, Code: "hex_not_found", Detail: "no hex 7", RequestId: "req-1"}
+	if p := decode(t, w); p.Type != want.Type || p.Title != want.Title || p.Status != want.Status || p.Code != want.Code || p.Detail != want.Detail || p.RequestId != want.RequestId || p.Errors != nil {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	Invalid(w, Field("hexValue", "is required"), Field("limit", "must be a number"))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	p := decode(t, w)
+	if p.Status != 400 || p.Code != string(ValidationFailed) || p.Title != "Bad Request" {
+		t.Errorf("problem = %+v", p)
+	}
+	want := []schema.FieldError{{Field: "hexValue", Detail: "is required"}, {Field: "limit", Detail: "must be a number"}}
+	if len(p.Errors) != len(want) || p.Errors[0] != want[0] || p.Errors[1] != want[1] {
+		t.Errorf("errors = %+v, want %+v", p.Errors, want)
+	}
+
+	// the raw JSON uses the documented member names
+	var raw map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &raw)
+	for _, k := range []string{"type", "title", "status", "code", "detail", "errors"} {
+		if _, ok := raw[k]; !ok {
+			t.Errorf("body has no %q member: %s", k, w.Body.String())
+		}
+	}
+}
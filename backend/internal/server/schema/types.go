@@ -2,8 +2,27 @@ package schema
 
 import "time"
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem is the body of every error response, an RFC 9457 problem details
+// object served as application/problem+json. Code is stable and meant for
+// programs to switch on; Detail is for people and may change.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+	// Errors lists the invalid fields when Code is validation_failed.
+	Errors []FieldError `json:"errors,omitempty"`
+	// RequestId matches the X-Request-Id response header and the server's
+	// logs.
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// FieldError is one invalid field of a request. Field is the JSON name of a
+// body field, or of a query parameter.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
 }
 
 type OkResponse struct {
@@ -34,12 +53,95 @@ type AuthedUserSchema struct {
 }
 
 type HexResponse struct {
-	Id        int64  `json:"id"`
-	HexValue  string `json:"hexValue"`
-	LikeCount int    `json:"likeCount,omitempty"`
-	IsLiked   bool   `json:"isLiked,omitempty"`
+	Id        int64     `json:"id"`
+	HexValue  string    `json:"hexValue"`
+	LikeCount int       `json:"likeCount,omitempty"`
+	IsLiked   bool      `json:"isLiked,omitempty"`
+	CreatedBy int64     `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitzero"`
+}
+
+type SimilarHexResponse struct {
+	Id       int64   `json:"id"`
+	HexValue string  `json:"hexValue"`
+	Distance float64 `json:"distance"`
 }
 
 type NewHexRequest struct {
 	HexValue string `json:"hexValue"`
 }
+
+// HexConflictResponse is returned when the submitted color already exists;
+// Id points at the existing hex.
+type HexConflictResponse struct {
+	Problem
+	Id int64 `json:"id"`
+}
+
+type HexFeedResponse struct {
+	Items      []HexResponse `json:"items"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+type SessionResponse struct {
+	Id             int64     `json:"id"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastVerifiedAt time.Time `json:"lastVerifiedAt"`
+	UserAgent      string    `json:"userAgent,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	Current        bool      `json:"current"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// IdentityResponse describes a login method linked to the user. Provider
+// tokens stay server side.
+type IdentityResponse struct {
+	Id             int64     `json:"id"`
+	Provider       string    `json:"provider"`
+	ProviderUserId string    `json:"providerUserId"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type CreateAccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is optional; zero creates a token that does not expire.
+	ExpiresInDays int `json:"expiresInDays,omitempty"`
+}
+
+type AccessTokenResponse struct {
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt,omitzero"`
+	ExpiresAt  time.Time `json:"expiresAt,omitzero"`
+}
+
+// CreatedAccessTokenResponse is the only response that includes the token;
+// it cannot be retrieved again.
+type CreatedAccessTokenResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
+
+// ReadinessResponse lists each readiness check as "ok" or the reason it
+// failed.
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Revision  string `json:"revision,omitempty"`
+	// RevisionTime is the commit time of Revision.
+	RevisionTime time.Time `json:"revisionTime,omitzero"`
+	// Modified reports uncommitted changes in the build's working tree.
+	Modified  bool      `json:"modified"`
+	StartedAt time.Time `json:"startedAt"`
+}
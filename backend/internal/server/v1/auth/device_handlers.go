@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+// Device authorization (RFC 8628) lets clients without a browser, such as a
+// CLI or a TV, log in: the client shows a user code, the user enters it on
+// GitHub from another device, and the client polls until it gets a hextok
+// session. The GitHub device code is handed to the client and sent back on
+// every poll, so the server keeps no state for pending logins.
+
+// deviceProvider is the provider behind the device flow endpoints.
+const deviceProvider = "github"
+
+// DeviceStartResponse uses the RFC 8628 field names.
+type DeviceStartResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type DevicePollRequest struct {
+	DeviceCode string `json:"device_code"`
+}
+
+// DevicePollErrorResponse carries an RFC 8628 token error, whose code
+// (authorization_pending, slow_down, access_denied or expired_token) becomes
+// the problem's. Interval is only set with slow_down.
+type DevicePollErrorResponse struct {
+	schema.Problem
+	Interval int `json:"interval,omitempty"`
+}
+
+// devicePollCodes are the provider poll errors passed on to the client.
+// Anything else, such as GitHub's incorrect_client_credentials, is a
+// problem with our setup rather than the login and is not exposed.
+var devicePollCodes = map[string]problem.Code{
+	domains.DeviceAuthorizationPending: problem.AuthorizationPending,
+	domains.DeviceSlowDown:             problem.SlowDown,
+	domains.DeviceAccessDenied:         problem.AccessDenied,
+	domains.DeviceExpiredToken:         problem.ExpiredToken,
+}
+
+func (h *Handler) deviceAuthorizer() (domains.DeviceAuthorizer, bool) {
+	p, ok := h.providers[deviceProvider].(domains.DeviceAuthorizer)
+	return p, ok
+}
+
+// StartDeviceHandler requests a device and user code from GitHub.
+func (h *Handler) StartDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.deviceAuthorizer()
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.DeviceLoginUnavailable, "device login is not available")
+		return
+	}
+	auth, err := p.StartDevice(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("device login: StartDevice failed", "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(DeviceStartResponse{
+		DeviceCode:      auth.DeviceCode,
+		UserCode:        auth.UserCode,
+		VerificationURI: auth.VerificationURI,
+		ExpiresIn:       int(auth.ExpiresIn.Seconds()),
+		Interval:        int(auth.Interval.Seconds()),
+	})
+}
+
+// PollDeviceHandler asks GitHub whether the user approved the device code.
+// While they have not it answers 400 with authorization_pending, or
+// slow_down and a longer interval when the client polls too fast; the
+// client must keep waiting the interval between polls. Once approved it logs
+// the user in and returns a session token like the mobile exchange does.
+func (h *Handler) PollDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.deviceAuthorizer()
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.DeviceLoginUnavailable, "device login is not available")
+		return
+	}
+	var req DevicePollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceCode == "" {
+		problem.Invalid(w, problem.Field("device_code", "is required"))
+		return
+	}
+
+	tok, err := p.PollDevice(r.Context(), req.DeviceCode)
+	var pe *domains.DevicePollError
+	if errors.As(err, &pe) {
+		code, ok := devicePollCodes[pe.Code]
+		if !ok {
+			logging.FromContext(r.Context()).Error("device login: unexpected poll error", "err", pe)
+			problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
+			return
+		}
+		if code != problem.AuthorizationPending && code != problem.SlowDown {
+			logging.FromContext(r.Context()).Warn("device login: poll rejected", "err", pe)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		problem.Send(w, http.StatusBadRequest, DevicePollErrorResponse{
+			Problem:  problem.New(w, http.StatusBadRequest, code, pe.Description),
+			Interval: int(pe.Interval.Seconds()),
+		})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("device login: PollDevice failed", "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
+		return
+	}
+
+	profile, err := h.providers[deviceProvider].Profile(r.Context(), tok)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("device login: profile fetch failed", "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "failed to fetch user profile")
+		return
+	}
+	userId, err := h.loginUser(r.Context(), deviceProvider, profile, tok)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("device login failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create user")
+		return
+	}
+	sessionID, rawTok, err := h.createSession(r, userId)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("device login: CreateSession failed", "user_id", userId, "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create session")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(MobileTokenResponse{
+		Token:     encodeSessionToken(sessionID, rawTok),
+		ExpiresIn: int(h.sessionTTL.Seconds()),
+		UserID:    userId,
+		SessionID: sessionID,
+	})
+	logging.FromContext(r.Context()).Info("device login: logged in", "user_id", userId, "session_id", sessionID)
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// fakeDeviceProvider answers every poll with err.
+type fakeDeviceProvider struct {
+	fakeProvider
+	err error
+}
+
+func (fakeDeviceProvider) StartDevice(ctx context.Context) (domains.DeviceAuth, error) {
+	return domains.DeviceAuth{DeviceCode: "dc", UserCode: "ABCD-1234", VerificationURI: "https://idp.test/device", ExpiresIn: 15 * time.Minute, Interval: 5 * time.Second}, nil
+}
+
+func (p fakeDeviceProvider) PollDevice(ctx context.Context, deviceCode string) (domains.OAuthToken, error) {
+	if p.err != nil {
+		return domains.OAuthToken{}, p.err
+	}
+	return domains.OAuthToken{AccessToken: "provider-access"}, nil
+}
+
+func TestPollDeviceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *domains.DevicePollError
+		status   int
+		code     string
+		detail   string
+		interval int
+	}{
+		{"pending", &domains.DevicePollError{Code: "authorization_pending", Description: "waiting"}, 400, "authorization_pending", "waiting", 0},
+		{"slow down", &domains.DevicePollError{Code: "slow_down", Interval: 10 * time.Second}, 400, "slow_down", "", 10},
+		{"denied", &domains.DevicePollError{Code: "access_denied", Description: "user said no"}, 400, "access_denied", "user said no", 0},
+		{"expired", &domains.DevicePollError{Code: "expired_token"}, 400, "expired_token", "", 0},
+		{"provider misconfigured", &domains.DevicePollError{Code: "incorrect_client_credentials", Description: "The client_id and/or client_secret passed are incorrect."}, 502, "provider_unavailable", "provider unavailable", 0},
+		{"made up code", &domains.DevicePollError{Code: "<script>", Description: "<b>hi</b>"}, 502, "provider_unavailable", "provider unavailable", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _, srv := newTestHandler(t, nil)
+			h.providers[deviceProvider] = fakeDeviceProvider{err: tt.err}
+
+			resp := do(t, srv, http.MethodPost, "/api/v1/oauth/device/poll", `{"device_code":"dc"}`)
+			var body DevicePollErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || body.Code != tt.code || body.Detail != tt.detail || body.Interval != tt.interval {
+				t.Errorf("poll = %d %+v, want %d %s %q interval %d", resp.StatusCode, body, tt.status, tt.code, tt.detail, tt.interval)
+			}
+		})
+	}
+}
+
+func TestPollDeviceLogsIn(t *testing.T) {
+	h, _, srv := newTestHandler(t, nil)
+	h.providers[deviceProvider] = fakeDeviceProvider{}
+
+	resp := do(t, srv, http.MethodPost, "/api/v1/oauth/device/poll", `{"device_code":"dc"}`)
+	var tok MobileTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || resp.StatusCode != http.StatusOK || tok.Token == "" {
+		t.Errorf("poll = %d %+v, %v", resp.StatusCode, tok, err)
+	}
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+// Passwordless email login. POST /auth/email/start mails a link to
+// GET /auth/email/verify, which logs the user in the same way an OAuth
+// callback does: a session cookie for web clients, a deep link with a
+// one-time auth code for the mobile app.
+
+const (
+	emailLinkTTL  = 15 * time.Minute
+	emailTokenLen = 32
+	// emailLinkPurpose separates link signatures from other uses of the
+	// state key.
+	emailLinkPurpose = "email-login|"
+
+	// At most emailStartPerAddr links are mailed to one address, and
+	// emailStartPerIP requested from one client, per emailStartWindow.
+	emailStartWindow  = 15 * time.Minute
+	emailStartPerAddr = 5
+	emailStartPerIP   = 20
+)
+
+// EmailStartRequest starts an email login. Mobile clients set Client to
+// "mobile" and pass the same state and PKCE parameters as for
+// /oauth/start/{provider}?client=mobile.
+type EmailStartRequest struct {
+	Email               string `json:"email"`
+	Client              string `json:"client"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// StartEmailLoginHandler emails a single-use login link. It answers the same
+// way whether or not the address belongs to a user, and 429 once the address
+// or the client has asked for too many links.
+func (h *Handler) StartEmailLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req EmailStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidBody, "invalid request body")
+		return
+	}
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		problem.Invalid(w, problem.Field("email", "is not a valid email address"))
+		return
+	}
+	email := strings.ToLower(addr.Address)
+
+	t := domains.EmailToken{Email: email, ExpiresAt: time.Now().Add(emailLinkTTL)}
+	switch req.Client {
+	case "", "web":
+	case "mobile":
+		if req.State == "" {
+			problem.Invalid(w, problem.Field("state", "is required for mobile clients"))
+			return
+		}
+		if fe := h.checkCodeChallenge(req.CodeChallenge, req.CodeChallengeMethod); fe != nil {
+			problem.Invalid(w, *fe)
+			return
+		}
+		t.Mobile, t.State, t.CodeChallenge = true, req.State, req.CodeChallenge
+	default:
+		problem.Invalid(w, problem.Field("client", "must be web or mobile"))
+		return
+	}
+
+	now := time.Now()
+	ip := middlewares.ClientIP(r)
+	if ok, wait := h.emailStartByIP.allow(ip, now); !ok {
+		logging.FromContext(r.Context()).Warn("email login: too many links requested", "ip", ip)
+		tooManyRequests(w, wait)
+		return
+	}
+	if ok, wait := h.emailStartByAddr.allow(email, now); !ok {
+		logging.FromContext(r.Context()).Warn("email login: too many links requested for address", "ip", ip)
+		tooManyRequests(w, wait)
+		return
+	}
+
+	raw, err := generateRandomToken(emailTokenLen)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create login link")
+		return
+	}
+	t.TokenHash = hashSecret(raw)
+	if _, err := h.EmailTokenRepo.CreateEmailToken(r.Context(), t); err != nil {
+		logging.FromContext(r.Context()).Error("email login: CreateEmailToken failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create login link")
+		return
+	}
+
+	link := h.baseURL + "/api/v1/auth/email/verify?token=" + url.QueryEscape(h.signEmailLink(raw, t.ExpiresAt))
+	msg := domains.Message{
+		To:      email,
+		Subject: "Your hextok login link",
+		Text: "Use this link to log in to hextok:\n\n" + link + "\n\n" +
+			fmt.Sprintf("It expires in %d minutes and works once. ", int(emailLinkTTL.Minutes())) +
+			"If you did not ask to log in, you can ignore this email.\n",
+	}
+	if err := h.Mailer.Send(r.Context(), msg); err != nil {
+		logging.FromContext(r.Context()).Error("email login: sending link failed", "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.MailUnavailable, "failed to send login link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "login link sent"})
+}
+
+// VerifyEmailLoginHandler redeems a login link.
+func (h *Handler) VerifyEmailLoginHandler(w http.ResponseWriter, r *http.Request) {
+	raw, err := h.parseEmailLink(r.URL.Query().Get("token"), time.Now())
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("email login: rejected link", "err", err)
+		problem.Write(w, http.StatusUnauthorized, problem.LoginLinkInvalid, "this login link is invalid or has expired")
+		return
+	}
+
+	t, err := h.EmailTokenRepo.RedeemEmailToken(r.Context(), hashSecret(raw), time.Now())
+	switch {
+	case errors.Is(err, domains.ErrEmailTokenUsed):
+		logging.FromContext(r.Context()).Warn("email login: REPLAY of a used login link", "ip", middlewares.ClientIP(r), "user_agent", r.UserAgent())
+		problem.Write(w, http.StatusUnauthorized, problem.LoginLinkUsed, "this login link has already been used")
+		return
+	case errors.Is(err, domains.ErrEmailTokenExpired), errors.Is(err, domains.ErrNotFound):
+		logging.FromContext(r.Context()).Warn("email login: rejected link", "err", err)
+		problem.Write(w, http.StatusUnauthorized, problem.LoginLinkInvalid, "this login link is invalid or has expired")
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("email login: RedeemEmailToken failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to verify login link")
+		return
+	}
+
+	login, _, _ := strings.Cut(t.Email, "@")
+	profile := domains.OAuthProfile{ProviderUserId: t.Email, Login: login, Email: t.Email, EmailVerified: true}
+	userId, err := h.loginUser(r.Context(), domains.ProviderEnum[domains.Email], profile, domains.OAuthToken{})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("email login failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create user")
+		return
+	}
+
+	if t.Mobile {
+		h.redirectWithAuthCode(w, r, userId, t.State, t.CodeChallenge)
+		return
+	}
+	sid, rawTok, err := h.createSession(r, userId)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("email login: CreateSession failed", "user_id", userId, "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create session")
+		return
+	}
+	h.setSessionCookie(w, encodeSessionToken(sid, rawTok))
+	http.Redirect(w, r, h.baseURL+"/", http.StatusFound)
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	problem.Write(w, http.StatusTooManyRequests, problem.TooManyRequests, "too many login links requested, try again later")
+}
+
+// signEmailLink binds the link secret to its expiry, so a link that was
+// tampered with or has expired is turned away before touching the database.
+func (h *Handler) signEmailLink(raw string, expiresAt time.Time) string {
+	v := raw + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	mac := hmac.New(sha256.New, h.stateKey)
+	mac.Write([]byte(emailLinkPurpose + v))
+	return v + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseEmailLink checks a signed link token and returns its secret.
+func (h *Handler) parseEmailLink(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("invalid link format")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("bad signature encoding")
+	}
+	mac := hmac.New(sha256.New, h.stateKey)
+	mac.Write([]byte(emailLinkPurpose + parts[0] + "." + parts[1]))
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return "", errors.New("link signature mismatch")
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", errors.New("bad link expiry")
+	}
+	if !now.Before(time.Unix(exp, 0)) {
+		return "", errors.New("link expired")
+	}
+	return parts[0], nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// outbox records the messages it is asked to send.
+type outbox struct {
+	mu   sync.Mutex
+	msgs []domains.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg domains.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, msg)
+	return nil
+}
+
+// lastLink returns the verify path and query from the last message sent.
+func (o *outbox) lastLink(t *testing.T) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) == 0 {
+		t.Fatal("no message sent")
+	}
+	text := o.msgs[len(o.msgs)-1].Text
+	i := strings.Index(text, "https://api.test")
+	if i < 0 {
+		t.Fatalf("no link in %q", text)
+	}
+	link, _, _ := strings.Cut(text[i+len("https://api.test"):], "\n")
+	return link
+}
+
+// startEmail asks for a login link for email from a client at ip.
+func startEmail(t *testing.T, srv http.Handler, email, ip string) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(EmailStartRequest{Email: email})
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/email/start", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func wantProblem(t *testing.T, resp *http.Response, status int, code string) {
+	t.Helper()
+	var p struct{ Code string }
+	_ = json.NewDecoder(resp.Body).Decode(&p)
+	if resp.StatusCode != status || p.Code != code {
+		t.Errorf("response = %d %q, want %d %s", resp.StatusCode, p.Code, status, code)
+	}
+}
+
+func TestEmailLoginRoundTrip(t *testing.T) {
+	h, _, srv := newTestHandler(t, nil)
+	mail := &outbox{}
+	h.Mailer = mail
+
+	if resp := startEmail(t, srv, "Alice@Example.com", "192.0.2.1"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("start = %d, want 202", resp.StatusCode)
+	}
+	link := mail.lastLink(t)
+
+	resp := do(t, srv, http.MethodGet, link, "")
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("verify = %d, want 302", resp.StatusCode)
+	}
+	var session bool
+	for _, c := range resp.Cookies() {
+		session = session || c.Name == sessionCookieName && c.Value != ""
+	}
+	if !session {
+		t.Error("verify set no session cookie")
+	}
+
+	wantProblem(t, do(t, srv, http.MethodGet, link, ""), http.StatusUnauthorized, "login_link_used")
+}
+
+func TestEmailLoginRejectsExpiredToken(t *testing.T) {
+	h, s, srv := newTestHandler(t, nil)
+	// the stored token has expired though the link's signed expiry has not,
+	// so the store turns it away
+	raw := "expired-secret"
+	if _, err := s.CreateEmailToken(context.Background(), domains.EmailToken{Email: "alice@example.com", TokenHash: hashSecret(raw), ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	link := "/api/v1/auth/email/verify?token=" + url.QueryEscape(h.signEmailLink(raw, time.Now().Add(time.Minute)))
+	wantProblem(t, do(t, srv, http.MethodGet, link, ""), http.StatusUnauthorized, "login_link_invalid")
+
+	unknown := "/api/v1/auth/email/verify?token=" + url.QueryEscape(h.signEmailLink("unknown", time.Now().Add(time.Minute)))
+	wantProblem(t, do(t, srv, http.MethodGet, unknown, ""), http.StatusUnauthorized, "login_link_invalid")
+}
+
+func TestEmailStartRateLimitPerAddress(t *testing.T) {
+	h, _, srv := newTestHandler(t, nil)
+	mail := &outbox{}
+	h.Mailer = mail
+
+	for i := range emailStartPerAddr {
+		// from different clients, so only the address limit applies
+		if resp := startEmail(t, srv, "alice@example.com", fmt.Sprintf("192.0.2.%d", i+1)); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("start %d = %d, want 202", i, resp.StatusCode)
+		}
+	}
+	// the address is compared after normalizing it
+	resp := startEmail(t, srv, "ALICE@example.com", "198.51.100.1")
+	wantProblem(t, resp, http.StatusTooManyRequests, "too_many_requests")
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+	if len(mail.msgs) != emailStartPerAddr {
+		t.Errorf("sent %d messages, want %d", len(mail.msgs), emailStartPerAddr)
+	}
+
+	if resp := startEmail(t, srv, "bob@example.com", "198.51.100.1"); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("start for another address = %d, want 202", resp.StatusCode)
+	}
+}
+
+func TestEmailStartRateLimitPerIP(t *testing.T) {
+	h, _, srv := newTestHandler(t, nil)
+	h.Mailer = &outbox{}
+
+	for i := range emailStartPerIP {
+		if resp := startEmail(t, srv, fmt.Sprintf("user%d@example.com", i), "192.0.2.1"); resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("start %d = %d, want 202", i, resp.StatusCode)
+		}
+	}
+	wantProblem(t, startEmail(t, srv, "new@example.com", "192.0.2.1"), http.StatusTooManyRequests, "too_many_requests")
+
+	if resp := startEmail(t, srv, "new@example.com", "192.0.2.2"); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("start from another client = %d, want 202", resp.StatusCode)
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	l := newRateLimiter(2, time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+
+	for i := range 2 {
+		if ok, _ := l.allow("k", now); !ok {
+			t.Fatalf("event %d refused", i)
+		}
+	}
+	ok, wait := l.allow("k", now.Add(15*time.Second))
+	if ok || wait != 45*time.Second {
+		t.Errorf("over limit = %v, %v; want false, 45s", ok, wait)
+	}
+	if ok, _ := l.allow("other", now); !ok {
+		t.Error("other key refused")
+	}
+
+	if ok, _ := l.allow("k", now.Add(time.Minute)); !ok {
+		t.Error("refused in the next window")
+	}
+	// ended windows are forgotten
+	l.allow("k", now.Add(3*time.Minute))
+	if _, ok := l.windows["other"]; ok {
+		t.Error("ended window was kept")
+	}
+}
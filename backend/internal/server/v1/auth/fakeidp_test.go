@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/fakeidp"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+)
+
+// providerTimeout bounds calls to the stand-in, so a slow-reply fault fails
+// the request quickly.
+const providerTimeout = 500 * time.Millisecond
+
+// idpEnv is the API served over TLS with GitHub pointed at a fakeidp.Server.
+type idpEnv struct {
+	idp   *fakeidp.Server
+	store *memstore.Store
+	api   *httptest.Server
+	// browser keeps cookies and does not follow redirects; idpClient
+	// follows none either, so each hop can be checked.
+	browser   *http.Client
+	idpClient *http.Client
+}
+
+func newIdPEnv(t *testing.T) *idpEnv {
+	t.Helper()
+	idp := fakeidp.New("gh-client", "gh-secret")
+	idp.AddUser(fakeidp.User{ID: 583231, Login: "octocat", Email: "octocat@example.com"})
+	idpSrv := httptest.NewServer(idp)
+	t.Cleanup(idpSrv.Close)
+
+	cfg := config.Default().Auth
+	cfg.StateKey = "test-state-key"
+	cfg.GithubClientID, cfg.GithubClientSecret = "gh-client", "gh-secret"
+	cfg.GithubURL, cfg.GithubAPIURL = idpSrv.URL, idpSrv.URL
+	s := memstore.New()
+	h := NewHandler(s, s, s, s, s, nil, cfg, &http.Client{Timeout: providerTimeout})
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, h)
+
+	api, c := browser(t, http.StripPrefix("/api/v1", mux))
+	h.baseURL = api.URL
+	// the OAuth app's one registered callback
+	idp.CallbackURL = api.URL + "/api/v1/oauth/callback/github"
+
+	idpClient := idpSrv.Client()
+	idpClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &idpEnv{idp: idp, store: s, api: api, browser: c, idpClient: idpClient}
+}
+
+// authorize follows a start redirect to the stand-in and returns where it
+// sends the browser back to.
+func (e *idpEnv) authorize(t *testing.T, start *http.Response) string {
+	t.Helper()
+	if start.StatusCode != http.StatusFound {
+		t.Fatalf("start = %d, want 302", start.StatusCode)
+	}
+	resp := get(t, e.idpClient, start.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize = %d, want 302", resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
+
+func (e *idpEnv) post(t *testing.T, path string, body any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	resp, err := e.browser.Post(e.api.URL+path, "application/json", strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func problemCode(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	var p struct{ Code string }
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	return p.Code
+}
+
+func TestFakeIdPWebLogin(t *testing.T) {
+	e := newIdPEnv(t)
+
+	callback := e.authorize(t, get(t, e.browser, e.api.URL+"/api/v1/oauth/start/github"))
+	resp := get(t, e.browser, callback)
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("callback = %d, want 302", resp.StatusCode)
+	}
+	var session bool
+	for _, ck := range resp.Cookies() {
+		session = session || ck.Name == sessionCookieName && ck.Value != ""
+	}
+	if !session {
+		t.Error("callback set no session cookie")
+	}
+	if _, err := e.store.GetOauthByProviderUserID(t.Context(), "github", "583231"); err != nil {
+		t.Errorf("GitHub identity not stored: %v", err)
+	}
+}
+
+func TestFakeIdPWebLoginTokenEndpointDown(t *testing.T) {
+	e := newIdPEnv(t)
+	e.idp.SetFault(fakeidp.Token, fakeidp.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+
+	callback := e.authorize(t, get(t, e.browser, e.api.URL+"/api/v1/oauth/start/github"))
+	resp := get(t, e.browser, callback)
+	if code := problemCode(t, resp); resp.StatusCode != http.StatusBadGateway || code != "provider_unavailable" {
+		t.Errorf("callback = %d %s, want 502 provider_unavailable", resp.StatusCode, code)
+	}
+}
+
+func TestFakeIdPMobileLogin(t *testing.T) {
+	e := newIdPEnv(t)
+
+	q := url.Values{"client": {"mobile"}, "state": {"app-state"}, "code_challenge": {s256(testVerifier)}, "code_challenge_method": {"S256"}}
+	callback := e.authorize(t, get(t, e.browser, e.api.URL+"/api/v1/oauth/start/github?"+q.Encode()))
+	deep := redirectQuery(t, get(t, e.browser, callback))
+	if deep.Get("state") != "app-state" || deep.Get("token") == "" {
+		t.Fatalf("deep link = %v, want a code and the app state", deep)
+	}
+
+	resp := e.post(t, "/api/v1/oauth/mobile/exchange", map[string]string{"token": deep.Get("token"), "state": "app-state", "code_verifier": testVerifier})
+	var tok MobileTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || resp.StatusCode != http.StatusOK || tok.Token == "" {
+		t.Errorf("exchange = %d %+v, %v", resp.StatusCode, tok, err)
+	}
+}
+
+func TestFakeIdPDeviceLogin(t *testing.T) {
+	e := newIdPEnv(t)
+
+	resp := e.post(t, "/api/v1/oauth/device/start", nil)
+	var start DeviceStartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&start); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("device start = %d, %v", resp.StatusCode, err)
+	}
+	poll := map[string]string{"device_code": start.DeviceCode}
+
+	resp = e.post(t, "/api/v1/oauth/device/poll", poll)
+	if code := problemCode(t, resp); resp.StatusCode != http.StatusBadRequest || code != "authorization_pending" {
+		t.Fatalf("poll before approval = %d %s, want 400 authorization_pending", resp.StatusCode, code)
+	}
+
+	if err := e.idp.ApproveDevice(start.UserCode, "octocat"); err != nil {
+		t.Fatal(err)
+	}
+	resp = e.post(t, "/api/v1/oauth/device/poll", poll)
+	var tok MobileTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || resp.StatusCode != http.StatusOK || tok.Token == "" {
+		t.Errorf("poll after approval = %d %+v, %v", resp.StatusCode, tok, err)
+	}
+}
+
+func TestFakeIdPDeviceLoginSlowProfile(t *testing.T) {
+	e := newIdPEnv(t)
+
+	resp := e.post(t, "/api/v1/oauth/device/start", nil)
+	var start DeviceStartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&start); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.idp.ApproveDevice(start.UserCode, "octocat"); err != nil {
+		t.Fatal(err)
+	}
+	e.idp.SetFault(fakeidp.UserAPI, fakeidp.Fault{Delay: 4 * providerTimeout, Times: 1})
+
+	began := time.Now()
+	resp = e.post(t, "/api/v1/oauth/device/poll", map[string]string{"device_code": start.DeviceCode})
+	if code := problemCode(t, resp); resp.StatusCode != http.StatusBadGateway || code != "provider_unavailable" {
+		t.Errorf("poll = %d %s, want 502 provider_unavailable", resp.StatusCode, code)
+	}
+	if d := time.Since(began); d >= 4*providerTimeout {
+		t.Errorf("poll took %s, want the client timeout to cut it short", d)
+	}
+}
@@ -1,63 +1,81 @@
 package auth
 
 import (
-	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
-	_ "github.com/joho/godotenv"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
 const (
-	stateCookieName   = "hextok_oauth_state"
+	stateCookieName = "hextok_oauth_state"
+	// stateCookiePath covers the callbacks, which are served under /api/v1.
+	stateCookiePath   = "/api/v1/oauth"
 	sessionCookieName = "hextok_session"
 	stateTTL          = 10 * time.Minute
 	sessionTokLen     = 32
 )
 
 type Handler struct {
-	UserRepo     domains.UserRepo
-	OauthRepo    domains.OauthRepo
-	SessionRepo  domains.SessionRepo
-	HTTPClient   *http.Client
-	stateKey     []byte
-	clientID     string
-	clientSecret string
-	baseURL      string
+	UserRepo       domains.UserRepo
+	OauthRepo      domains.OauthRepo
+	SessionRepo    domains.SessionRepo
+	AuthCodeRepo   domains.AuthCodeRepo
+	EmailTokenRepo domains.EmailTokenRepo
+	Mailer         domains.Mailer
+	HTTPClient     *http.Client
+	stateKey       []byte
+	providers      map[string]domains.OAuthProvider
+	baseURL        string
+	requirePKCE    bool
+	// sessionTTL is the absolute session lifetime, used as the cookie MaxAge.
+	sessionTTL     time.Duration
+	authMiddleware func(http.Handler) http.Handler
+	// emailStartByAddr and emailStartByIP limit how many login links can be
+	// requested for one address and from one client.
+	emailStartByAddr *rateLimiter
+	emailStartByIP   *rateLimiter
 }
 
-func NewHandler(u domains.UserRepo, o domains.OauthRepo, s domains.SessionRepo, httpClient *http.Client) *Handler {
+func NewHandler(u domains.UserRepo, o domains.OauthRepo, s domains.SessionRepo, codes domains.AuthCodeRepo, emailTokens domains.EmailTokenRepo, mailer domains.Mailer, cfg config.Auth, httpClient *http.Client) *Handler {
 	if httpClient == nil {
-		httpClient = &http.Client{Timeout: 10 * time.Second}
-	}
-	key := []byte(os.Getenv("OAUTH_STATE_KEY"))
-
-	if os.Getenv("GITHUB_CLIENT_ID") == "" || os.Getenv("GITHUB_CLIENT_SECRET") == "" {
-		fmt.Println("warning: GITHUB_CLIENT_ID or GITHUB_CLIENT_SECRET is not set")
+		// the transport adds a client span and traceparent to provider calls
+		httpClient = &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
 	}
 	return &Handler{
-		UserRepo:     u,
-		OauthRepo:    o,
-		SessionRepo:  s,
-		HTTPClient:   httpClient,
-		stateKey:     key,
-		clientID:     os.Getenv("GITHUB_CLIENT_ID"),
-		clientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-		baseURL:      os.Getenv("BASE_URL"),
+		UserRepo:       u,
+		OauthRepo:      o,
+		SessionRepo:    s,
+		AuthCodeRepo:   codes,
+		EmailTokenRepo: emailTokens,
+		Mailer:         mailer,
+		HTTPClient:     httpClient,
+		stateKey:       []byte(cfg.StateKey),
+		providers:      newProviders(cfg, httpClient),
+		baseURL:        cfg.BaseURL,
+		requirePKCE:    cfg.RequirePKCE,
+
+		sessionTTL:     cfg.Session.AbsoluteTTL,
+		authMiddleware: middlewares.NewAuthMiddleware(s, nil, cfg.Session.Policy()),
+
+		emailStartByAddr: newRateLimiter(emailStartPerAddr, emailStartWindow),
+		emailStartByIP:   newRateLimiter(emailStartPerIP, emailStartWindow),
 	}
 }
 
@@ -82,7 +100,7 @@ func (h *Handler) SetStateCookie(w http.ResponseWriter, state string) {
 		Value:    enc,
 		HttpOnly: true,
 		Secure:   true,
-		Path:     "/oauth",
+		Path:     stateCookiePath,
 		SameSite: http.SameSiteLaxMode,
 		MaxAge:   int(stateTTL.Seconds()),
 	})
@@ -137,158 +155,116 @@ func (h *Handler) ClearStateCookie(w http.ResponseWriter) {
 		Value:    "",
 		HttpOnly: true,
 		Secure:   true,
-		Path:     "/oauth",
+		Path:     stateCookiePath,
 		MaxAge:   -1,
 	})
 }
 
+// StartAuthHandler starts a login with the {provider} in the path. The web
+// flow keeps its state in a cookie; ?client=mobile hands over to the app
+// flow in StartMobileOAuthHandler.
 func (h *Handler) StartAuthHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("client") {
+	case "", "web":
+	case "mobile":
+		h.StartMobileOAuthHandler(w, r)
+		return
+	default:
+		problem.Invalid(w, problem.Field("client", "must be web or mobile"))
+		return
+	}
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
+		return
+	}
 	state, err := GenerateState()
 	if err != nil {
-		http.Error(w, "unable to create state", http.StatusInternalServerError)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "unable to create state")
+		return
+	}
+	redir, err := p.AuthCodeURL(r.Context(), state, h.callbackURL(p.Name()))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("oauth: authorize URL failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
 		return
 	}
 	h.SetStateCookie(w, state)
-	redir := fmt.Sprintf("https://github.com/login/oauth/authorize?client_id=%s&state=%s", h.clientID, state)
 	http.Redirect(w, r, redir, http.StatusFound)
 }
 
-func (h *Handler) GithubOAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+// OAuthCallbackHandler completes the web login for the {provider} in the
+// path and sets the session cookie.
+func (h *Handler) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Write(w, http.StatusMethodNotAllowed, problem.MethodNotAllowed, "method not allowed")
+		return
+	}
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
 		return
 	}
 	q := r.URL.Query()
 	code := q.Get("code")
 	state := q.Get("state")
 	if code == "" || state == "" {
-		http.Error(w, "missing code or state", http.StatusBadRequest)
+		problem.Invalid(w, missingCallbackParams(code, state)...)
 		return
 	}
 
 	if err := h.ValidateState(r, state); err != nil {
 		h.ClearStateCookie(w)
 
-		fmt.Printf("oauth: state validation failed: %v\n", err)
-		http.Error(w, "invalid state", http.StatusForbidden)
+		logging.FromContext(r.Context()).Warn("oauth: state validation failed", "err", err)
+		problem.Write(w, http.StatusForbidden, problem.InvalidState, "invalid state")
 		return
 	}
 	h.ClearStateCookie(w)
 
-	accessToken, err := h.exchangeCodeForToken(r.Context(), code)
+	tok, err := p.Exchange(r.Context(), code, state, h.callbackURL(p.Name()))
 	if err != nil {
-		fmt.Printf("oauth: token exchange failed: %v\n", err)
-		http.Error(w, "token exchange failed", http.StatusBadGateway)
+		logging.FromContext(r.Context()).Error("oauth: token exchange failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "token exchange failed")
 		return
 	}
 
-	ghID, ghLogin, err := h.fetchGithubUser(r.Context(), accessToken)
+	profile, err := p.Profile(r.Context(), tok)
 	if err != nil {
-		http.Error(w, "failed to fetch github user", http.StatusBadGateway)
+		logging.FromContext(r.Context()).Error("oauth: profile fetch failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "failed to fetch user profile")
 		return
 	}
-	provider := "github"
 
-	oauthRow, err := h.OauthRepo.GetOauthByProviderUserID(r.Context(), provider, ghID)
-	var userId int64
-	if err == nil {
-		userId = oauthRow.UserId
-	} else {
-
-		uid, err := h.UserRepo.CreateUser(r.Context(), ghLogin)
-		if err != nil {
-			fmt.Printf("oauth: CreateUser failed: %v\n", err)
-			http.Error(w, "failed to create user", http.StatusInternalServerError)
-			return
-		}
-		userId = uid
-		if _, err := h.OauthRepo.CreateOauth(r.Context(), userId, provider, ghID, accessToken, ""); err != nil {
-			fmt.Printf("oauth: CreateOauth failed: %v\n", err)
-
-			http.Error(w, "failed to create oauth row", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	rawTok, err := generateRandomToken(sessionTokLen)
+	userId, err := h.loginUser(r.Context(), p.Name(), profile, tok)
 	if err != nil {
-		http.Error(w, "failed to create session token", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("oauth: login failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create user")
 		return
 	}
-	sum := sha256.Sum256([]byte(rawTok))
-	hash := base64.StdEncoding.EncodeToString(sum[:])
 
-	sid, err := h.SessionRepo.CreateSession(r.Context(), userId, hash)
+	sid, rawTok, err := h.createSession(r, userId)
 	if err != nil {
-		fmt.Printf("oauth: CreateSession failed for user %d: %v\n", userId, err)
-		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("oauth: CreateSession failed", "user_id", userId, "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create session")
 		return
 	}
-
-	sessionVal := fmt.Sprintf("%d|%s", sid, rawTok)
-	enc := base64.RawURLEncoding.EncodeToString([]byte(sessionVal))
-	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    enc,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   60 * 60 * 24 * 30,
-	})
+	h.setSessionCookie(w, encodeSessionToken(sid, rawTok))
 
 	http.Redirect(w, r, h.baseURL+"/", http.StatusFound)
 }
 
-func (h *Handler) exchangeCodeForToken(ctx context.Context, code string) (string, error) {
-	reqBody := fmt.Sprintf("client_id=%s&client_secret=%s&code=%s", h.clientID, h.clientSecret, code)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "https://github.com/login/oauth/access_token", strings.NewReader(reqBody))
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := h.HTTPClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("github token endpoint: %s", string(b))
-	}
-	var out struct {
-		AccessToken string `json:"access_token"`
-		TokenType   string `json:"token_type"`
-		Scope       string `json:"scope"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", err
-	}
-	if out.AccessToken == "" {
-		return "", errors.New("no access token returned")
-	}
-	return out.AccessToken, nil
-}
-
-func (h *Handler) fetchGithubUser(ctx context.Context, accessToken string) (providerUserID, login string, err error) {
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
-	req.Header.Set("Authorization", "token "+accessToken)
-	req.Header.Set("Accept", "application/json")
-	resp, err := h.HTTPClient.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("github user endpoint: %s", string(b))
-	}
-	var u struct {
-		ID    int64  `json:"id"`
-		Login string `json:"login"`
+// missingCallbackParams lists which of a provider callback's code and state
+// parameters are empty.
+func missingCallbackParams(code, state string) []schema.FieldError {
+	var errs []schema.FieldError
+	if code == "" {
+		errs = append(errs, problem.Field("code", "is required"))
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
-		return "", "", err
+	if state == "" {
+		errs = append(errs, problem.Field("state", "is required"))
 	}
-	return strconv.FormatInt(u.ID, 10), u.Login, nil
+	return errs
 }
 
 func generateRandomToken(n int) (string, error) {
@@ -299,33 +275,14 @@ func generateRandomToken(n int) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+// LogoutHandler ends the session that authenticated the request, whether
+// it came from the cookie or the Authorization header, and clears the cookie.
 func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := r.Cookie(sessionCookieName)
-	if err != nil {
-
-		http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	raw, err := base64.RawURLEncoding.DecodeString(c.Value)
-	if err != nil {
-		http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	parts := strings.SplitN(string(raw), "|", 2)
-	if len(parts) != 2 {
-		http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-	sid, err := strconv.ParseInt(parts[0], 10, 64)
-	if err == nil {
-
-		_ = h.SessionRepo.DeleteSession(r.Context(), sid)
+	if sid, ok := middlewares.GetAuthedSessionID(r.Context()); ok {
+		if err := h.SessionRepo.DeleteSession(r.Context(), sid); err != nil {
+			logging.FromContext(r.Context()).Error("logout: DeleteSession failed", "session_id", sid, "err", err)
+		}
 	}
-
 	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+)
+
+// fakeProvider logs everyone in as the same identity. Its authorize URL
+// carries the state back, like a provider redirecting to the callback.
+type fakeProvider struct{}
+
+const fakeProviderName = "fake"
+
+func (fakeProvider) Name() string { return fakeProviderName }
+
+func (fakeProvider) AuthCodeURL(ctx context.Context, state, redirectURI string) (string, error) {
+	return "https://idp.test/authorize?" + url.Values{"state": {state}, "redirect_uri": {redirectURI}}.Encode(), nil
+}
+
+func (fakeProvider) Exchange(ctx context.Context, code, state, redirectURI string) (domains.OAuthToken, error) {
+	if code != "good-code" {
+		return domains.OAuthToken{}, errors.New("bad code")
+	}
+	return domains.OAuthToken{AccessToken: "provider-access"}, nil
+}
+
+func (fakeProvider) Profile(ctx context.Context, tok domains.OAuthToken) (domains.OAuthProfile, error) {
+	return domains.OAuthProfile{ProviderUserId: "fake-1", Login: "alice"}, nil
+}
+
+// newTestHandler returns a handler over an empty memstore with
+// fakeProvider as its only provider, serving RegisterRoutes under
+// /api/v1 like the API does.
+func newTestHandler(t *testing.T, mod func(*config.Auth)) (*Handler, *memstore.Store, http.Handler) {
+	t.Helper()
+	cfg := config.Default().Auth
+	cfg.BaseURL = "https://api.test"
+	cfg.StateKey = "test-state-key"
+	if mod != nil {
+		mod(&cfg)
+	}
+	s := memstore.New()
+	h := NewHandler(s, s, s, s, s, nil, cfg, nil)
+	h.providers = map[string]domains.OAuthProvider{fakeProviderName: fakeProvider{}}
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, h)
+	return h, s, http.StripPrefix("/api/v1", mux)
+}
+
+// do sends a request through srv, adding cookies, and returns the
+// response.
+func do(t *testing.T, srv http.Handler, method, target, body string, cookies ...*http.Cookie) *http.Response {
+	t.Helper()
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	return w.Result()
+}
+
+// redirectQuery returns the query of the Location a response redirects to.
+func redirectQuery(t *testing.T, resp *http.Response) url.Values {
+	t.Helper()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want 302", resp.StatusCode)
+	}
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Location: %v", err)
+	}
+	return u.Query()
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+)
+
+// Account linking adds another provider identity to the logged-in user
+// instead of logging in with it. The flow is the web one with a signed state
+// that names the user, and the callback only links when the same user is
+// still logged in, so a victim cannot be tricked into completing someone
+// else's link.
+
+const linkStatePrefix = "link_"
+
+type linkState struct {
+	Provider string `json:"p"`
+	UserId   int64  `json:"u"`
+	// Nonce keeps every state unique; the state cookie carries the same
+	// value.
+	Nonce    string `json:"n"`
+	IssuedAt int64  `json:"t"`
+}
+
+// StartLinkHandler starts linking the {provider} in the path to the caller.
+func (h *Handler) StartLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middlewares.GetAuthedUserID(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
+		return
+	}
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
+		return
+	}
+	nonce, err := GenerateState()
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "unable to create state")
+		return
+	}
+	state, err := h.signState(linkStatePrefix, linkState{
+		Provider: p.Name(),
+		UserId:   userId,
+		Nonce:    nonce,
+		IssuedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "unable to create state")
+		return
+	}
+	redir, err := p.AuthCodeURL(r.Context(), state, h.callbackURL(p.Name()))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("oauth link: authorize URL failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
+		return
+	}
+	h.SetStateCookie(w, state)
+	http.Redirect(w, r, redir, http.StatusFound)
+}
+
+// LinkCallbackHandler completes a link started by StartLinkHandler. It runs
+// behind the auth middleware.
+func (h *Handler) LinkCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
+		return
+	}
+	q := r.URL.Query()
+	code := q.Get("code")
+	state := q.Get("state")
+	if code == "" || state == "" {
+		problem.Invalid(w, missingCallbackParams(code, state)...)
+		return
+	}
+
+	// the cookie check gives the state its expiry and binds it to this
+	// browser; the signed payload says who asked for the link
+	err := h.ValidateState(r, state)
+	h.ClearStateCookie(w)
+	var st linkState
+	if err == nil {
+		err = h.parseState(linkStatePrefix, state, &st)
+	}
+	if err == nil && st.Provider != p.Name() {
+		err = fmt.Errorf("state was issued for %q", st.Provider)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("oauth link: state validation failed", "err", err)
+		problem.Write(w, http.StatusForbidden, problem.InvalidState, "invalid state")
+		return
+	}
+	if userId, _ := middlewares.GetAuthedUserID(r.Context()); userId != st.UserId {
+		logging.FromContext(r.Context()).Warn("oauth link: completed by another user", "started_by", st.UserId)
+		problem.Write(w, http.StatusForbidden, problem.LinkUserMismatch, "link was started by another user")
+		return
+	}
+
+	tok, err := p.Exchange(r.Context(), code, state, h.callbackURL(p.Name()))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("oauth link: token exchange failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "token exchange failed")
+		return
+	}
+	profile, err := p.Profile(r.Context(), tok)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("oauth link: profile fetch failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "failed to fetch user profile")
+		return
+	}
+
+	existing, err := h.OauthRepo.GetOauthByProviderUserID(r.Context(), p.Name(), profile.ProviderUserId)
+	switch {
+	case err == nil && existing.UserId == st.UserId:
+		// already linked; nothing to do
+	case err == nil:
+		problem.Write(w, http.StatusConflict, problem.IdentityInUse, "this "+p.Name()+" account is linked to another user")
+		return
+	case !errors.Is(err, domains.ErrNotFound):
+		logging.FromContext(r.Context()).Error("oauth link: GetOauthByProviderUserID failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to link account")
+		return
+	default:
+		_, err := h.OauthRepo.CreateOauth(r.Context(), st.UserId, p.Name(), profile.ProviderUserId, tok.AccessToken, tok.RefreshToken)
+		if errors.Is(err, domains.ErrAlreadyExists) {
+			problem.Write(w, http.StatusConflict, problem.IdentityInUse, "this "+p.Name()+" account is linked to another user")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("oauth link: CreateOauth failed", "err", err)
+			problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to link account")
+			return
+		}
+		logging.FromContext(r.Context()).Info("oauth link: identity linked", "provider", p.Name())
+	}
+
+	http.Redirect(w, r, h.baseURL+"/", http.StatusFound)
+}
+
+// isLinkState reports whether a callback belongs to the link flow.
+func isLinkState(state string) bool {
+	return strings.HasPrefix(state, linkStatePrefix)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// browser is a client with a cookie jar that does not follow redirects, so
+// cookies are scoped by path the way a browser scopes them.
+func browser(t *testing.T, srv http.Handler) (*httptest.Server, *http.Client) {
+	t.Helper()
+	ts := httptest.NewTLSServer(srv)
+	t.Cleanup(ts.Close)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := ts.Client()
+	c.Jar = jar
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return ts, c
+}
+
+func get(t *testing.T, c *http.Client, target string) *http.Response {
+	t.Helper()
+	resp, err := c.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// providerRedirect follows the fake provider's authorize URL back to our
+// callback with a code, as the provider would.
+func providerRedirect(t *testing.T, ts *httptest.Server, resp *http.Response) string {
+	t.Helper()
+	q := redirectQuery(t, resp)
+	return ts.URL + "/api/v1/oauth/callback/fake?" + url.Values{"code": {"good-code"}, "state": {q.Get("state")}}.Encode()
+}
+
+func TestStateCookieScopedToCallbacks(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+	resp := do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake", "")
+	for _, c := range resp.Cookies() {
+		if c.Name == stateCookieName {
+			if c.Path != "/api/v1/oauth" {
+				t.Errorf("state cookie path = %q, want /api/v1/oauth", c.Path)
+			}
+			return
+		}
+	}
+	t.Fatal("start set no state cookie")
+}
+
+func TestWebLoginRoundTrip(t *testing.T) {
+	_, s, srv := newTestHandler(t, nil)
+	ts, c := browser(t, srv)
+
+	resp := get(t, c, providerRedirect(t, ts, get(t, c, ts.URL+"/api/v1/oauth/start/fake")))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("callback = %d, want 302", resp.StatusCode)
+	}
+	var session bool
+	for _, ck := range resp.Cookies() {
+		session = session || ck.Name == sessionCookieName && ck.Value != ""
+	}
+	if !session {
+		t.Error("callback set no session cookie")
+	}
+	if _, err := s.GetOauthByProviderUserID(context.Background(), fakeProviderName, "fake-1"); err != nil {
+		t.Errorf("identity not stored: %v", err)
+	}
+
+	// the state cookie was cleared, so the callback cannot be replayed
+	u, _ := url.Parse(ts.URL + "/api/v1/oauth/callback/fake")
+	for _, ck := range c.Jar.Cookies(u) {
+		if ck.Name == stateCookieName {
+			t.Errorf("state cookie still set after the callback")
+		}
+	}
+}
+
+func TestLinkRoundTrip(t *testing.T) {
+	h, s, srv := newTestHandler(t, nil)
+	ts, c := browser(t, srv)
+	ctx := context.Background()
+
+	// alice is logged in with another provider
+	alice, err := s.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateOauth(ctx, alice, "github", "gh-1", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	sid, err := s.CreateSession(ctx, alice, hashSecret("raw"), "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(ts.URL)
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: sessionCookieName, Value: encodeSessionToken(sid, "raw"), Path: "/", Secure: true}})
+
+	start := get(t, c, ts.URL+"/api/v1/oauth/link/fake")
+	if start.StatusCode != http.StatusFound {
+		t.Fatalf("link start = %d, want 302", start.StatusCode)
+	}
+	resp := get(t, c, providerRedirect(t, ts, start))
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != h.baseURL+"/" {
+		t.Fatalf("link callback = %d to %q, want 302 to %s/", resp.StatusCode, resp.Header.Get("Location"), h.baseURL)
+	}
+	o, err := s.GetOauthByProviderUserID(ctx, fakeProviderName, "fake-1")
+	if err != nil || o.UserId != alice {
+		t.Errorf("linked identity = %+v, %v; want it on user %d", o, err, alice)
+	}
+}
+
+func TestLinkCallbackWithoutStateCookie(t *testing.T) {
+	_, s, srv := newTestHandler(t, nil)
+	ts, c := browser(t, srv)
+	ctx := context.Background()
+
+	alice, _ := s.CreateUser(ctx, "alice")
+	sid, _ := s.CreateSession(ctx, alice, hashSecret("raw"), "test", "127.0.0.1")
+	u, _ := url.Parse(ts.URL)
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: sessionCookieName, Value: encodeSessionToken(sid, "raw"), Path: "/", Secure: true}})
+
+	callback := providerRedirect(t, ts, get(t, c, ts.URL+"/api/v1/oauth/link/fake"))
+	// a link started in another browser has no state cookie here
+	c.Jar, _ = cookiejar.New(nil)
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: sessionCookieName, Value: encodeSessionToken(sid, "raw"), Path: "/", Secure: true}})
+	if resp := get(t, c, callback); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("callback without state cookie = %d, want 403", resp.StatusCode)
+	}
+}
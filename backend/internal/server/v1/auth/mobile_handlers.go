@@ -1,14 +1,20 @@
 package auth
 
 import (
-	"crypto/sha256"
-	"encoding/base64"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
 // Mobile OAuth flow handlers
@@ -37,9 +43,16 @@ type MobileTokenResponse struct {
 	SessionID int64  `json:"session_id"`
 }
 
-// StartMobileOAuthHandler initiates OAuth flow for mobile apps
-// This creates a state and redirects to GitHub with mobile-specific redirect URI
+// StartMobileOAuthHandler initiates OAuth flow for mobile apps, served as
+// /oauth/start/{provider}?client=mobile. It signs the app's state into the
+// provider state and redirects to the {provider} in the path.
 func (h *Handler) StartMobileOAuthHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
+		return
+	}
+
 	// Get redirect URI from query params (mobile app will provide this)
 	redirectURI := r.URL.Query().Get("redirect_uri")
 	if redirectURI == "" {
@@ -49,8 +62,8 @@ func (h *Handler) StartMobileOAuthHandler(w http.ResponseWriter, r *http.Request
 	// Validate redirect URI format for security - allow hextok scheme
 	parsedURI, err := url.Parse(redirectURI)
 	if err != nil || parsedURI.Scheme != "hextok" {
-		fmt.Printf("Mobile OAuth: invalid redirect URI format: %s, scheme: %s\n", redirectURI, parsedURI.Scheme)
-		http.Error(w, "invalid redirect URI - must use hextok:// scheme", http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("mobile oauth: invalid redirect URI", "redirect_uri", redirectURI)
+		problem.Invalid(w, problem.Field("redirect_uri", "must use the hextok:// scheme"))
 		return
 	}
 
@@ -58,270 +71,258 @@ func (h *Handler) StartMobileOAuthHandler(w http.ResponseWriter, r *http.Request
 	// stateless for mobile flows and avoids relying on cookies in the in-app browser.
 	state := r.URL.Query().Get("state")
 	if state == "" {
-		fmt.Printf("Mobile OAuth: missing state in request from %s\n", r.RemoteAddr)
-		http.Error(w, "state parameter is required from the mobile client", http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("mobile oauth: start without state")
+		problem.Invalid(w, problem.Field("state", "is required from the mobile client"))
+		return
+	}
+
+	// Optional PKCE. The challenge travels inside the signed state and is
+	// stored with the auth code, so only the app holding the verifier can
+	// redeem it.
+	challenge := r.URL.Query().Get("code_challenge")
+	if fe := h.checkCodeChallenge(challenge, r.URL.Query().Get("code_challenge_method")); fe != nil {
+		logging.FromContext(r.Context()).Warn("mobile oauth: rejected start", "field", fe.Field, "reason", fe.Detail)
+		problem.Invalid(w, *fe)
 		return
 	}
 
-	// Validate redirect URI format for security - already done above. We'll
-	// use the provided state directly and mark it as mobile by prefixing
-	// when sending to GitHub so the unified callback can route appropriately.
-	mobileState := "mobile_" + state
-
-	// Build GitHub redirect URL and pass through the client's state.
-	// Use the mobile-specific callback path so it matches the callback URL
-	// configured in the OAuth app (e.g. /api/v1/oauth/mobile/callback).
-	githubRedirectURL := fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&state=%s&redirect_uri=%s",
-		h.clientID,
-		url.QueryEscape(mobileState),
-		url.QueryEscape(h.baseURL+"/api/v1/oauth/mobile/callback"),
-	)
-
-	// Log for debugging - include some request context and headers of interest
-	fmt.Printf("Mobile OAuth START from %s: redirect_uri=%s, state=%s, user_agent=%s, referer=%s\n",
-		r.RemoteAddr, redirectURI, state, r.UserAgent(), r.Referer())
-	// Optionally log the accept header
-	fmt.Printf("Mobile OAuth START headers: Accept=%s\n", r.Header.Get("Accept"))
-
-	// Log the full GitHub authorize URL so you can match it against the GitHub app settings
-	fmt.Printf("Mobile OAuth START: GitHub authorize URL: %s\n", githubRedirectURL)
-
-	http.Redirect(w, r, githubRedirectURL, http.StatusFound)
+	// The client's state and challenge are signed into the state we send to
+	// the provider; the prefix lets the unified callback route it as mobile.
+	mobileState, err := h.signMobileState(mobileState{
+		Provider:      p.Name(),
+		State:         state,
+		CodeChallenge: challenge,
+		IssuedAt:      time.Now().Unix(),
+	})
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "unable to create state")
+		return
+	}
+
+	authURL, err := p.AuthCodeURL(r.Context(), mobileState, h.callbackURL(p.Name()))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("mobile oauth: authorize URL failed", "provider", p.Name(), "err", err)
+		problem.Write(w, http.StatusBadGateway, problem.ProviderUnavailable, "provider unavailable")
+		return
+	}
+
+	// The state and the authorize URL are not logged: together they are
+	// enough to complete someone else's login.
+	logging.FromContext(r.Context()).Info("mobile oauth: started", "provider", p.Name(), "redirect_uri", redirectURI, "pkce", challenge != "")
+
+	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
-// MobileOAuthCallbackHandler handles the GitHub callback for mobile OAuth
+// MobileOAuthCallbackHandler handles the provider callback for mobile OAuth
 // This exchanges the code for tokens and creates a temporary mobile token
 func (h *Handler) MobileOAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Write(w, http.StatusMethodNotAllowed, problem.MethodNotAllowed, "method not allowed")
+		return
+	}
+	p, ok := h.provider(r)
+	if !ok {
+		problem.Write(w, http.StatusNotFound, problem.UnknownProvider, "unknown provider")
 		return
 	}
 
 	q := r.URL.Query()
 	code := q.Get("code")
 	state := q.Get("state")
-	fmt.Printf("Mobile OAuth CALLBACK from %s: raw_query=%s\n", r.RemoteAddr, r.URL.RawQuery)
 	if code == "" || state == "" {
-		fmt.Printf("Mobile OAuth: missing code or state in callback from %s\n", r.RemoteAddr)
-		http.Error(w, "missing code or state", http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("mobile oauth: callback without code or state")
+		problem.Invalid(w, missingCallbackParams(code, state)...)
 		return
 	}
 
-	// Trim the mobile_ prefix so `state` contains the original client state
-	clientState := strings.TrimPrefix(state, "mobile_")
-	fmt.Printf("Mobile OAuth: callback received code=<redacted> state=%s clientState=%s\n", state, clientState)
+	st, err := h.parseMobileState(state)
+	if err == nil && st.Provider != p.Name() {
+		err = fmt.Errorf("state was issued for %q", st.Provider)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("mobile oauth: state validation failed", "err", err)
+		errorURL := fmt.Sprintf("hextok://oauth/callback?error=invalid_state&error_description=%s",
+			url.QueryEscape("Login request is invalid or has expired"))
+		http.Redirect(w, r, errorURL, http.StatusFound)
+		return
+	}
+	clientState := st.State
+	logging.FromContext(r.Context()).Info("mobile oauth: callback", "provider", p.Name(), "pkce", st.CodeChallenge != "")
 
-	accessToken, err := h.exchangeCodeForToken(r.Context(), code)
+	tok, err := p.Exchange(r.Context(), code, state, h.callbackURL(p.Name()))
 	if err != nil {
-		fmt.Printf("Mobile OAuth: token exchange failed for code from %s: %v\n", r.RemoteAddr, err)
+		logging.FromContext(r.Context()).Error("mobile oauth: token exchange failed", "provider", p.Name(), "err", err)
 		errorURL := fmt.Sprintf("hextok://oauth/callback?error=token_exchange&error_description=%s",
 			url.QueryEscape("Failed to exchange code for token"))
 		http.Redirect(w, r, errorURL, http.StatusFound)
 		return
 	}
 
-	// Get GitHub user info
-	ghID, ghLogin, err := h.fetchGithubUser(r.Context(), accessToken)
+	profile, err := p.Profile(r.Context(), tok)
 	if err != nil {
-		fmt.Printf("Mobile OAuth: failed to fetch GitHub user for access token from %s: %v\n", r.RemoteAddr, err)
+		logging.FromContext(r.Context()).Error("mobile oauth: profile fetch failed", "provider", p.Name(), "err", err)
 		errorURL := fmt.Sprintf("hextok://oauth/callback?error=user_fetch&error_description=%s",
 			url.QueryEscape("Failed to fetch user information"))
 		http.Redirect(w, r, errorURL, http.StatusFound)
 		return
 	}
-	fmt.Printf("Mobile OAuth: GitHub user fetched: provider_user_id=%s login=%s\n", ghID, ghLogin)
-
-	provider := "github"
-
-	// Find or create user
-	oauthRow, err := h.OauthRepo.GetOauthByProviderUserID(r.Context(), provider, ghID)
-	var userID int64
-	if err == nil {
-		userID = oauthRow.UserId
-		fmt.Printf("Mobile OAuth: found existing oauth row for provider_user_id=%s user_id=%d\n", ghID, userID)
-	} else {
-		// Create new user
-		uid, err := h.UserRepo.CreateUser(r.Context(), ghLogin)
-		if err != nil {
-			fmt.Printf("Mobile OAuth: CreateUser failed: %v\n", err)
-			errorURL := fmt.Sprintf("hextok://oauth/callback?error=user_creation&error_description=%s",
-				url.QueryEscape("Failed to create user"))
-			http.Redirect(w, r, errorURL, http.StatusFound)
-			return
-		}
-		userID = uid
-		fmt.Printf("Mobile OAuth: created new user %d for gh login %s\n", userID, ghLogin)
-
-		// Create OAuth record
-		if _, err := h.OauthRepo.CreateOauth(r.Context(), userID, provider, ghID, accessToken, ""); err != nil {
-			fmt.Printf("Mobile OAuth: CreateOauth failed for user %d provider_user_id=%s: %v\n", userID, ghID, err)
-			errorURL := fmt.Sprintf("hextok://oauth/callback?error=oauth_creation&error_description=%s",
-				url.QueryEscape("Failed to create OAuth record"))
-			http.Redirect(w, r, errorURL, http.StatusFound)
-			return
-		}
-		fmt.Printf("Mobile OAuth: created oauth row for user %d provider_user_id=%s\n", userID, ghID)
-	}
+	logging.FromContext(r.Context()).Info("mobile oauth: profile fetched", "provider", p.Name(), "provider_user_id", profile.ProviderUserId, "login", profile.Login)
 
-	// Create session
-	rawTok, err := generateRandomToken(sessionTokLen)
+	userID, err := h.loginUser(r.Context(), p.Name(), profile, tok)
 	if err != nil {
-		errorURL := fmt.Sprintf("hextok://oauth/callback?error=session_token&error_description=%s",
-			url.QueryEscape("Failed to create session token"))
+		logging.FromContext(r.Context()).Error("mobile oauth: login failed", "provider", p.Name(), "err", err)
+		errorURL := fmt.Sprintf("hextok://oauth/callback?error=user_creation&error_description=%s",
+			url.QueryEscape("Failed to create user"))
 		http.Redirect(w, r, errorURL, http.StatusFound)
 		return
 	}
 
-	// Hash the token for storage
-	sum := sha256.Sum256([]byte(rawTok))
-	hash := base64.StdEncoding.EncodeToString(sum[:])
+	h.redirectWithAuthCode(w, r, userID, clientState, st.CodeChallenge)
+}
 
-	sessionID, err := h.SessionRepo.CreateSession(r.Context(), userID, hash)
+// redirectWithAuthCode finishes a mobile login by deep linking back to the
+// app with a one-time code rather than a session. The session is only
+// created when the code is redeemed together with the state the app started
+// the flow with, so a leaked deep link is useless on its own.
+func (h *Handler) redirectWithAuthCode(w http.ResponseWriter, r *http.Request, userID int64, clientState, codeChallenge string) {
+	authCode, err := generateRandomToken(mobileTokenLen)
 	if err != nil {
-		fmt.Printf("Mobile OAuth: CreateSession failed for user %d: %v\n", userID, err)
-		errorURL := fmt.Sprintf("hextok://oauth/callback?error=session_creation&error_description=%s",
-			url.QueryEscape("Failed to create session"))
+		errorURL := fmt.Sprintf("hextok://oauth/callback?error=mobile_token&error_description=%s",
+			url.QueryEscape("Failed to create mobile token"))
 		http.Redirect(w, r, errorURL, http.StatusFound)
 		return
 	}
-	fmt.Printf("Mobile OAuth: created session %d for user %d\n", sessionID, userID)
-
-	// Generate mobile token for token exchange (for future use)
-	_, err = generateRandomToken(mobileTokenLen)
-	if err != nil {
+	if _, err := h.AuthCodeRepo.CreateAuthCode(r.Context(), userID, hashSecret(authCode), clientState, codeChallenge, time.Now().Add(mobileTokenTTL)); err != nil {
+		logging.FromContext(r.Context()).Error("mobile oauth: CreateAuthCode failed", "user_id", userID, "err", err)
 		errorURL := fmt.Sprintf("hextok://oauth/callback?error=mobile_token&error_description=%s",
 			url.QueryEscape("Failed to create mobile token"))
 		http.Redirect(w, r, errorURL, http.StatusFound)
 		return
 	}
 
-	// Store mobile token temporarily (you'd use Redis in production)
-	// For now, we'll encode the session info in the token itself
-	tokenData := map[string]interface{}{
-		"user_id":    userID,
-		"session_id": sessionID,
-		"raw_token":  rawTok,
-		"expires_at": time.Now().Add(mobileTokenTTL).Unix(),
-	}
-
-	tokenDataJSON, _ := json.Marshal(tokenData)
-	encodedToken := base64.URLEncoding.EncodeToString(tokenDataJSON)
-
-	fmt.Printf("Mobile OAuth: Success for user %d, session %d - encoded_token_len=%d\n", userID, sessionID, len(encodedToken))
+	logging.FromContext(r.Context()).Info("mobile oauth: issued auth code", "user_id", userID)
 
 	// Build the simple deep link to return to the mobile app
 	successURL := fmt.Sprintf("hextok://oauth/callback?token=%s&user_id=%d&expires_in=%d&state=%s",
-		url.QueryEscape(encodedToken), userID, int(mobileTokenTTL.Seconds()), url.QueryEscape(clientState))
-
-	fmt.Printf("Mobile OAuth: Redirecting to deep link %s\n", successURL)
+		url.QueryEscape(authCode), userID, int(mobileTokenTTL.Seconds()), url.QueryEscape(clientState))
 
 	// Simple redirect to the deep link
 	http.Redirect(w, r, successURL, http.StatusFound)
 }
 
-// UnifiedOAuthCallbackHandler handles both web and mobile OAuth callbacks
-// Detects the source by checking if state has "mobile_" prefix
+// UnifiedOAuthCallbackHandler handles web, mobile and account linking
+// callbacks
+// Detects the source by checking if state has a "mobile_" or "link_" prefix
 func (h *Handler) UnifiedOAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 
 	// Check if this is a mobile OAuth flow by looking at state prefix
-	if strings.HasPrefix(state, "mobile_") {
+	if strings.HasPrefix(state, mobileStatePrefix) {
 		// Handle as mobile OAuth
 		h.MobileOAuthCallbackHandler(w, r)
+	} else if isLinkState(state) {
+		// Linking needs the logged-in user
+		h.authMiddleware(http.HandlerFunc(h.LinkCallbackHandler)).ServeHTTP(w, r)
 	} else {
 		// Handle as web OAuth
-		h.GithubOAuthCallbackHandler(w, r)
+		h.OAuthCallbackHandler(w, r)
 	}
 }
 
-// ExchangeMobileTokenHandler exchanges mobile token for session cookie
-// Mobile app calls this endpoint with the token to get proper session
+// ExchangeMobileTokenHandler redeems the one-time code from the mobile
+// callback for a session. The code must come with the state the app started
+// the flow with, plus the PKCE code_verifier when the flow used one, and can
+// be redeemed exactly once; a second attempt is logged as a replay.
 func (h *Handler) ExchangeMobileTokenHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		problem.Write(w, http.StatusMethodNotAllowed, problem.MethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		State        string `json:"state"`
+		CodeVerifier string `json:"code_verifier"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		fmt.Printf("Mobile OAuth Exchange: invalid request body from %s: %v\n", r.RemoteAddr, err)
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		logging.FromContext(r.Context()).Warn("mobile exchange: invalid request body", "err", err)
+		problem.Write(w, http.StatusBadRequest, problem.InvalidBody, "invalid request body")
 		return
 	}
 
-	if req.Token == "" {
-		fmt.Printf("Mobile OAuth Exchange: missing token in request from %s\n", r.RemoteAddr)
-		http.Error(w, "token is required", http.StatusBadRequest)
+	if req.Token == "" || req.State == "" {
+		logging.FromContext(r.Context()).Warn("mobile exchange: missing token or state")
+		var errs []schema.FieldError
+		if req.Token == "" {
+			errs = append(errs, problem.Field("token", "is required"))
+		}
+		if req.State == "" {
+			errs = append(errs, problem.Field("state", "is required"))
+		}
+		problem.Invalid(w, errs...)
 		return
 	}
 
-	// Decode token
-	tokenData, err := base64.URLEncoding.DecodeString(req.Token)
-	if err != nil {
-		fmt.Printf("Mobile OAuth Exchange: invalid token format from %s: %v\n", r.RemoteAddr, err)
-		http.Error(w, "invalid token format", http.StatusBadRequest)
+	code, err := h.AuthCodeRepo.RedeemAuthCode(r.Context(), hashSecret(req.Token), time.Now())
+	switch {
+	case errors.Is(err, domains.ErrAuthCodeUsed):
+		logging.FromContext(r.Context()).Warn("mobile exchange: REPLAY of a used auth code", "ip", middlewares.ClientIP(r), "user_agent", r.UserAgent())
+		problem.Write(w, http.StatusUnauthorized, problem.InvalidExchangeToken, "invalid or expired token")
+		return
+	case errors.Is(err, domains.ErrAuthCodeExpired), errors.Is(err, domains.ErrNotFound):
+		logging.FromContext(r.Context()).Warn("mobile exchange: rejected auth code", "err", err)
+		problem.Write(w, http.StatusUnauthorized, problem.InvalidExchangeToken, "invalid or expired token")
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("mobile exchange: RedeemAuthCode failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to redeem token")
 		return
 	}
 
-	var data map[string]interface{}
-	if err := json.Unmarshal(tokenData, &data); err != nil {
-		fmt.Printf("Mobile OAuth Exchange: invalid token data from %s: %v\n", r.RemoteAddr, err)
-		http.Error(w, "invalid token data", http.StatusBadRequest)
+	// The code is already burnt at this point, so a wrong state cannot be
+	// retried with the same code.
+	if subtle.ConstantTimeCompare([]byte(code.State), []byte(req.State)) != 1 {
+		logging.FromContext(r.Context()).Warn("mobile exchange: state mismatch", "auth_code_id", code.Id, "user_id", code.UserId, "ip", middlewares.ClientIP(r))
+		problem.Write(w, http.StatusUnauthorized, problem.InvalidExchangeToken, "invalid or expired token")
 		return
 	}
 
-	// Check expiration
-	if expiresAt, ok := data["expires_at"].(float64); ok {
-		if time.Now().Unix() > int64(expiresAt) {
-			fmt.Printf("Mobile OAuth Exchange: token expired from %s\n", r.RemoteAddr)
-			http.Error(w, "token expired", http.StatusUnauthorized)
+	if code.CodeChallenge != "" {
+		if !verifyCodeVerifier(req.CodeVerifier, code.CodeChallenge) {
+			logging.FromContext(r.Context()).Warn("mobile exchange: code_verifier mismatch", "auth_code_id", code.Id, "user_id", code.UserId, "ip", middlewares.ClientIP(r))
+			problem.Write(w, http.StatusUnauthorized, problem.InvalidExchangeToken, "invalid or expired token")
 			return
 		}
+	} else if h.requirePKCE {
+		// issued before PKCE became mandatory
+		logging.FromContext(r.Context()).Warn("mobile exchange: rejected auth code without PKCE", "auth_code_id", code.Id)
+		problem.Write(w, http.StatusUnauthorized, problem.InvalidExchangeToken, "invalid or expired token")
+		return
 	}
 
-	// Extract session info
-	userID, ok1 := data["user_id"].(float64)
-	sessionID, ok2 := data["session_id"].(float64)
-	rawToken, ok3 := data["raw_token"].(string)
-
-	if !ok1 || !ok2 || !ok3 {
-		fmt.Printf("Mobile OAuth Exchange: token data missing fields from %s\n", r.RemoteAddr)
-		http.Error(w, "invalid token data", http.StatusBadRequest)
+	sessionID, rawTok, err := h.createSession(r, code.UserId)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("mobile exchange: CreateSession failed", "user_id", code.UserId, "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create session")
 		return
 	}
 
-	// Create session cookie
-	sessionVal := fmt.Sprintf("%d|%s", int64(sessionID), rawToken)
-	enc := base64.RawURLEncoding.EncodeToString([]byte(sessionVal))
-
-	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    enc,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   60 * 60 * 24 * 30, // 30 days
-	})
+	enc := encodeSessionToken(sessionID, rawTok)
+	h.setSessionCookie(w, enc)
 
-	// Return success response
 	response := MobileTokenResponse{
 		Token:     enc,
-		ExpiresIn: 60 * 60 * 24 * 30,
-		UserID:    int64(userID),
-		SessionID: int64(sessionID),
+		ExpiresIn: int(h.sessionTTL.Seconds()),
+		UserID:    code.UserId,
+		SessionID: sessionID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		fmt.Printf("Mobile OAuth Exchange: failed to write response to %s: %v\n", r.RemoteAddr, err)
+		logging.FromContext(r.Context()).Warn("mobile exchange: writing response failed", "err", err)
 		return
 	}
 
-	fmt.Printf("Mobile OAuth: Token exchanged for user %d, session %d from %s\n", int64(userID), int64(sessionID), r.RemoteAddr)
+	logging.FromContext(r.Context()).Info("mobile exchange: auth code exchanged", "auth_code_id", code.Id, "user_id", code.UserId, "session_id", sessionID)
 }
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+)
+
+const testVerifier = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+
+// mobileLogin runs a mobile login up to the deep link and returns the
+// one-time code it carries.
+func mobileLogin(t *testing.T, srv http.Handler, clientState, challenge string) string {
+	t.Helper()
+	q := url.Values{"client": {"mobile"}, "state": {clientState}}
+	if challenge != "" {
+		q.Set("code_challenge", challenge)
+		q.Set("code_challenge_method", "S256")
+	}
+	authorize := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake?"+q.Encode(), ""))
+
+	cb := url.Values{"code": {"good-code"}, "state": {authorize.Get("state")}}
+	deep := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/callback/fake?"+cb.Encode(), ""))
+	if deep.Get("error") != "" || deep.Get("state") != clientState {
+		t.Fatalf("deep link = %v, want the code and state %q", deep, clientState)
+	}
+	return deep.Get("token")
+}
+
+func exchange(t *testing.T, srv http.Handler, code, state, verifier string) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"token": code, "state": state, "code_verifier": verifier})
+	return do(t, srv, http.MethodPost, "/api/v1/oauth/mobile/exchange", string(body))
+}
+
+func wantExchangeRejected(t *testing.T, resp *http.Response) {
+	t.Helper()
+	var p struct{ Code string }
+	_ = json.NewDecoder(resp.Body).Decode(&p)
+	if resp.StatusCode != http.StatusUnauthorized || p.Code != "invalid_exchange_token" {
+		t.Errorf("exchange = %d %q, want 401 invalid_exchange_token", resp.StatusCode, p.Code)
+	}
+}
+
+func TestMobileExchangePKCE(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+
+	code := mobileLogin(t, srv, "client-state", s256(testVerifier))
+	resp := exchange(t, srv, code, "client-state", testVerifier)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("exchange = %d, want 200", resp.StatusCode)
+	}
+	var tok MobileTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil || tok.Token == "" || tok.SessionID == 0 {
+		t.Fatalf("exchange response = %+v, %v", tok, err)
+	}
+
+	// the code works once
+	wantExchangeRejected(t, exchange(t, srv, code, "client-state", testVerifier))
+}
+
+func TestMobileExchangeVerifierMismatch(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+
+	for name, verifier := range map[string]string{
+		"wrong verifier":   strings.Repeat("x", 43),
+		"missing verifier": "",
+		"challenge itself": s256(testVerifier),
+	} {
+		t.Run(name, func(t *testing.T) {
+			code := mobileLogin(t, srv, "client-state", s256(testVerifier))
+			wantExchangeRejected(t, exchange(t, srv, code, "client-state", verifier))
+			// the failed attempt burnt the code
+			wantExchangeRejected(t, exchange(t, srv, code, "client-state", testVerifier))
+		})
+	}
+}
+
+func TestMobileExchangeStateMismatchBurnsCode(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+
+	code := mobileLogin(t, srv, "client-state", s256(testVerifier))
+	wantExchangeRejected(t, exchange(t, srv, code, "attacker-state", testVerifier))
+	wantExchangeRejected(t, exchange(t, srv, code, "client-state", testVerifier))
+}
+
+func TestMobileExchangeWithoutPKCE(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+	code := mobileLogin(t, srv, "client-state", "")
+	if resp := exchange(t, srv, code, "client-state", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("exchange without PKCE while optional = %d, want 200", resp.StatusCode)
+	}
+	wantExchangeRejected(t, exchange(t, srv, "unknown-code", "client-state", ""))
+}
+
+func TestStartClient(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+	const callback = "https://api.test/api/v1/oauth/callback/fake"
+
+	web := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake", ""))
+	mobile := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake?client=mobile&state=s", ""))
+	if web.Get("redirect_uri") != callback || mobile.Get("redirect_uri") != callback {
+		t.Errorf("redirect_uri web %q, mobile %q, want both %q", web.Get("redirect_uri"), mobile.Get("redirect_uri"), callback)
+	}
+	if strings.HasPrefix(web.Get("state"), mobileStatePrefix) || !strings.HasPrefix(mobile.Get("state"), mobileStatePrefix) {
+		t.Errorf("state web %q, mobile %q, want only the mobile one prefixed", web.Get("state"), mobile.Get("state"))
+	}
+
+	if resp := do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake?client=desktop", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("start with an unknown client = %d, want 400", resp.StatusCode)
+	}
+	for _, target := range []string{"/api/v1/oauth/mobile/start/fake?state=s", "/api/v1/oauth/mobile/callback?code=c&state=s"} {
+		if resp := do(t, srv, http.MethodGet, target, ""); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", target, resp.StatusCode)
+		}
+	}
+}
+
+func TestMobileRequirePKCE(t *testing.T) {
+	h, _, srv := newTestHandler(t, func(a *config.Auth) { a.RequirePKCE = true })
+
+	resp := do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake?client=mobile&state=s", "")
+	var p struct {
+		Code   string
+		Errors []struct{ Field string }
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&p)
+	if resp.StatusCode != http.StatusBadRequest || p.Code != "validation_failed" || len(p.Errors) != 1 || p.Errors[0].Field != "code_challenge" {
+		t.Errorf("start without challenge = %d %+v, want 400 on code_challenge", resp.StatusCode, p)
+	}
+
+	// a code issued without a challenge before PKCE became mandatory
+	h.requirePKCE = false
+	code := mobileLogin(t, srv, "client-state", "")
+	h.requirePKCE = true
+	wantExchangeRejected(t, exchange(t, srv, code, "client-state", ""))
+
+	code = mobileLogin(t, srv, "client-state", s256(testVerifier))
+	if resp := exchange(t, srv, code, "client-state", testVerifier); resp.StatusCode != http.StatusOK {
+		t.Errorf("exchange with PKCE = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestMobileCallbackRejectsTamperedState(t *testing.T) {
+	_, _, srv := newTestHandler(t, nil)
+
+	q := url.Values{"client": {"mobile"}, "state": {"s"}, "code_challenge": {s256(testVerifier)}, "code_challenge_method": {"S256"}}
+	authorize := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/start/fake?"+q.Encode(), ""))
+	state := authorize.Get("state")
+
+	for name, bad := range map[string]string{
+		"signature": state[:len(state)-2] + "AA",
+		"unsigned":  mobileStatePrefix + "eyJwIjoiZmFrZSJ9",
+	} {
+		cb := url.Values{"code": {"good-code"}, "state": {bad}}
+		deep := redirectQuery(t, do(t, srv, http.MethodGet, "/api/v1/oauth/callback/fake?"+cb.Encode(), ""))
+		if deep.Get("error") != "invalid_state" || deep.Get("token") != "" {
+			t.Errorf("%s: deep link = %v, want error=invalid_state", name, deep)
+		}
+	}
+}
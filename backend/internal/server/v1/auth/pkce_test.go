@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+	challenge := s256(verifier)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"match", verifier, challenge, true},
+		{"rfc 7636 example", "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", true},
+		{"other verifier", strings.Repeat("b", 43), challenge, false},
+		{"verifier as plain challenge", verifier, verifier, false},
+		{"empty verifier", "", challenge, false},
+		{"too short", strings.Repeat("a", 42), s256(strings.Repeat("a", 42)), false},
+		{"too long", strings.Repeat("a", 129), s256(strings.Repeat("a", 129)), false},
+		{"bad character", strings.Repeat("a", 42) + "+", s256(strings.Repeat("a", 42) + "+"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeVerifier(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("verifyCodeVerifier = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCodeChallenge(t *testing.T) {
+	challenge := s256(strings.Repeat("a", 43))
+	tests := []struct {
+		name              string
+		requirePKCE       bool
+		challenge, method string
+		wantField         string
+	}{
+		{"s256", false, challenge, "S256", ""},
+		{"none while optional", false, "", "", ""},
+		{"none while required", true, "", "", "code_challenge"},
+		{"s256 while required", true, challenge, "S256", ""},
+		{"plain", false, challenge, "plain", "code_challenge_method"},
+		{"no method", false, challenge, "", "code_challenge_method"},
+		{"method without challenge", false, "", "S256", "code_challenge_method"},
+		{"not a digest", false, "abc", "S256", "code_challenge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{requirePKCE: tt.requirePKCE}
+			fe := h.checkCodeChallenge(tt.challenge, tt.method)
+			switch {
+			case tt.wantField == "" && fe != nil:
+				t.Errorf("checkCodeChallenge = %+v, want nil", *fe)
+			case tt.wantField != "" && (fe == nil || fe.Field != tt.wantField):
+				t.Errorf("checkCodeChallenge = %+v, want field %s", fe, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestMobileStateSigning(t *testing.T) {
+	h := &Handler{stateKey: []byte("k")}
+	raw, err := h.signMobileState(mobileState{Provider: "fake", State: "s", CodeChallenge: "c", IssuedAt: time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := h.parseMobileState(raw)
+	if err != nil || st.State != "s" || st.CodeChallenge != "c" {
+		t.Fatalf("parseMobileState = %+v, %v", st, err)
+	}
+
+	enc, sig, _ := strings.Cut(strings.TrimPrefix(raw, mobileStatePrefix), ".")
+	other, _ := h.signMobileState(mobileState{Provider: "fake", State: "s", CodeChallenge: "", IssuedAt: time.Now().Unix()})
+	otherEnc, _, _ := strings.Cut(strings.TrimPrefix(other, mobileStatePrefix), ".")
+	for name, bad := range map[string]string{
+		"challenge stripped": mobileStatePrefix + otherEnc + "." + sig,
+		"other key":          func() string { s, _ := (&Handler{stateKey: []byte("x")}).signMobileState(st); return s }(),
+		"link prefix":        linkStatePrefix + enc + "." + sig,
+		"expired":            func() string { s, _ := h.signMobileState(mobileState{Provider: "fake", IssuedAt: 1}); return s }(),
+	} {
+		if _, err := h.parseMobileState(bad); err == nil {
+			t.Errorf("%s: parseMobileState accepted it", name)
+		}
+	}
+}
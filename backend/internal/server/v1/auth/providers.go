@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
+	"github.com/HimanshuKumarDutt094/hextok/internal/oauth"
+)
+
+// newProviders builds every login provider that has credentials configured.
+func newProviders(cfg config.Auth, httpClient *http.Client) map[string]domains.OAuthProvider {
+	providers := make(map[string]domains.OAuthProvider)
+	add := func(p domains.OAuthProvider) { providers[p.Name()] = p }
+
+	if cfg.GithubClientID == "" || cfg.GithubClientSecret == "" {
+		slog.Warn("GITHUB_CLIENT_ID or GITHUB_CLIENT_SECRET is not set; GitHub login is disabled")
+	} else {
+		add(oauth.NewGitHub(cfg.GithubURL, cfg.GithubAPIURL, cfg.GithubClientID, cfg.GithubClientSecret, httpClient))
+	}
+	if cfg.GoogleClientID != "" {
+		add(oauth.NewGoogle(cfg.GoogleIssuer, cfg.GoogleClientID, cfg.GoogleClientSecret, httpClient))
+	}
+	if cfg.OIDC.Issuer != "" {
+		add(oauth.NewOIDC(cfg.OIDC.Name, cfg.OIDC.Issuer, cfg.OIDC.ClientID, cfg.OIDC.ClientSecret, strings.Fields(cfg.OIDC.Scopes), httpClient))
+	}
+	return providers
+}
+
+// provider looks up the {provider} path value.
+func (h *Handler) provider(r *http.Request) (domains.OAuthProvider, bool) {
+	p, ok := h.providers[r.PathValue("provider")]
+	return p, ok
+}
+
+// callbackURL is the redirect_uri sent to the provider. Every flow uses the
+// same one, so an app with a single registered callback, like a GitHub OAuth
+// app, needs only /api/v1/oauth/callback/github registered.
+func (h *Handler) callbackURL(provider string) string {
+	return h.baseURL + "/api/v1/oauth/callback/" + provider
+}
+
+// loginUser returns the user linked to the provider identity, creating the
+// user and the link on first login. Every flow that logs a user in goes
+// through here, so this is where logins are counted.
+func (h *Handler) loginUser(ctx context.Context, provider string, profile domains.OAuthProfile, tok domains.OAuthToken) (int64, error) {
+	row, err := h.OauthRepo.GetOauthByProviderUserID(ctx, provider, profile.ProviderUserId)
+	if err == nil {
+		metrics.Logins.WithLabelValues(provider).Inc()
+		return row.UserId, nil
+	}
+	if !errors.Is(err, domains.ErrNotFound) {
+		return 0, fmt.Errorf("GetOauthByProviderUserID: %w", err)
+	}
+
+	userId, err := h.UserRepo.CreateUser(ctx, profile.Login)
+	if err != nil {
+		return 0, fmt.Errorf("CreateUser: %w", err)
+	}
+	if _, err := h.OauthRepo.CreateOauth(ctx, userId, provider, profile.ProviderUserId, tok.AccessToken, tok.RefreshToken); err != nil {
+		return 0, fmt.Errorf("CreateOauth: %w", err)
+	}
+	metrics.Logins.WithLabelValues(provider).Inc()
+	return userId, nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows up to limit events per key in each fixed window. Counts
+// are kept in memory, so every API replica limits on its own.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]rateWindow
+	pruned  time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	n     int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, windows: make(map[string]rateWindow)}
+}
+
+// allow counts an event for key at now. When key is over its limit it
+// returns false and how long until its window ends.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// once per window, forget keys whose window has ended
+	if now.Sub(l.pruned) >= l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = rateWindow{start: now}
+	}
+	w.n++
+	l.windows[key] = w
+	if w.n > l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	return true, 0
+}
@@ -2,24 +2,32 @@ package auth
 
 import (
 	"net/http"
-
-	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 )
 
-// h := auth.NewHandler(userStore, oauthStore, sessionStore, nil)
+// h := auth.NewHandler(userStore, oauthStore, sessionStore, authCodeStore, emailTokenStore, mailer, cfg.Auth, nil)
 // auth.RegisterRoutes(mux, h)
 func RegisterRoutes(mux *http.ServeMux, h *Handler) {
-	// OAuth routes - unified callback handles both web and mobile
-	mux.HandleFunc("GET /oauth/start/github", h.StartAuthHandler)
-	mux.HandleFunc("GET /oauth/mobile/start/github", h.StartMobileOAuthHandler)
-	mux.HandleFunc("GET /oauth/callback/github", h.UnifiedOAuthCallbackHandler)
-	// GitHub mobile callback (matches OAuth app setting)
-	mux.HandleFunc("GET /oauth/mobile/callback", h.UnifiedOAuthCallbackHandler)
+	// OAuth login. ?client=mobile starts the app flow, which ends at the
+	// hextok:// deep link instead of setting a cookie. Web, mobile and link
+	// flows all return to the provider's one callback, which tells them apart
+	// by the state.
+	mux.HandleFunc("GET /oauth/start/{provider}", h.StartAuthHandler)
+	mux.HandleFunc("GET /oauth/callback/{provider}", h.UnifiedOAuthCallbackHandler)
+	// Link another provider to the logged-in user
+	mux.Handle("GET /oauth/link/{provider}", h.authMiddleware(http.HandlerFunc(h.StartLinkHandler)))
 
 	// Mobile token exchange endpoint
 	mux.HandleFunc("POST /oauth/mobile/exchange", h.ExchangeMobileTokenHandler)
 
-	// Auth middleware for protected routes
-	authMiddleware := middlewares.NewAuthMiddleware(h.SessionRepo)
-	mux.Handle("GET /oauth/logout", authMiddleware(http.HandlerFunc(h.LogoutHandler)))
+	// Device authorization for clients without a browser
+	mux.HandleFunc("POST /oauth/device/start", h.StartDeviceHandler)
+	mux.HandleFunc("POST /oauth/device/poll", h.PollDeviceHandler)
+
+	// Passwordless email login
+	mux.HandleFunc("POST /auth/email/start", h.StartEmailLoginHandler)
+	mux.HandleFunc("GET /auth/email/verify", h.VerifyEmailLoginHandler)
+
+	// Auth middleware for protected routes. Logout changes state, so it is
+	// POST only; a GET gets 405 with Allow: POST.
+	mux.Handle("POST /oauth/logout", h.authMiddleware(http.HandlerFunc(h.LogoutHandler)))
 }
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+)
+
+// maxUserAgentLen caps what is stored from the User-Agent header.
+const maxUserAgentLen = 512
+
+// createSession stores a new session for userId, recording the user agent
+// and IP of the client that logged in. It returns the session id and the raw
+// secret; only the secret's hash is stored.
+func (h *Handler) createSession(r *http.Request, userId int64) (int64, string, error) {
+	rawTok, err := generateRandomToken(sessionTokLen)
+	if err != nil {
+		return 0, "", fmt.Errorf("generate session token: %w", err)
+	}
+	hash := hashSecret(rawTok)
+
+	sid, err := h.SessionRepo.CreateSession(r.Context(), userId, hash, truncateUserAgent(r.UserAgent()), middlewares.ClientIP(r))
+	if err != nil {
+		return 0, "", err
+	}
+	return sid, rawTok, nil
+}
+
+// truncateUserAgent cuts ua to at most maxUserAgentLen bytes without
+// splitting a UTF-8 sequence, and drops invalid bytes, so it stores cleanly
+// in a text column.
+func truncateUserAgent(ua string) string {
+	ua = strings.ToValidUTF8(ua, "")
+	if len(ua) <= maxUserAgentLen {
+		return ua
+	}
+	n := maxUserAgentLen
+	for n > 0 && !utf8.RuneStart(ua[n]) {
+		n--
+	}
+	return ua[:n]
+}
+
+// hashSecret is how session secrets and mobile auth codes are stored.
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// encodeSessionToken builds the bearer token / cookie value the auth
+// middleware expects.
+func encodeSessionToken(sid int64, rawTok string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d|%s", sid, rawTok)))
+}
+
+func (h *Handler) setSessionCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		HttpOnly: true,
+		Secure:   true,
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(h.sessionTTL.Seconds()),
+	})
+}
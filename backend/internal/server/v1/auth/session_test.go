@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"short", "Mozilla/5.0", "Mozilla/5.0"},
+		{"at the limit", strings.Repeat("a", maxUserAgentLen), strings.Repeat("a", maxUserAgentLen)},
+		{"ascii over", strings.Repeat("a", maxUserAgentLen+10), strings.Repeat("a", maxUserAgentLen)},
+		// "é" is 2 bytes, so byte 512 is the start of the 257th
+		{"two byte runes", strings.Repeat("é", 300), strings.Repeat("é", 256)},
+		// "€" is 3 bytes; cutting at 512 would split the 171st
+		{"three byte runes", strings.Repeat("€", 200), strings.Repeat("€", 170)},
+		{"emoji across the limit", strings.Repeat("a", maxUserAgentLen-2) + "🙂tail", strings.Repeat("a", maxUserAgentLen-2)},
+		{"invalid bytes", "curl\xff/8.0", "curl/8.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateUserAgent(tt.in)
+			if got != tt.want {
+				t.Errorf("truncateUserAgent = %q (%d bytes), want %q", got, len(got), tt.want)
+			}
+			if len(got) > maxUserAgentLen || !utf8.ValidString(got) {
+				t.Errorf("truncateUserAgent = %d bytes, valid %v", len(got), utf8.ValidString(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// signState encodes v as an OAuth state value: prefix, then the base64url
+// JSON payload and its HMAC. The prefix tells the unified callback which
+// flow the state belongs to and is covered by the signature, so a state from
+// one flow cannot be replayed into another.
+func (h *Handler) signState(prefix string, v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	return prefix + enc + "." + h.stateMAC(prefix, enc), nil
+}
+
+// parseState verifies a value produced by signState and decodes it into v.
+func (h *Handler) parseState(prefix, raw string, v any) error {
+	rest, ok := strings.CutPrefix(raw, prefix)
+	if !ok {
+		return errors.New("state is for another flow")
+	}
+	enc, sig, ok := strings.Cut(rest, ".")
+	if !ok {
+		return errors.New("invalid state format")
+	}
+	if !hmac.Equal([]byte(h.stateMAC(prefix, enc)), []byte(sig)) {
+		return errors.New("state signature mismatch")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return errors.New("invalid state encoding")
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errors.New("invalid state payload")
+	}
+	return nil
+}
+
+func (h *Handler) stateMAC(prefix, enc string) string {
+	mac := hmac.New(sha256.New, h.stateKey)
+	mac.Write([]byte(prefix + enc))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
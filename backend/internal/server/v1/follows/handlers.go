@@ -2,53 +2,62 @@ package follows
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
 type Handler struct {
-	followStore  domains.FollowRepo
-	sessionStore domains.SessionRepo
+	followStore    domains.FollowRepo
+	authMiddleware func(http.Handler) http.Handler
 }
 
-func NewHandler(f domains.FollowRepo, s domains.SessionRepo) *Handler {
-	return &Handler{followStore: f, sessionStore: s}
+func NewHandler(f domains.FollowRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{followStore: f, authMiddleware: authMiddleware}
 }
 
 func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
 	idStr := strings.TrimPrefix(r.URL.Path, "/follows/follow/")
 	if idStr == "" || idStr == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "missing user id in path"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "missing user id in path")
 		return
 	}
 
 	targetId, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid user id")
 		return
 	}
 
-	if err := h.followStore.FollowUser(r.Context(), userId, targetId); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to follow user"})
+	err = h.followStore.FollowUser(r.Context(), userId, targetId)
+	if errors.Is(err, domains.ErrAlreadyExists) {
+		problem.Write(w, http.StatusConflict, problem.AlreadyFollowing, "already following this user")
+		return
+	}
+	if errors.Is(err, domains.ErrNotFound) {
+		problem.Write(w, http.StatusNotFound, problem.UserNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to follow user")
 		return
 	}
 
+	metrics.Follows.WithLabelValues("follow").Inc()
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "followed"})
 
@@ -58,31 +67,28 @@ func (h *Handler) UnfollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
 	idStr := strings.TrimPrefix(r.URL.Path, "/follows/unfollow/")
 	if idStr == "" || idStr == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "missing user id in path"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "missing user id in path")
 		return
 	}
 
 	targetId, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid user id"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid user id")
 		return
 	}
 
 	if err := h.followStore.UnfollowUser(r.Context(), userId, targetId); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to unfollow user"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to unfollow user")
 		return
 	}
 
+	metrics.Follows.WithLabelValues("unfollow").Inc()
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "unfollowed"})
 }
@@ -91,8 +97,7 @@ func (h *Handler) GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
@@ -107,8 +112,7 @@ func (h *Handler) GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.followStore.GetFollowers(r.Context(), targetId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to get followers"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get followers")
 		return
 	}
 	users := make([]schema.UserResponse, 0, len(res))
@@ -122,8 +126,7 @@ func (h *Handler) GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewEncoder(w).Encode(users); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 
@@ -133,8 +136,7 @@ func (h *Handler) GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
@@ -149,8 +151,7 @@ func (h *Handler) GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.followStore.GetFollowing(r.Context(), targetId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to get following"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get following")
 		return
 	}
 	users := make([]schema.UserResponse, 0, len(res))
@@ -164,8 +165,7 @@ func (h *Handler) GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewEncoder(w).Encode(users); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 }
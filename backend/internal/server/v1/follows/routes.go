@@ -3,14 +3,17 @@ package follows
 import (
 	"net/http"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 )
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	authMiddleware := middlewares.NewAuthMiddleware(h.sessionStore)
-	mux.Handle("GET /follows/followers/{id}", authMiddleware(http.HandlerFunc(h.GetFollowersHandler)))
-	mux.Handle("GET /follows/following/{id}", authMiddleware(http.HandlerFunc(h.GetFollowingHandler)))
-	mux.Handle("POST /follows/follow/{id}", authMiddleware(http.HandlerFunc(h.FollowUserHandler)))
-	mux.Handle("POST /follows/unfollow/{id}", authMiddleware(http.HandlerFunc(h.UnfollowUserHandler)))
+	authMiddleware := h.authMiddleware
+	read := middlewares.RequireScope(domains.ScopeReadFollows)
+	write := middlewares.RequireScope(domains.ScopeWriteFollows)
+	mux.Handle("GET /follows/followers/{id}", authMiddleware(read(http.HandlerFunc(h.GetFollowersHandler))))
+	mux.Handle("GET /follows/following/{id}", authMiddleware(read(http.HandlerFunc(h.GetFollowingHandler))))
+	mux.Handle("POST /follows/follow/{id}", authMiddleware(write(http.HandlerFunc(h.FollowUserHandler))))
+	mux.Handle("POST /follows/unfollow/{id}", authMiddleware(write(http.HandlerFunc(h.UnfollowUserHandler))))
 
 }
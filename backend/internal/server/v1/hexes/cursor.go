@@ -0,0 +1,35 @@
+package hexes
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const cursorPrefix = "v1:"
+
+// encodeFeedCursor hides the keyset position behind an opaque token so the
+// feed ordering can change without breaking clients that hold a cursor.
+func encodeFeedCursor(lastId int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(lastId, 10)))
+}
+
+// decodeFeedCursor accepts only cursors encodeFeedCursor could have made.
+// Decoding is strict so an edited cursor is not read as a different
+// position.
+func decodeFeedCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.Strict().DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor encoding")
+	}
+	idStr, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, errors.New("unsupported cursor version")
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor position")
+	}
+	return id, nil
+}
@@ -0,0 +1,46 @@
+package hexes
+
+import (
+	"encoding/base64"
+	"math"
+	"testing"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	for _, id := range []int64{1, 42, math.MaxInt64} {
+		c := encodeFeedCursor(id)
+		got, err := decodeFeedCursor(c)
+		if err != nil || got != id {
+			t.Errorf("decodeFeedCursor(encodeFeedCursor(%d)) = %d, %v", id, got, err)
+		}
+	}
+}
+
+func TestDecodeFeedCursorRejects(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := encodeFeedCursor(42)
+	tests := []struct {
+		name, cursor, want string
+	}{
+		{"not base64", "!!!", "invalid cursor encoding"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("v1:42")), "invalid cursor encoding"},
+		{"truncated", valid[:len(valid)-1], "invalid cursor encoding"},
+		{"flipped character", "X" + valid[1:], "unsupported cursor version"},
+		{"raw id", "42", "invalid cursor encoding"},
+		{"other version", enc("v2:42"), "unsupported cursor version"},
+		{"no id", enc("v1:"), "invalid cursor position"},
+		{"not a number", enc("v1:abc"), "invalid cursor position"},
+		{"zero", enc("v1:0"), "invalid cursor position"},
+		{"negative", enc("v1:-5"), "invalid cursor position"},
+		{"overflow", enc("v1:9223372036854775808"), "invalid cursor position"},
+		{"trailing data", enc("v1:42;drop"), "invalid cursor position"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := decodeFeedCursor(tt.cursor)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("decodeFeedCursor(%q) = %d, %v; want error %q", tt.cursor, id, err, tt.want)
+			}
+		})
+	}
+}
@@ -2,31 +2,53 @@ package hexes
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/colorindex"
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/logging"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
+const (
+	defaultFeedLimit    = 10
+	maxFeedLimit        = 50
+	defaultSimilarLimit = 20
+	maxSimilarLimit     = 100
+	// similarIndexMaxAge bounds how stale the in-memory color index may get
+	// with respect to hexes created by other instances.
+	similarIndexMaxAge = 5 * time.Minute
+)
+
 type Handler struct {
-	hexStore     domains.HexRepo
-	sessionStore domains.SessionRepo
-	likeStore    domains.LikeRepo
+	hexStore       domains.HexRepo
+	userStore      domains.UserRepo
+	likeStore      domains.LikeRepo
+	colorIndex     *colorindex.Index
+	authMiddleware func(http.Handler) http.Handler
 }
 
-func NewHandler(h domains.HexRepo, l domains.LikeRepo, s domains.SessionRepo) *Handler {
-	return &Handler{hexStore: h, sessionStore: s, likeStore: l}
+func NewHandler(h domains.HexRepo, u domains.UserRepo, l domains.LikeRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{
+		hexStore:       h,
+		userStore:      u,
+		likeStore:      l,
+		colorIndex:     colorindex.New(h.ListHexLabs, similarIndexMaxAge),
+		authMiddleware: authMiddleware,
+	}
 }
 
 func (h *Handler) listHexesHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := h.hexStore.ListAllHexColors(r.Context())
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to get colors"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get colors")
 		return
 	}
 	users := make([]schema.HexResponse, 0, len(data))
@@ -36,41 +58,206 @@ func (h *Handler) listHexesHandler(w http.ResponseWriter, r *http.Request) {
 		ids = append(ids, v.Id)
 	}
 
-	// fetch counts in one query if likeStore available
-	counts := map[int64]int{}
-	if h.likeStore != nil {
-		if c, err := h.likeStore.GetLikeCountsForHexes(r.Context(), ids); err == nil {
-			counts = c
+	counts, liked, err := h.likeInfo(r, ids)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("hexes: loading likes failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get likes")
+		return
+	}
+
+	for _, v := range data {
+		users = append(users, schema.HexResponse{
+			Id:        v.Id,
+			HexValue:  v.HexValue,
+			LikeCount: counts[v.Id],
+			IsLiked:   liked[v.Id],
+			CreatedBy: v.CreatedBy,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
+		return
+	}
+}
+
+func (h *Handler) feedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	afterId, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	// fetch one extra row to learn whether another page exists
+	data, err := h.hexStore.ListHexFeed(r.Context(), afterId, limit+1)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get feed")
+		return
+	}
+	h.writeHexPage(w, r, data, limit)
+}
+
+// userHexesHandler lists the hexes a user created, paginated like the feed.
+func (h *Handler) userHexesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
+		return
+	}
+	afterId, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.userStore.GetUserById(r.Context(), userId); err != nil {
+		if errors.Is(err, domains.ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.UserNotFound, "user not found")
+			return
 		}
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get user")
+		return
+	}
+	data, err := h.hexStore.ListHexesByCreator(r.Context(), userId, afterId, limit+1)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get hexes")
+		return
 	}
+	h.writeHexPage(w, r, data, limit)
+}
+
+// parsePage reads the cursor and limit query parameters shared by the
+// paginated listings. It writes a 400 and returns false on bad input.
+func parsePage(w http.ResponseWriter, r *http.Request) (afterId int64, limit int, ok bool) {
+	q := r.URL.Query()
 
-	// build a set of liked ids for the current user (if authed)
-	likedSet := map[int64]struct{}{}
-	if h.likeStore != nil {
-		if userId, ok := middlewares.GetAuthedUserID(r.Context()); ok {
-			if likedHexes, err := h.likeStore.GetLikedHexesByUser(r.Context(), userId); err == nil {
-				for _, lh := range likedHexes {
-					likedSet[lh.Id] = struct{}{}
-				}
-			}
+	limit = defaultFeedLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			problem.Invalid(w, problem.Field("limit", "must be a positive integer"))
+			return 0, 0, false
 		}
+		limit = min(n, maxFeedLimit)
 	}
 
+	if c := q.Get("cursor"); c != "" {
+		id, err := decodeFeedCursor(c)
+		if err != nil {
+			problem.Invalid(w, problem.Field("cursor", err.Error()))
+			return 0, 0, false
+		}
+		afterId = id
+	}
+	return afterId, limit, true
+}
+
+// writeHexPage encodes up to limit hexes from data, which was fetched with
+// one extra row so the presence of a next page is known.
+func (h *Handler) writeHexPage(w http.ResponseWriter, r *http.Request, data []domains.Hex, limit int) {
+	var next string
+	if len(data) > limit {
+		data = data[:limit]
+		next = encodeFeedCursor(data[len(data)-1].Id)
+	}
+
+	ids := make([]int64, 0, len(data))
 	for _, v := range data {
-		lr := schema.HexResponse{
+		ids = append(ids, v.Id)
+	}
+	counts, liked, err := h.likeInfo(r, ids)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("hexes: loading likes failed", "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get likes")
+		return
+	}
+
+	resp := schema.HexFeedResponse{
+		Items:      make([]schema.HexResponse, 0, len(data)),
+		NextCursor: next,
+	}
+	for _, v := range data {
+		resp.Items = append(resp.Items, schema.HexResponse{
 			Id:        v.Id,
 			HexValue:  v.HexValue,
 			LikeCount: counts[v.Id],
-		}
-		if _, ok := likedSet[v.Id]; ok {
-			lr.IsLiked = true
-		}
-		users = append(users, lr)
+			IsLiked:   liked[v.Id],
+			CreatedBy: v.CreatedBy,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
+		return
+	}
+}
+
+// likeInfo returns the like counts of ids and which of them the caller, if
+// authenticated, has liked.
+func (h *Handler) likeInfo(r *http.Request, ids []int64) (map[int64]int, map[int64]bool, error) {
+	if h.likeStore == nil || len(ids) == 0 {
+		return nil, nil, nil
+	}
+	counts, err := h.likeStore.GetLikeCountsForHexes(r.Context(), ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("like counts: %w", err)
+	}
+	userId, ok := middlewares.GetAuthedUserID(r.Context())
+	if !ok {
+		return counts, nil, nil
 	}
+	liked, err := h.likeStore.GetLikedHexIds(r.Context(), userId, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("liked hexes: %w", err)
+	}
+	return counts, liked, nil
+}
+
+// similarHexesHandler serves GET /hexes/similar?to=<color>, the hexes
+// perceptually closest to a color, nearest first, with their distance.
+// metric is ciede2000 (the default) or cie76. CIE76 results are exact.
+// CIEDE2000 results are approximate: the nearest 16*limit hexes by CIE76 are
+// re-ranked by CIEDE2000, so a hex that CIEDE2000 ranks higher than CIE76
+// does can be missed. Results come from an in-memory index that may lag
+// other instances' writes by a few minutes.
+func (h *Handler) similarHexesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(users); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+	q := r.URL.Query()
+
+	target, err := domains.ParseColor(q.Get("to"))
+	if err != nil {
+		problem.Invalid(w, problem.Field("to", err.Error()))
+		return
+	}
+	metric, err := colorindex.ParseMetric(q.Get("metric"))
+	if err != nil {
+		problem.Invalid(w, problem.Field("metric", err.Error()))
+		return
+	}
+	limit := defaultSimilarLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			problem.Invalid(w, problem.Field("limit", "must be a positive integer"))
+			return
+		}
+		limit = min(n, maxSimilarLimit)
+	}
+
+	matches, err := h.colorIndex.Nearest(r.Context(), target.Lab(), limit, metric)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to search colors")
+		return
+	}
+	res := make([]schema.SimilarHexResponse, 0, len(matches))
+	for _, m := range matches {
+		res = append(res, schema.SimilarHexResponse{
+			Id:       m.Id,
+			HexValue: m.HexValue,
+			Distance: m.Distance,
+		})
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 }
@@ -79,46 +266,36 @@ func (h *Handler) getHexHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	idStr := strings.TrimPrefix(r.URL.Path, "/hexes/")
 	if len(idStr) == 0 || idStr == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "no id provided"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "no id provided")
 		return
 	}
 	hexId, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid id"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
 		return
 	}
 	res, err := h.hexStore.GetHexById(r.Context(), hexId)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "hex not found"})
+		problem.Write(w, http.StatusNotFound, problem.HexNotFound, "hex not found")
 		return
 	}
-	// populate like count and isLiked if likeStore available
-	lr := schema.HexResponse{
-		Id:       res.Id,
-		HexValue: res.HexValue,
+	counts, liked, err := h.likeInfo(r, []int64{res.Id})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("hexes: loading likes failed", "hex_id", res.Id, "err", err)
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get likes")
+		return
 	}
-	if h.likeStore != nil {
-		if counts, err := h.likeStore.GetLikeCountsForHexes(r.Context(), []int64{res.Id}); err == nil {
-			lr.LikeCount = counts[res.Id]
-		}
-		if userId, ok := middlewares.GetAuthedUserID(r.Context()); ok {
-			if likedHexes, err := h.likeStore.GetLikedHexesByUser(r.Context(), userId); err == nil {
-				for _, lh := range likedHexes {
-					if lh.Id == res.Id {
-						lr.IsLiked = true
-						break
-					}
-				}
-			}
-		}
+	lr := schema.HexResponse{
+		Id:        res.Id,
+		HexValue:  res.HexValue,
+		LikeCount: counts[res.Id],
+		IsLiked:   liked[res.Id],
+		CreatedBy: res.CreatedBy,
+		CreatedAt: res.CreatedAt,
 	}
 
 	if err := json.NewEncoder(w).Encode(lr); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 }
@@ -129,23 +306,42 @@ func (h *Handler) createHexHandler(w http.ResponseWriter, r *http.Request) {
 	dec.DisallowUnknownFields()
 
 	if err := dec.Decode(&body); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid request body"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidBody, "invalid request body")
 		return
 	}
-	res, err := h.hexStore.CreateHex(r.Context(), body.HexValue)
+	hexValue, err := domains.NormalizeHex(body.HexValue)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to create hex"})
+		problem.Invalid(w, problem.Field("hexValue", err.Error()))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	// the route is authenticated, so this only misses when called directly
+	authorId, _ := middlewares.GetAuthedUserID(r.Context())
+	res, err := h.hexStore.CreateHex(r.Context(), hexValue, authorId)
+	if errors.Is(err, domains.ErrAlreadyExists) {
+		existing, lookupErr := h.hexStore.GetHexByValue(r.Context(), hexValue)
+		if lookupErr == nil {
+			p := problem.New(w, http.StatusConflict, problem.HexExists, "hex already exists")
+			problem.Send(w, http.StatusConflict, schema.HexConflictResponse{Problem: p, Id: existing.Id})
+			return
+		}
+	}
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create hex")
+		return
+	}
+	if c, err := domains.ParseColor(hexValue); err == nil {
+		h.colorIndex.Add(domains.HexLab{Hex: domains.Hex{Id: res, HexValue: hexValue, CreatedBy: authorId}, Lab: c.Lab()})
+	}
 	hex := schema.HexResponse{
-		Id:       res,
-		HexValue: body.HexValue,
+		Id:        res,
+		HexValue:  hexValue,
+		CreatedBy: authorId,
 	}
+	// read back the row for its database-assigned createdAt
+	if created, err := h.hexStore.GetHexById(r.Context(), res); err == nil {
+		hex.CreatedAt = created.CreatedAt
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(hex)
 }
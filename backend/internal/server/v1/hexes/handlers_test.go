@@ -0,0 +1,226 @@
+package hexes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/config"
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+// testServer serves the hex routes over a memstore holding one user with n
+// hexes, ids 1 to n. It returns the user's id and a bearer header for them.
+type testServer struct {
+	store  *memstore.Store
+	srv    http.Handler
+	userId int64
+	auth   string
+}
+
+func newTestServer(t *testing.T, n int, likes func(*memstore.Store) domains.LikeRepo) *testServer {
+	t.Helper()
+	ctx := context.Background()
+	s := memstore.New()
+	userId, err := s.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if _, err := s.CreateHex(ctx, fmt.Sprintf("#%06x", i), userId); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum := sha256.Sum256([]byte("secret"))
+	sid, err := s.CreateSession(ctx, userId, base64.StdEncoding.EncodeToString(sum[:]), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l domains.LikeRepo = s
+	if likes != nil {
+		l = likes(s)
+	}
+	h := NewHandler(s, s, l, middlewares.NewAuthMiddleware(s, nil, config.Default().Auth.Session.Policy()))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	return &testServer{
+		store:  s,
+		srv:    mux,
+		userId: userId,
+		auth:   "Bearer " + base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(sid, 10)+"|secret")),
+	}
+}
+
+func (ts *testServer) get(t *testing.T, target string) *http.Response {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Authorization", ts.auth)
+	w := httptest.NewRecorder()
+	ts.srv.ServeHTTP(w, r)
+	return w.Result()
+}
+
+// page fetches a feed page and returns its ids and next cursor.
+func (ts *testServer) page(t *testing.T, target string) ([]int64, string) {
+	t.Helper()
+	resp := ts.get(t, target)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d, want 200", target, resp.StatusCode)
+	}
+	var body schema.HexFeedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, 0, len(body.Items))
+	for _, it := range body.Items {
+		ids = append(ids, it.Id)
+	}
+	return ids, body.NextCursor
+}
+
+// walk pages through target with limit and returns every page's ids.
+func (ts *testServer) walk(t *testing.T, target string, limit int) [][]int64 {
+	t.Helper()
+	var pages [][]int64
+	cursor := ""
+	for {
+		q := "?limit=" + strconv.Itoa(limit)
+		if cursor != "" {
+			q += "&cursor=" + cursor
+		}
+		ids, next := ts.page(t, target+q)
+		pages = append(pages, ids)
+		if next == "" {
+			return pages
+		}
+		if len(pages) > 100 {
+			t.Fatal("paging does not end")
+		}
+		cursor = next
+	}
+}
+
+func TestFeedPaging(t *testing.T) {
+	tests := []struct {
+		name  string
+		hexes int
+		limit int
+		want  string
+	}{
+		{"empty", 0, 2, "[[]]"},
+		{"fewer than a page", 1, 2, "[[1]]"},
+		{"exactly one page", 2, 2, "[[2 1]]"},
+		{"one more than a page", 3, 2, "[[3 2] [1]]"},
+		{"exact pages", 4, 2, "[[4 3] [2 1]]"},
+		{"limit one", 3, 1, "[[3] [2] [1]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.hexes, nil)
+			for _, target := range []string{"/hexes/feed", fmt.Sprintf("/users/%d/hexes", ts.userId)} {
+				if got := fmt.Sprint(ts.walk(t, target, tt.limit)); got != tt.want {
+					t.Errorf("%s pages = %s, want %s", target, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFeedLimits(t *testing.T) {
+	ts := newTestServer(t, maxFeedLimit+1, nil)
+
+	ids, next := ts.page(t, "/hexes/feed")
+	if len(ids) != defaultFeedLimit || next == "" {
+		t.Errorf("default page has %d items, next %q; want %d and a cursor", len(ids), next, defaultFeedLimit)
+	}
+	ids, next = ts.page(t, "/hexes/feed?limit=1000")
+	if len(ids) != maxFeedLimit || next == "" {
+		t.Errorf("oversized page has %d items, next %q; want %d and a cursor", len(ids), next, maxFeedLimit)
+	}
+	// a cursor at the oldest hex ends the feed
+	ids, next = ts.page(t, "/hexes/feed?cursor="+encodeFeedCursor(1))
+	if len(ids) != 0 || next != "" {
+		t.Errorf("page after the last hex = %v, next %q; want empty", ids, next)
+	}
+}
+
+func TestFeedRejectsBadParameters(t *testing.T) {
+	ts := newTestServer(t, 3, nil)
+	for _, q := range []string{
+		"limit=0",
+		"limit=-1",
+		"limit=ten",
+		"cursor=" + encodeFeedCursor(2) + "x",
+		"cursor=" + base64.RawURLEncoding.EncodeToString([]byte("v1:0")),
+		"cursor=2",
+	} {
+		resp := ts.get(t, "/hexes/feed?"+q)
+		var p schema.Problem
+		_ = json.NewDecoder(resp.Body).Decode(&p)
+		if resp.StatusCode != http.StatusBadRequest || p.Code != "validation_failed" {
+			t.Errorf("%s: %d %q, want 400 validation_failed", q, resp.StatusCode, p.Code)
+		}
+	}
+}
+
+func TestLikeInfo(t *testing.T) {
+	ts := newTestServer(t, 2, nil)
+	if err := ts.store.AddLike(context.Background(), ts.userId, 2); err != nil {
+		t.Fatal(err)
+	}
+	var got schema.HexResponse
+	if err := json.NewDecoder(ts.get(t, "/hexes/2").Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.LikeCount != 1 || !got.IsLiked {
+		t.Errorf("GET /hexes/2 = %+v, want 1 like by the caller", got)
+	}
+}
+
+// failingLikes fails the lookups the hex listings make.
+type failingLikes struct {
+	domains.LikeRepo
+	counts, liked bool
+}
+
+func (f failingLikes) GetLikeCountsForHexes(ctx context.Context, hexIds []int64) (map[int64]int, error) {
+	if f.counts {
+		return nil, errors.New("connection reset")
+	}
+	return f.LikeRepo.GetLikeCountsForHexes(ctx, hexIds)
+}
+
+func (f failingLikes) GetLikedHexIds(ctx context.Context, userId int64, hexIds []int64) (map[int64]bool, error) {
+	if f.liked {
+		return nil, errors.New("connection reset")
+	}
+	return f.LikeRepo.GetLikedHexIds(ctx, userId, hexIds)
+}
+
+func TestLikeLookupErrors(t *testing.T) {
+	for _, f := range []failingLikes{{counts: true}, {liked: true}} {
+		ts := newTestServer(t, 2, func(s *memstore.Store) domains.LikeRepo {
+			f.LikeRepo = s
+			return f
+		})
+		for _, target := range []string{"/hexes", "/hexes/feed", "/hexes/1", fmt.Sprintf("/users/%d/hexes", ts.userId)} {
+			resp := ts.get(t, target)
+			var p schema.Problem
+			_ = json.NewDecoder(resp.Body).Decode(&p)
+			if resp.StatusCode != http.StatusInternalServerError || p.Code != "internal_error" {
+				t.Errorf("%+v GET %s = %d %q, want 500 internal_error", f, target, resp.StatusCode, p.Code)
+			}
+		}
+	}
+}
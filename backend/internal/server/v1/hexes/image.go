@@ -0,0 +1,155 @@
+package hexes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+	"github.com/HimanshuKumarDutt094/hextok/internal/swatch"
+)
+
+// imageCacheControl lets browsers and CDNs keep swatches forever: a hex row
+// never changes its value, and the ETag covers every render option.
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+var imageContentTypes = map[string]string{
+	"png": "image/png",
+	"svg": "image/svg+xml",
+}
+
+// imageHandler serves a swatch for a stored hex. format is fixed by the
+// route extension; on the bare /image route it comes from the query string.
+func (h *Handler) imageHandler(ext string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := ext
+		if f := strings.ToLower(q.Get("format")); f != "" {
+			if ext != "" && f != ext {
+				problem.Invalid(w, problem.Field("format", "conflicts with the file extension"))
+				return
+			}
+			format = f
+		}
+		if format == "" {
+			format = "png"
+		}
+		contentType, ok := imageContentTypes[format]
+		if !ok {
+			problem.Invalid(w, problem.Field("format", "must be png or svg"))
+			return
+		}
+
+		opts, errs := parseSwatchOptions(q)
+		if errs != nil {
+			problem.Invalid(w, errs...)
+			return
+		}
+
+		hexId, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
+			return
+		}
+		res, err := h.hexStore.GetHexById(r.Context(), hexId)
+		if errors.Is(err, domains.ErrNotFound) {
+			problem.Write(w, http.StatusNotFound, problem.HexNotFound, "hex not found")
+			return
+		}
+		if err != nil {
+			problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get hex")
+			return
+		}
+		c, err := domains.ParseColor(res.HexValue)
+		if err != nil {
+			problem.Write(w, http.StatusInternalServerError, problem.Internal, "stored hex is not a valid color")
+			return
+		}
+
+		etag := swatchETag(c, format, opts)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", imageCacheControl)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var buf bytes.Buffer
+		if format == "svg" {
+			err = swatch.SVG(&buf, c, opts)
+		} else {
+			err = swatch.PNG(&buf, c, opts)
+		}
+		if err != nil {
+			w.Header().Del("ETag")
+			w.Header().Del("Cache-Control")
+			problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to render image")
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// parseSwatchOptions reads the image query parameters, reporting every bad
+// one.
+func parseSwatchOptions(q url.Values) (swatch.Options, []schema.FieldError) {
+	opts := swatch.Options{Width: swatch.DefaultWidth, Height: swatch.DefaultHeight}
+	var errs []schema.FieldError
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{
+		{"width", &opts.Width},
+		{"height", &opts.Height},
+	} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < swatch.MinSize || n > swatch.MaxSize {
+			errs = append(errs, problem.Field(p.name, fmt.Sprintf("must be a number between %d and %d", swatch.MinSize, swatch.MaxSize)))
+			continue
+		}
+		*p.dst = n
+	}
+	if v := q.Get("label"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, problem.Field("label", "must be true or false"))
+		}
+		opts.Label = b
+	}
+	return opts, errs
+}
+
+// swatchETag is a strong validator derived from everything that affects the
+// rendered bytes, so it can be checked before rendering.
+func swatchETag(c domains.Color, format string, opts swatch.Options) string {
+	key := fmt.Sprintf("v%d|%s|%s|%dx%d|%t", swatch.Version, c.Hex(), format, opts.Width, opts.Height, opts.Label)
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatches implements the If-None-Match comparison, which uses weak
+// comparison and accepts a list of tags or "*".
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
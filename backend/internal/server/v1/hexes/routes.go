@@ -3,12 +3,22 @@ package hexes
 import (
 	"net/http"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 )
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	authMiddleware := middlewares.NewAuthMiddleware(h.sessionStore)
-	mux.Handle("GET /hexes", authMiddleware(http.HandlerFunc(h.listHexesHandler)))
-	mux.Handle("POST /hexes", authMiddleware(http.HandlerFunc(h.createHexHandler)))
-	mux.Handle("GET /hexes/{id}", authMiddleware(http.HandlerFunc(h.getHexHandler)))
+	authMiddleware := h.authMiddleware
+	read := middlewares.RequireScope(domains.ScopeReadHexes)
+	write := middlewares.RequireScope(domains.ScopeWriteHexes)
+	mux.Handle("GET /hexes", authMiddleware(read(http.HandlerFunc(h.listHexesHandler))))
+	mux.Handle("GET /hexes/feed", authMiddleware(read(http.HandlerFunc(h.feedHandler))))
+	mux.Handle("GET /hexes/similar", authMiddleware(read(http.HandlerFunc(h.similarHexesHandler))))
+	mux.Handle("POST /hexes", authMiddleware(write(http.HandlerFunc(h.createHexHandler))))
+	mux.Handle("GET /hexes/{id}", authMiddleware(read(http.HandlerFunc(h.getHexHandler))))
+	mux.Handle("GET /users/{id}/hexes", authMiddleware(read(http.HandlerFunc(h.userHexesHandler))))
+	// swatch images are public so link preview crawlers can fetch them
+	mux.HandleFunc("GET /hexes/{id}/image", h.imageHandler(""))
+	mux.HandleFunc("GET /hexes/{id}/image.png", h.imageHandler("png"))
+	mux.HandleFunc("GET /hexes/{id}/image.svg", h.imageHandler("svg"))
 }
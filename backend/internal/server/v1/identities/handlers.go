@@ -0,0 +1,87 @@
+package identities
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+type Handler struct {
+	oauthStore     domains.OauthRepo
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewHandler(o domains.OauthRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{oauthStore: o, authMiddleware: authMiddleware}
+}
+
+// listIdentitiesHandler returns the login methods linked to the caller.
+// Provider tokens are never included.
+func (h *Handler) listIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.oauthStore.GetOauthsByUser(r.Context(), userId)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get identities")
+		return
+	}
+	identities := make([]schema.IdentityResponse, 0, len(res))
+	for _, o := range res {
+		identities = append(identities, schema.IdentityResponse{
+			Id:             o.Id,
+			Provider:       o.Provider,
+			ProviderUserId: o.ProviderUserId,
+			CreatedAt:      o.CreatedAt,
+		})
+	}
+	if err := json.NewEncoder(w).Encode(identities); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
+		return
+	}
+}
+
+// unlinkIdentityHandler removes one of the caller's identities. The last
+// one cannot be removed, since the account would have no way to log in.
+func (h *Handler) unlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
+		return
+	}
+	err = h.oauthStore.DeleteOauth(r.Context(), userId, id)
+	switch {
+	case errors.Is(err, domains.ErrNotFound):
+		problem.Write(w, http.StatusNotFound, problem.IdentityNotFound, "identity not found")
+		return
+	case errors.Is(err, domains.ErrLastIdentity):
+		problem.Write(w, http.StatusConflict, problem.LastLoginMethod, "cannot unlink the last login method")
+		return
+	case err != nil:
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to unlink identity")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "identity unlinked"})
+}
+
+func authed(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userId, ok := middlewares.GetAuthedUserID(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
+	}
+	return userId, ok
+}
@@ -0,0 +1,14 @@
+package identities
+
+import (
+	"net/http"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+)
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	authMiddleware := h.authMiddleware
+	session := middlewares.RequireSession
+	mux.Handle("GET /me/identities", authMiddleware(session(http.HandlerFunc(h.listIdentitiesHandler))))
+	mux.Handle("DELETE /me/identities/{id}", authMiddleware(session(http.HandlerFunc(h.unlinkIdentityHandler))))
+}
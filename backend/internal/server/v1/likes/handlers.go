@@ -2,51 +2,63 @@ package likes
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/metrics"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
 type Handler struct {
-	hexStore     domains.HexRepo
-	likeStore    domains.LikeRepo
-	sessionStore domains.SessionRepo
+	hexStore       domains.HexRepo
+	likeStore      domains.LikeRepo
+	authMiddleware func(http.Handler) http.Handler
 }
 
-func NewHandler(h domains.HexRepo, l domains.LikeRepo, s domains.SessionRepo) *Handler {
-	return &Handler{hexStore: h, likeStore: l, sessionStore: s}
+func NewHandler(h domains.HexRepo, l domains.LikeRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{hexStore: h, likeStore: l, authMiddleware: authMiddleware}
 }
 
 func (h *Handler) LikeHexHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 	idStr := strings.TrimPrefix(r.URL.Path, "/likes/like/")
 	if len(idStr) == 0 || idStr == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "no id provided"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "no id provided")
 		return
 	}
 	hexId, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid id"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
 		return
 	}
-	if err := h.likeStore.AddLike(r.Context(), userId, hexId); err != nil {
+	err = h.likeStore.AddLike(r.Context(), userId, hexId)
+	if errors.Is(err, domains.ErrNotFound) {
+		problem.Write(w, http.StatusNotFound, problem.HexNotFound, "hex not found")
+		return
+	}
+	if err != nil && !errors.Is(err, domains.ErrAlreadyExists) {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to like hex")
+		return
+	}
+	// liking an already liked hex toggles the like off
+	if err != nil {
 		h.likeStore.RemoveLike(r.Context(), userId, hexId)
+		metrics.Likes.WithLabelValues("unlike").Inc()
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "removed like"})
 		return
 	}
+	metrics.Likes.WithLabelValues("like").Inc()
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "liked"})
 }
@@ -55,27 +67,24 @@ func (h *Handler) UnlikeHexHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 	idStr := strings.TrimPrefix(r.URL.Path, "/likes/unlike/")
 	if len(idStr) == 0 || idStr == r.URL.Path {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "no id provided"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "no id provided")
 		return
 	}
 	hexId, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "invalid id"})
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
 		return
 	}
 	if err := h.likeStore.RemoveLike(r.Context(), userId, hexId); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to remove like"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to remove like")
 		return
 	}
+	metrics.Likes.WithLabelValues("unlike").Inc()
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "unliked"})
 }
@@ -84,27 +93,26 @@ func (h *Handler) GetUserLikedHexesHandler(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
 	res, err := h.likeStore.GetLikedHexesByUser(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to fetch liked hexes"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to fetch liked hexes")
 		return
 	}
 	hexRs := make([]schema.HexResponse, 0, len(res))
 	for _, v := range res {
 		hexRs = append(hexRs, schema.HexResponse{
-			Id:       v.Id,
-			HexValue: v.HexValue,
+			Id:        v.Id,
+			HexValue:  v.HexValue,
+			CreatedBy: v.CreatedBy,
+			CreatedAt: v.CreatedAt,
 		})
 	}
 	if err := json.NewEncoder(w).Encode(hexRs); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 }
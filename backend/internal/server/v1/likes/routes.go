@@ -3,12 +3,15 @@ package likes
 import (
 	"net/http"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 )
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	authMiddleware := middlewares.NewAuthMiddleware(h.sessionStore)
-	mux.Handle("POST /likes/like/{hexId}", authMiddleware(http.HandlerFunc(h.LikeHexHandler)))
-	mux.Handle("POST /likes/unlike/{hexId}", authMiddleware(http.HandlerFunc(h.UnlikeHexHandler)))
-	mux.Handle("GET /likes/user", authMiddleware(http.HandlerFunc(h.GetUserLikedHexesHandler)))
+	authMiddleware := h.authMiddleware
+	read := middlewares.RequireScope(domains.ScopeReadLikes)
+	write := middlewares.RequireScope(domains.ScopeWriteLikes)
+	mux.Handle("POST /likes/like/{hexId}", authMiddleware(write(http.HandlerFunc(h.LikeHexHandler))))
+	mux.Handle("POST /likes/unlike/{hexId}", authMiddleware(write(http.HandlerFunc(h.UnlikeHexHandler))))
+	mux.Handle("GET /likes/user", authMiddleware(read(http.HandlerFunc(h.GetUserLikedHexesHandler))))
 }
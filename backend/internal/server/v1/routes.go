@@ -6,11 +6,14 @@ import (
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/auth"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/follows"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/hexes"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/identities"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/likes"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/sessions"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/tokens"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/v1/users"
 )
 
-func RegisterV1Routes(mux *http.ServeMux, usersHandler *users.Handler, authHandler *auth.Handler, followsHandler *follows.Handler, hexHandler *hexes.Handler, likeHandler *likes.Handler) {
+func RegisterV1Routes(mux *http.ServeMux, usersHandler *users.Handler, authHandler *auth.Handler, followsHandler *follows.Handler, hexHandler *hexes.Handler, likeHandler *likes.Handler, sessionsHandler *sessions.Handler, identitiesHandler *identities.Handler, tokensHandler *tokens.Handler) {
 	if usersHandler != nil {
 		usersHandler.RegisterRoutes(mux)
 	}
@@ -29,4 +32,13 @@ func RegisterV1Routes(mux *http.ServeMux, usersHandler *users.Handler, authHandl
 	if followsHandler != nil {
 		followsHandler.RegisterRoutes(mux)
 	}
+	if sessionsHandler != nil {
+		sessionsHandler.RegisterRoutes(mux)
+	}
+	if identitiesHandler != nil {
+		identitiesHandler.RegisterRoutes(mux)
+	}
+	if tokensHandler != nil {
+		tokensHandler.RegisterRoutes(mux)
+	}
 }
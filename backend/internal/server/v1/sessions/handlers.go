@@ -0,0 +1,134 @@
+package sessions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+const sessionCookieName = "hextok_session"
+
+type Handler struct {
+	sessionStore   domains.SessionRepo
+	policy         domains.SessionPolicy
+	authMiddleware func(http.Handler) http.Handler
+}
+
+// NewHandler returns the session management handlers. policy must be the one
+// the auth middleware enforces, so the list shows only sessions that still
+// work.
+func NewHandler(s domains.SessionRepo, policy domains.SessionPolicy, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{sessionStore: s, policy: policy, authMiddleware: authMiddleware}
+}
+
+func (h *Handler) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, sessionId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.sessionStore.GetSessionsByUser(r.Context(), userId)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get sessions")
+		return
+	}
+	// expired sessions linger until the cleanup job deletes them
+	now := time.Now()
+	sessions := make([]schema.SessionResponse, 0, len(res))
+	for _, s := range res {
+		if h.policy.Expired(s, now) {
+			continue
+		}
+		sessions = append(sessions, schema.SessionResponse{
+			Id:             s.Id,
+			CreatedAt:      s.CreatedAt,
+			LastVerifiedAt: s.LastVerifiedAt,
+			UserAgent:      s.UserAgent,
+			IP:             s.IP,
+			Current:        s.Id == sessionId,
+		})
+	}
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
+		return
+	}
+}
+
+// revokeSessionHandler deletes one of the caller's sessions. Revoking the
+// current session is allowed and behaves like logging out.
+func (h *Handler) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, sessionId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
+		return
+	}
+	s, err := h.sessionStore.GetSessionById(r.Context(), id)
+	// another user's session is reported as missing so ids cannot be probed
+	if errors.Is(err, domains.ErrNotFound) || (err == nil && s.UserId != userId) {
+		problem.Write(w, http.StatusNotFound, problem.SessionNotFound, "session not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get session")
+		return
+	}
+	if err := h.sessionStore.DeleteSession(r.Context(), id); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to revoke session")
+		return
+	}
+	if id == sessionId {
+		http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "session revoked"})
+}
+
+// revokeOtherSessionsHandler logs the caller out everywhere except the
+// session making the request.
+func (h *Handler) revokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, sessionId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.sessionStore.GetSessionsByUser(r.Context(), userId)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get sessions")
+		return
+	}
+	revoked := 0
+	for _, s := range res {
+		if s.Id == sessionId {
+			continue
+		}
+		if err := h.sessionStore.DeleteSession(r.Context(), s.Id); err != nil {
+			problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to revoke sessions")
+			return
+		}
+		revoked++
+	}
+	_ = json.NewEncoder(w).Encode(schema.RevokeSessionsResponse{Revoked: revoked})
+}
+
+func authed(w http.ResponseWriter, r *http.Request) (userId, sessionId int64, ok bool) {
+	userId, ok = middlewares.GetAuthedUserID(r.Context())
+	if ok {
+		sessionId, ok = middlewares.GetAuthedSessionID(r.Context())
+	}
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
+	}
+	return userId, sessionId, ok
+}
@@ -0,0 +1,202 @@
+package sessions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+var testPolicy = domains.SessionPolicy{AbsoluteTTL: 24 * time.Hour, IdleTTL: time.Hour, RenewAfter: time.Minute}
+
+type testServer struct {
+	store *memstore.Store
+	srv   http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := memstore.New()
+	h := NewHandler(s, testPolicy, middlewares.NewAuthMiddleware(s, s, testPolicy))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	return &testServer{store: s, srv: mux}
+}
+
+// session creates a session for userId and returns its id and Authorization
+// header.
+func (ts *testServer) session(t *testing.T, userId int64, ua string) (int64, string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte("secret"))
+	sid, err := ts.store.CreateSession(context.Background(), userId, base64.StdEncoding.EncodeToString(sum[:]), ua, "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sid, "Bearer " + base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(sid, 10)+"|secret"))
+}
+
+func (ts *testServer) user(t *testing.T, name string) int64 {
+	t.Helper()
+	id, err := ts.store.CreateUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func (ts *testServer) do(t *testing.T, method, target, auth string) *http.Response {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("Authorization", auth)
+	w := httptest.NewRecorder()
+	ts.srv.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func decode[T any](t *testing.T, resp *http.Response) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return v
+}
+
+func (ts *testServer) list(t *testing.T, auth string) []schema.SessionResponse {
+	t.Helper()
+	resp := ts.do(t, http.MethodGet, "/sessions", auth)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list = %d, want 200", resp.StatusCode)
+	}
+	return decode[[]schema.SessionResponse](t, resp)
+}
+
+func TestListSessions(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.user(t, "alice")
+	current, auth := ts.session(t, alice, "laptop")
+	phone, _ := ts.session(t, alice, "phone")
+	idle, _ := ts.session(t, alice, "old tablet")
+	if err := ts.store.UpdateLastVerified(context.Background(), idle, time.Now().Add(-testPolicy.IdleTTL)); err != nil {
+		t.Fatal(err)
+	}
+	ts.session(t, ts.user(t, "bob"), "bob's laptop")
+
+	list := ts.list(t, auth)
+	if len(list) != 2 {
+		t.Fatalf("list = %+v, want alice's two live sessions", list)
+	}
+	for _, s := range list {
+		switch s.Id {
+		case current:
+			if !s.Current || s.UserAgent != "laptop" || s.IP != "203.0.113.7" {
+				t.Errorf("current session = %+v", s)
+			}
+		case phone:
+			if s.Current {
+				t.Errorf("phone session marked current")
+			}
+		default:
+			t.Errorf("unexpected session %+v", s)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.user(t, "alice")
+	_, auth := ts.session(t, alice, "laptop")
+	phone, _ := ts.session(t, alice, "phone")
+
+	target := "/sessions/" + strconv.FormatInt(phone, 10)
+	if resp := ts.do(t, http.MethodDelete, target, auth); resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke = %d, want 200", resp.StatusCode)
+	}
+	if resp := ts.do(t, http.MethodDelete, target, auth); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second revoke = %d, want 404", resp.StatusCode)
+	}
+	if list := ts.list(t, auth); len(list) != 1 || !list[0].Current {
+		t.Errorf("list after revoke = %+v, want only the current session", list)
+	}
+	if resp := ts.do(t, http.MethodDelete, "/sessions/x", auth); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("revoke with a bad id = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRevokeCurrentSession(t *testing.T) {
+	ts := newTestServer(t)
+	current, auth := ts.session(t, ts.user(t, "alice"), "laptop")
+
+	resp := ts.do(t, http.MethodDelete, "/sessions/"+strconv.FormatInt(current, 10), auth)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke = %d, want 200", resp.StatusCode)
+	}
+	var cleared bool
+	for _, c := range resp.Cookies() {
+		cleared = cleared || c.Name == sessionCookieName && c.MaxAge < 0
+	}
+	if !cleared {
+		t.Error("revoking the current session did not clear the cookie")
+	}
+	if resp := ts.do(t, http.MethodGet, "/sessions", auth); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("list after logging out = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestRevokeOtherUsersSession(t *testing.T) {
+	ts := newTestServer(t)
+	_, alice := ts.session(t, ts.user(t, "alice"), "laptop")
+	bobs, bob := ts.session(t, ts.user(t, "bob"), "bob's laptop")
+
+	resp := ts.do(t, http.MethodDelete, "/sessions/"+strconv.FormatInt(bobs, 10), alice)
+	if p := decode[schema.Problem](t, resp); resp.StatusCode != http.StatusNotFound || p.Code != "session_not_found" {
+		t.Errorf("revoking another user's session = %d %q, want 404 session_not_found", resp.StatusCode, p.Code)
+	}
+	if list := ts.list(t, bob); len(list) != 1 {
+		t.Errorf("bob's sessions = %+v, want the one left alone", list)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.user(t, "alice")
+	current, auth := ts.session(t, alice, "laptop")
+	ts.session(t, alice, "phone")
+	ts.session(t, alice, "tablet")
+	_, bob := ts.session(t, ts.user(t, "bob"), "bob's laptop")
+
+	resp := ts.do(t, http.MethodPost, "/sessions/revoke-others", auth)
+	if got := decode[schema.RevokeSessionsResponse](t, resp); resp.StatusCode != http.StatusOK || got.Revoked != 2 {
+		t.Errorf("revoke-others = %d %+v, want 200 and 2 revoked", resp.StatusCode, got)
+	}
+	if list := ts.list(t, auth); len(list) != 1 || list[0].Id != current {
+		t.Errorf("list after revoke-others = %+v, want only the current session", list)
+	}
+	if list := ts.list(t, bob); len(list) != 1 {
+		t.Errorf("bob's sessions = %+v, want them untouched", list)
+	}
+}
+
+func TestSessionsRequireSession(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.user(t, "alice")
+	if _, err := ts.store.CreateAccessToken(context.Background(), domains.AccessToken{
+		UserId: alice, Name: "ci", TokenHash: middlewares.HashAccessToken("hxt_all"), Scopes: domains.Scopes,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp := ts.do(t, http.MethodGet, "/sessions", "Bearer hxt_all")
+	if p := decode[schema.Problem](t, resp); resp.StatusCode != http.StatusForbidden || p.Code != "session_required" {
+		t.Errorf("list with a token = %d %q, want 403 session_required", resp.StatusCode, p.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package sessions
+
+import (
+	"net/http"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+)
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	authMiddleware := h.authMiddleware
+	session := middlewares.RequireSession
+	mux.Handle("GET /sessions", authMiddleware(session(http.HandlerFunc(h.listSessionsHandler))))
+	mux.Handle("DELETE /sessions/{id}", authMiddleware(session(http.HandlerFunc(h.revokeSessionHandler))))
+	mux.Handle("POST /sessions/revoke-others", authMiddleware(session(http.HandlerFunc(h.revokeOtherSessionsHandler))))
+}
@@ -0,0 +1,170 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+const (
+	// tokenLen is the number of random bytes behind each token.
+	tokenLen      = 32
+	maxNameLen    = 100
+	maxExpiryDays = 365
+)
+
+type Handler struct {
+	tokenStore     domains.AccessTokenRepo
+	authMiddleware func(http.Handler) http.Handler
+}
+
+func NewHandler(t domains.AccessTokenRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{tokenStore: t, authMiddleware: authMiddleware}
+}
+
+// createTokenHandler issues a personal access token. The token itself is
+// only ever returned here; the server keeps its hash.
+func (h *Handler) createTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	var req schema.CreateAccessTokenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidBody, "invalid request body")
+		return
+	}
+	var errs []schema.FieldError
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLen {
+		errs = append(errs, problem.Field("name", "must be 1 to "+strconv.Itoa(maxNameLen)+" characters"))
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, problem.Field("scopes", "at least one scope is required"))
+	}
+	for _, s := range req.Scopes {
+		if !slices.Contains(domains.Scopes, s) {
+			errs = append(errs, problem.Field("scopes", "unknown scope "+strconv.Quote(s)))
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxExpiryDays {
+		errs = append(errs, problem.Field("expiresInDays", "must be between 0 (never) and "+strconv.Itoa(maxExpiryDays)))
+	}
+	if errs != nil {
+		problem.Invalid(w, errs...)
+		return
+	}
+
+	b := make([]byte, tokenLen)
+	if _, err := rand.Read(b); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create token")
+		return
+	}
+	raw := domains.AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	t := domains.AccessToken{
+		UserId:    userId,
+		Name:      name,
+		TokenHash: middlewares.HashAccessToken(raw),
+		Scopes:    slices.Compact(scopes),
+	}
+	if req.ExpiresInDays > 0 {
+		t.ExpiresAt = time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+	}
+	id, err := h.tokenStore.CreateAccessToken(r.Context(), t)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to create token")
+		return
+	}
+	t.Id = id
+	t.CreatedAt = time.Now()
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(schema.CreatedAccessTokenResponse{
+		AccessTokenResponse: tokenResponse(t),
+		Token:               raw,
+	})
+}
+
+func (h *Handler) listTokensHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.tokenStore.GetAccessTokensByUser(r.Context(), userId)
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get tokens")
+		return
+	}
+	tokens := make([]schema.AccessTokenResponse, 0, len(res))
+	for _, t := range res {
+		tokens = append(tokens, tokenResponse(t))
+	}
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
+		return
+	}
+}
+
+func (h *Handler) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userId, ok := authed(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, problem.InvalidID, "invalid id")
+		return
+	}
+	err = h.tokenStore.DeleteAccessToken(r.Context(), userId, id)
+	if errors.Is(err, domains.ErrNotFound) {
+		problem.Write(w, http.StatusNotFound, problem.TokenNotFound, "token not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to revoke token")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(schema.OkResponse{Message: "token revoked"})
+}
+
+func tokenResponse(t domains.AccessToken) schema.AccessTokenResponse {
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return schema.AccessTokenResponse{
+		Id:         t.Id,
+		Name:       t.Name,
+		Scopes:     scopes,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+		ExpiresAt:  t.ExpiresAt,
+	}
+}
+
+func authed(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userId, ok := middlewares.GetAuthedUserID(r.Context())
+	if !ok {
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
+	}
+	return userId, ok
+}
@@ -0,0 +1,172 @@
+package tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+	"github.com/HimanshuKumarDutt094/hextok/internal/platform/memstore"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
+)
+
+type testServer struct {
+	store *memstore.Store
+	srv   http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := memstore.New()
+	policy := domains.SessionPolicy{AbsoluteTTL: 24 * time.Hour, IdleTTL: time.Hour, RenewAfter: time.Minute}
+	h := NewHandler(s, middlewares.NewAuthMiddleware(s, s, policy))
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	return &testServer{store: s, srv: mux}
+}
+
+// login creates a user with a session and returns its Authorization header.
+func (ts *testServer) login(t *testing.T, name string) (int64, string) {
+	t.Helper()
+	ctx := context.Background()
+	userId, err := ts.store.CreateUser(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("secret"))
+	sid, err := ts.store.CreateSession(ctx, userId, base64.StdEncoding.EncodeToString(sum[:]), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userId, "Bearer " + base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(sid, 10)+"|secret"))
+}
+
+func (ts *testServer) do(t *testing.T, method, target, auth, body string) *http.Response {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", auth)
+	w := httptest.NewRecorder()
+	ts.srv.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func decode[T any](t *testing.T, resp *http.Response) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return v
+}
+
+func TestIssueListRevoke(t *testing.T) {
+	ts := newTestServer(t)
+	userId, auth := ts.login(t, "alice")
+
+	resp := ts.do(t, http.MethodPost, "/me/tokens", auth, `{"name":" ci ","scopes":["write:hexes","read:hexes","read:hexes"],"expiresInDays":30}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create = %d, want 201", resp.StatusCode)
+	}
+	created := decode[schema.CreatedAccessTokenResponse](t, resp)
+	if !strings.HasPrefix(created.Token, domains.AccessTokenPrefix) {
+		t.Errorf("token %q lacks the %s prefix", created.Token, domains.AccessTokenPrefix)
+	}
+	if created.Name != "ci" || strings.Join(created.Scopes, " ") != "read:hexes write:hexes" {
+		t.Errorf("created = %+v, want name ci and sorted, unique scopes", created.AccessTokenResponse)
+	}
+	if d := time.Until(created.ExpiresAt); d < 29*24*time.Hour || d > 30*24*time.Hour {
+		t.Errorf("expiresAt in %s, want 30 days", d)
+	}
+	// only the hash is stored
+	stored, err := ts.store.GetAccessTokenByHash(context.Background(), middlewares.HashAccessToken(created.Token))
+	if err != nil || stored.UserId != userId {
+		t.Fatalf("stored token = %+v, %v", stored, err)
+	}
+
+	list := decode[[]schema.AccessTokenResponse](t, ts.do(t, http.MethodGet, "/me/tokens", auth, ""))
+	if len(list) != 1 || list[0].Id != created.Id {
+		t.Fatalf("list = %+v, want the created token", list)
+	}
+
+	target := "/me/tokens/" + strconv.FormatInt(created.Id, 10)
+	if resp := ts.do(t, http.MethodDelete, target, auth, ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("revoke = %d, want 200", resp.StatusCode)
+	}
+	if resp := ts.do(t, http.MethodDelete, target, auth, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second revoke = %d, want 404", resp.StatusCode)
+	}
+	if list := decode[[]schema.AccessTokenResponse](t, ts.do(t, http.MethodGet, "/me/tokens", auth, "")); len(list) != 0 {
+		t.Errorf("list after revoke = %+v, want none", list)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	ts := newTestServer(t)
+	_, auth := ts.login(t, "alice")
+	for _, tt := range []struct {
+		body   string
+		fields string
+	}{
+		{`{"name":"","scopes":["read:hexes"]}`, "name"},
+		{`{"name":"` + strings.Repeat("é", maxNameLen+1) + `","scopes":["read:hexes"]}`, "name"},
+		{`{"name":"ci","scopes":[]}`, "scopes"},
+		{`{"name":"ci","scopes":["admin"]}`, "scopes"},
+		{`{"name":"ci","scopes":["read:hexes"],"expiresInDays":366}`, "expiresInDays"},
+		{`{"name":"ci","scopes":["read:hexes"],"expiresInDays":-1}`, "expiresInDays"},
+		{`{"scopes":["nope","read:likes","other"]}`, "name scopes scopes"},
+	} {
+		resp := ts.do(t, http.MethodPost, "/me/tokens", auth, tt.body)
+		p := decode[schema.Problem](t, resp)
+		var fields []string
+		for _, e := range p.Errors {
+			fields = append(fields, e.Field)
+		}
+		if resp.StatusCode != http.StatusBadRequest || strings.Join(fields, " ") != tt.fields {
+			t.Errorf("create %s = %d %v, want 400 on %s", tt.body, resp.StatusCode, fields, tt.fields)
+		}
+	}
+	if resp := ts.do(t, http.MethodPost, "/me/tokens", auth, `{`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with a bad body = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestTokensCannotManageTokens(t *testing.T) {
+	ts := newTestServer(t)
+	_, auth := ts.login(t, "alice")
+	created := decode[schema.CreatedAccessTokenResponse](t, ts.do(t, http.MethodPost, "/me/tokens", auth, `{"name":"ci","scopes":["read:hexes"]}`))
+	pat := "Bearer " + created.Token
+
+	for _, req := range []struct{ method, target, body string }{
+		{http.MethodGet, "/me/tokens", ""},
+		{http.MethodPost, "/me/tokens", `{"name":"more","scopes":["read:hexes"]}`},
+		{http.MethodDelete, "/me/tokens/" + strconv.FormatInt(created.Id, 10), ""},
+	} {
+		resp := ts.do(t, req.method, req.target, pat, req.body)
+		if p := decode[schema.Problem](t, resp); resp.StatusCode != http.StatusForbidden || p.Code != "session_required" {
+			t.Errorf("%s %s with a token = %d %q, want 403 session_required", req.method, req.target, resp.StatusCode, p.Code)
+		}
+	}
+}
+
+func TestRevokeOtherUsersToken(t *testing.T) {
+	ts := newTestServer(t)
+	_, alice := ts.login(t, "alice")
+	_, bob := ts.login(t, "bob")
+	created := decode[schema.CreatedAccessTokenResponse](t, ts.do(t, http.MethodPost, "/me/tokens", alice, `{"name":"ci","scopes":["read:hexes"]}`))
+
+	resp := ts.do(t, http.MethodDelete, "/me/tokens/"+strconv.FormatInt(created.Id, 10), bob, "")
+	if p := decode[schema.Problem](t, resp); resp.StatusCode != http.StatusNotFound || p.Code != "token_not_found" {
+		t.Errorf("revoking another user's token = %d %q, want 404 token_not_found", resp.StatusCode, p.Code)
+	}
+	if list := decode[[]schema.AccessTokenResponse](t, ts.do(t, http.MethodGet, "/me/tokens", bob, "")); len(list) != 0 {
+		t.Errorf("bob sees %+v, want no tokens", list)
+	}
+}
@@ -0,0 +1,16 @@
+package tokens
+
+import (
+	"net/http"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+)
+
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	authMiddleware := h.authMiddleware
+	// tokens are managed from a login session; a token cannot mint others
+	session := middlewares.RequireSession
+	mux.Handle("GET /me/tokens", authMiddleware(session(http.HandlerFunc(h.listTokensHandler))))
+	mux.Handle("POST /me/tokens", authMiddleware(session(http.HandlerFunc(h.createTokenHandler))))
+	mux.Handle("DELETE /me/tokens/{id}", authMiddleware(session(http.HandlerFunc(h.revokeTokenHandler))))
+}
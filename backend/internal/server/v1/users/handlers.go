@@ -6,24 +6,23 @@ import (
 
 	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
+	"github.com/HimanshuKumarDutt094/hextok/internal/server/problem"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/schema"
 )
 
 type Handler struct {
-	userStore    domains.UserRepo
-	sessionStore domains.SessionRepo
+	userStore      domains.UserRepo
+	authMiddleware func(http.Handler) http.Handler
 }
 
-func NewHandler(u domains.UserRepo, s domains.SessionRepo) *Handler {
-	return &Handler{userStore: u, sessionStore: s}
+func NewHandler(u domains.UserRepo, authMiddleware func(http.Handler) http.Handler) *Handler {
+	return &Handler{userStore: u, authMiddleware: authMiddleware}
 }
 
 func (h *Handler) handleGetAllUsers(w http.ResponseWriter, r *http.Request) {
 	res, err := h.userStore.GetAllUser(r.Context())
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to get users"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to get users")
 		return
 	}
 	users := make([]schema.UserResponse, 0, len(res))
@@ -35,8 +34,7 @@ func (h *Handler) handleGetAllUsers(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(users); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "failed to encode response"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "failed to encode response")
 		return
 	}
 
@@ -46,14 +44,12 @@ func (h *Handler) handleGetUserProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 	res, err := h.userStore.GetUserById(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "user not found"})
+		problem.Write(w, http.StatusNotFound, problem.UserNotFound, "user not found")
 		return
 	}
 	user := schema.UserResponse{
@@ -61,8 +57,7 @@ func (h *Handler) handleGetUserProfile(w http.ResponseWriter, r *http.Request) {
 		UserName: res.UserName,
 	}
 	if err := json.NewEncoder(w).Encode(user); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "encoding failed"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "encoding failed")
 		return
 	}
 }
@@ -73,16 +68,14 @@ func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
 	// Get the authenticated user ID from the middleware
 	userId, ok := middlewares.GetAuthedUserID(r.Context())
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "unauthorized"})
+		problem.Write(w, http.StatusUnauthorized, problem.Unauthenticated, "unauthorized")
 		return
 	}
 
 	// Get user from database
 	user, err := h.userStore.GetUserById(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "user not found"})
+		problem.Write(w, http.StatusNotFound, problem.UserNotFound, "user not found")
 		return
 	}
 
@@ -93,8 +86,7 @@ func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Error: "encoding failed"})
+		problem.Write(w, http.StatusInternalServerError, problem.Internal, "encoding failed")
 		return
 	}
 }
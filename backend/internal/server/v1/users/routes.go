@@ -3,13 +3,15 @@ package users
 import (
 	"net/http"
 
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
 	"github.com/HimanshuKumarDutt094/hextok/internal/server/middlewares"
 )
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 
-	m := middlewares.NewAuthMiddleware(h.sessionStore)
-	mux.Handle("GET /users", m(http.HandlerFunc(h.handleGetAllUsers)))
-	mux.Handle("GET /users/{id}", m(http.HandlerFunc(h.handleGetUserProfile)))
-	mux.Handle("GET /users/me", m(http.HandlerFunc(h.handleMe)))
+	m := h.authMiddleware
+	read := middlewares.RequireScope(domains.ScopeReadUsers)
+	mux.Handle("GET /users", m(read(http.HandlerFunc(h.handleGetAllUsers))))
+	mux.Handle("GET /users/{id}", m(read(http.HandlerFunc(h.handleGetUserProfile))))
+	mux.Handle("GET /users/me", m(read(http.HandlerFunc(h.handleMe))))
 }
@@ -0,0 +1,167 @@
+// Package swatch renders a hex color as a flat PNG or SVG image, optionally
+// labelled with its value, for link previews and share cards.
+package swatch
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/HimanshuKumarDutt094/hextok/internal/domains"
+)
+
+// Version is bumped whenever the rendered output changes, so that cached
+// images keyed on it are invalidated.
+const Version = 1
+
+const (
+	DefaultWidth  = 512
+	DefaultHeight = 512
+	MinSize       = 16
+	MaxSize       = 2048
+)
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+	// glyphAdvance leaves one blank column between characters.
+	glyphAdvance = glyphWidth + 1
+)
+
+// Options controls the size of the image and whether the hex value is drawn
+// on top of the color.
+type Options struct {
+	Width  int
+	Height int
+	Label  bool
+}
+
+// Validate checks the dimensions against MinSize and MaxSize.
+func (o Options) Validate() error {
+	if o.Width < MinSize || o.Width > MaxSize {
+		return fmt.Errorf("width must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Height < MinSize || o.Height > MaxSize {
+		return fmt.Errorf("height must be between %d and %d", MinSize, MaxSize)
+	}
+	return nil
+}
+
+// LabelColor returns black or white, whichever has the higher WCAG contrast
+// ratio against c. Alpha is ignored.
+func LabelColor(c domains.Color) domains.Color {
+	l := relativeLuminance(c)
+	// contrast against black is (l+0.05)/0.05, against white 1.05/(l+0.05);
+	// they are equal where l is about 0.179
+	if (l+0.05)*(l+0.05) > 1.05*0.05 {
+		return domains.Color{A: 0xff}
+	}
+	return domains.Color{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+}
+
+func relativeLuminance(c domains.Color) float64 {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(c.R) + 0.7152*lin(c.G) + 0.0722*lin(c.B)
+}
+
+// PNG writes c as a PNG image.
+func PNG(w io.Writer, c domains.Color, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	fill := color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = fill.R, fill.G, fill.B, fill.A
+	}
+	if opts.Label {
+		lc := LabelColor(c)
+		drawText(img, c.Hex(), color.NRGBA{R: lc.R, G: lc.G, B: lc.B, A: lc.A})
+	}
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	return enc.Encode(w, img)
+}
+
+// drawText centres s on img using the bitmap font, scaled to take up at most
+// 80% of the width and a quarter of the height.
+func drawText(img *image.NRGBA, s string, fg color.NRGBA) {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return
+	}
+	b := img.Bounds()
+	cols := len(runes)*glyphAdvance - 1
+	scale := min(b.Dx()*4/5/cols, b.Dy()/4/glyphHeight)
+	if scale < 1 {
+		// too small to be legible; leave the plain swatch
+		return
+	}
+	x0 := b.Min.X + (b.Dx()-cols*scale)/2
+	y0 := b.Min.Y + (b.Dy()-glyphHeight*scale)/2
+	for i, r := range runes {
+		g, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		gx := x0 + i*glyphAdvance*scale
+		for row, bits := range g {
+			for col := range glyphWidth {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				fillRect(img, gx+col*scale, y0+row*scale, scale, fg)
+			}
+		}
+	}
+}
+
+func fillRect(img *image.NRGBA, x, y, size int, c color.NRGBA) {
+	for dy := range size {
+		for dx := range size {
+			img.SetNRGBA(x+dx, y+dy, c)
+		}
+	}
+}
+
+// SVG writes c as an SVG document. The label uses the viewer's monospace
+// font rather than the bitmap font, so it stays sharp at any zoom.
+func SVG(w io.Writer, c domains.Color, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	hex := c.Hex()
+	opaque := fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	opacity := ""
+	if c.A != 0xff {
+		opacity = fmt.Sprintf(` fill-opacity="%.3f"`, float64(c.A)/255)
+	}
+	_, err := fmt.Fprintf(w,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+
+			`<title>%s</title><rect width="100%%" height="100%%" fill="%s"%s/>`,
+		opts.Width, opts.Height, opts.Width, opts.Height, hex, opaque, opacity)
+	if err != nil {
+		return err
+	}
+	if opts.Label {
+		// monospace glyphs are roughly 0.6em wide
+		size := min(float64(opts.Width)*0.8/(0.6*float64(len(hex))), float64(opts.Height)/4)
+		_, err = fmt.Fprintf(w,
+			`<text x="50%%" y="50%%" fill="%s" font-family="ui-monospace, Menlo, Consolas, monospace" `+
+				`font-size="%.1f" text-anchor="middle" dominant-baseline="central">%s</text>`,
+			LabelColor(c).Hex(), size, hex)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</svg>\n")
+	return err
+}